@@ -1,8 +1,11 @@
 package appconfig
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
+	"time"
 )
 
 const (
@@ -14,6 +17,33 @@ const (
 type AppConfig struct {
 	URL       string
 	FromEmail string
+
+	// Postgres connection string, made from the DB_ settings
+	DatabaseURL string
+
+	// LLM backend used for recipe extraction, see parsing.ExtractorConfig
+	LLMBackend string
+	LLMModel   string
+	LLMAPIKey  string
+	LLMBaseURL string
+
+	// LLM spending limits in dollars, zero for no limit, see usage.Budget
+	LLMGroupDailyBudget   float64
+	LLMGroupMonthlyBudget float64
+	LLMUserDailyBudget    float64
+	LLMUserMonthlyBudget  float64
+	// What the LLM model costs in dollars per million tokens, when LLMPriceSet.
+	// Otherwise the list price of known models is used.
+	LLMInputPrice  float64
+	LLMOutputPrice float64
+	LLMPriceSet    bool
+	// How long what the LLM read from a page is reused, zero for the default
+	ExtractionCacheTTL time.Duration
+
+	// Directory cached recipe images are stored in
+	ImageDir string
+	// Directory the saved copies of recipe pages are stored in
+	SnapshotDir string
 }
 
 var Config AppConfig
@@ -21,6 +51,40 @@ var Config AppConfig
 func Initialize() {
 	env := os.Getenv("APP_ENV")
 
+	Config.DatabaseURL = fmt.Sprintf(
+		"postgres://%s:%s@%s:%s/%s?sslmode=disable&connect_timeout=10",
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_NAME"),
+	)
+	Config.LLMBackend = os.Getenv("LLM_BACKEND")
+	Config.LLMModel = os.Getenv("LLM_MODEL")
+	Config.LLMAPIKey = os.Getenv("LLM_API_KEY")
+	Config.LLMBaseURL = os.Getenv("LLM_BASE_URL")
+	Config.LLMGroupDailyBudget = dollars("LLM_GROUP_DAILY_BUDGET")
+	Config.LLMGroupMonthlyBudget = dollars("LLM_GROUP_MONTHLY_BUDGET")
+	Config.LLMUserDailyBudget = dollars("LLM_USER_DAILY_BUDGET")
+	Config.LLMUserMonthlyBudget = dollars("LLM_USER_MONTHLY_BUDGET")
+	Config.LLMPriceSet = os.Getenv("LLM_INPUT_PRICE") != "" || os.Getenv("LLM_OUTPUT_PRICE") != ""
+	Config.LLMInputPrice = dollars("LLM_INPUT_PRICE")
+	Config.LLMOutputPrice = dollars("LLM_OUTPUT_PRICE")
+	Config.ExtractionCacheTTL = duration("EXTRACTION_CACHE_TTL")
+	Config.ImageDir = os.Getenv("IMAGE_DIR")
+	if Config.ImageDir == "" {
+		Config.ImageDir = "data/images"
+	}
+	Config.SnapshotDir = os.Getenv("SNAPSHOT_DIR")
+	if Config.SnapshotDir == "" {
+		Config.SnapshotDir = "data/snapshots"
+	}
+	// ANTHROPIC_KEY predates the other settings, keep it working
+	if key := os.Getenv("ANTHROPIC_KEY"); key != "" && Config.LLMBackend == "" {
+		Config.LLMBackend = "anthropic"
+		Config.LLMAPIKey = key
+	}
+
 	switch env {
 	case EnvDev:
 		Config.URL = "http://localhost:8080"
@@ -32,6 +96,36 @@ func Initialize() {
 	}
 }
 
+// dollars reads an amount of dollars, such as "2.50", from an environment
+// variable. Unset or invalid amounts are zero.
+func dollars(name string) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || amount < 0 {
+		slog.Error("Invalid dollar amount, ignoring it", "variable", name, "value", value)
+		return 0
+	}
+	return amount
+}
+
+// duration reads a duration, such as "720h", from an environment variable.
+// Unset or invalid durations are zero.
+func duration(name string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		slog.Error("Invalid duration, ignoring it", "variable", name, "value", value)
+		return 0
+	}
+	return d
+}
+
 func AppName() string {
 	return "Recipeze"
 }
@@ -0,0 +1,40 @@
+// Package blob stores files such as recipe images outside the database.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"path"
+)
+
+// ErrNotFound is returned when no blob has the key
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs by key. Keys are slash separated paths like
+// "recipes/ab12.jpg", and their extension gives the content type.
+type Store interface {
+	// Put saves the blob, replacing any with the same key
+	Put(ctx context.Context, key string, r io.Reader) error
+
+	// Get opens the blob for reading, the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, Info, error)
+
+	// Delete removes the blob, it is not an error if there is none
+	Delete(ctx context.Context, key string) error
+}
+
+// Info describes a stored blob
+type Info struct {
+	Size        int64
+	ContentType string
+}
+
+// ContentType guesses the content type of a key from its extension
+func ContentType(key string) string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
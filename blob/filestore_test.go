@@ -0,0 +1,63 @@
+package blob_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/blob"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stores and reads back a blob", func(t *testing.T) {
+		store := blob.NewFileStore(t.TempDir())
+		is.NotError(t, store.Put(ctx, "recipes/abc.jpg", strings.NewReader("photo")))
+
+		r, info, err := store.Get(ctx, "recipes/abc.jpg")
+		is.NotError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		is.NotError(t, err)
+		is.Equal(t, "photo", string(data))
+		is.Equal(t, int64(5), info.Size)
+		is.Equal(t, "image/jpeg", info.ContentType)
+	})
+
+	t.Run("replaces a blob with the same key", func(t *testing.T) {
+		store := blob.NewFileStore(t.TempDir())
+		is.NotError(t, store.Put(ctx, "a.png", strings.NewReader("old")))
+		is.NotError(t, store.Put(ctx, "a.png", strings.NewReader("new")))
+
+		r, _, err := store.Get(ctx, "a.png")
+		is.NotError(t, err)
+		defer r.Close()
+		data, _ := io.ReadAll(r)
+		is.Equal(t, "new", string(data))
+	})
+
+	t.Run("missing and deleted blobs are not found", func(t *testing.T) {
+		store := blob.NewFileStore(t.TempDir())
+		_, _, err := store.Get(ctx, "missing.jpg")
+		is.Error(t, blob.ErrNotFound, err)
+
+		is.NotError(t, store.Put(ctx, "gone.jpg", strings.NewReader("x")))
+		is.NotError(t, store.Delete(ctx, "gone.jpg"))
+		is.NotError(t, store.Delete(ctx, "gone.jpg"))
+		_, _, err = store.Get(ctx, "gone.jpg")
+		is.Error(t, blob.ErrNotFound, err)
+	})
+
+	t.Run("keys can't leave the directory", func(t *testing.T) {
+		store := blob.NewFileStore(t.TempDir())
+		for _, key := range []string{"", "../secret", "recipes/../../secret", "/etc/passwd", `..\secret`, "recipes/"} {
+			is.True(t, store.Put(ctx, key, strings.NewReader("x")) != nil)
+			_, _, err := store.Get(ctx, key)
+			is.Error(t, blob.ErrNotFound, err)
+		}
+	})
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -26,14 +25,6 @@ func main() {
 	}
 }
 
-type DBConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-}
-
 func start(log *slog.Logger) error {
 	log.Info("Starting app")
 
@@ -46,24 +37,7 @@ func start(log *slog.Logger) error {
 
 	appconfig.Initialize()
 
-	dbConfig := DBConfig{
-		Host:     os.Getenv("DB_HOST"),
-		Port:     os.Getenv("DB_PORT"),
-		User:     os.Getenv("DB_USER"),
-		Password: os.Getenv("DB_PASSWORD"),
-		DBName:   os.Getenv("DB_NAME"),
-	}
-
-	connString := fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s?sslmode=disable&connect_timeout=10",
-		dbConfig.User,
-		dbConfig.Password,
-		dbConfig.Host,
-		dbConfig.Port,
-		dbConfig.DBName,
-	)
-
-	dbpool, err := pgxpool.New(context.Background(), connString)
+	dbpool, err := pgxpool.New(context.Background(), appconfig.Config.DatabaseURL)
 	if err != nil {
 		slog.Error("Unable to create connection pool",
 			"error", err)
@@ -87,6 +61,16 @@ func start(log *slog.Logger) error {
 		return s.Start()
 	})
 
+	// Background jobs stop taking work with ctx, and finish what they are doing
+	eg.Go(func() error {
+		return s.ProcessJobs(ctx)
+	})
+
+	// Recipe events reach open pages until ctx is done, which ends their streams
+	eg.Go(func() error {
+		return s.ListenForEvents(ctx)
+	})
+
 	// Wait for the context to be done, which happens when a signal is caught
 	<-ctx.Done()
 	log.Info("Stopping app")
@@ -0,0 +1,120 @@
+// Command reprocess re-extracts recipes whose data came from an older parser
+// or prompt version, reading their saved pages where it can.
+//
+//	go run ./cmd/reprocess                   # every outdated recipe
+//	go run ./cmd/reprocess -group 3          # outdated recipes in one group
+//	go run ./cmd/reprocess -recipe 42 -force # one recipe, even if it is up to date
+//	go run ./cmd/reprocess -rollback -recipe 42
+//	go run ./cmd/reprocess -refresh-cache    # ask the LLM again instead of using cached results
+//	go run ./cmd/reprocess -purge-cache expired
+//	go run ./cmd/reprocess -purge-cache all
+//
+// Re-extracted recipes keep their previous data, which -rollback puts back.
+// What the LLM read is reused from the extraction cache, except with
+// -refresh-cache, which asks again and replaces it.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"maragu.dev/env"
+
+	"recipeze/appconfig"
+	"recipeze/blob"
+	"recipeze/events"
+	"recipeze/fetch"
+	"recipeze/parsing"
+	"recipeze/repo"
+	"recipeze/service"
+	"recipeze/usage"
+)
+
+func main() {
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	slog.SetDefault(log)
+
+	if err := run(log); err != nil {
+		log.Error("Error reprocessing recipes", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(log *slog.Logger) error {
+	var opts service.ReprocessOptions
+	rollback := flag.Bool("rollback", false, "put back the data recipes had before they were last re-extracted")
+	refreshCache := flag.Bool("refresh-cache", false, "ask the LLM again instead of using results from the extraction cache")
+	purgeCache := flag.String("purge-cache", "", "remove `which` results from the extraction cache, expired or all, and stop")
+	flag.IntVar(&opts.GroupID, "group", 0, "only recipes in this group")
+	flag.IntVar(&opts.RecipeID, "recipe", 0, "only this recipe")
+	flag.BoolVar(&opts.AllVersions, "force", false, "also recipes extracted by the current versions")
+	flag.BoolVar(&opts.DryRun, "dry-run", false, "list the recipes without changing them")
+	flag.Parse()
+	if *purgeCache != "" && *purgeCache != "expired" && *purgeCache != "all" {
+		return fmt.Errorf("-purge-cache takes expired or all, not %q", *purgeCache)
+	}
+
+	_ = env.Load()
+	appconfig.Initialize()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	db, err := pgxpool.New(ctx, appconfig.Config.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	queries := repo.New(db)
+
+	extractor, err := parsing.NewRecipeExtractor(parsing.ExtractorConfig{
+		Backend: appconfig.Config.LLMBackend,
+		Model:   appconfig.Config.LLMModel,
+		APIKey:  appconfig.Config.LLMAPIKey,
+		BaseURL: appconfig.Config.LLMBaseURL,
+	})
+	if err != nil {
+		log.Warn("LLM extraction disabled, only structured recipe data will be used", "error", err)
+		extractor = nil
+	}
+	if extractor != nil {
+		// Calls are recorded, but budgets are for what users add, not for reprocessing
+		var price *usage.Price
+		if appconfig.Config.LLMPriceSet {
+			price = &usage.Price{Input: appconfig.Config.LLMInputPrice, Output: appconfig.Config.LLMOutputPrice}
+		}
+		extractor = usage.NewMeter(extractor, queries, usage.Options{Price: price})
+	}
+	cache := service.NewExtractionCache(queries, service.ExtractionCacheOptions{
+		TTL:     appconfig.Config.ExtractionCacheTTL,
+		Refresh: *refreshCache,
+	})
+	if *purgeCache != "" {
+		all := *purgeCache == "all"
+		removed, err := cache.Purge(ctx, all)
+		log.Info("Purged extraction cache", "all", all, "removed", removed)
+		return err
+	}
+
+	fetcher := fetch.New(fetch.Options{})
+	// Open recipe pages are told about re-extracted recipes by the running servers
+	recipes := service.NewRecipeService(queries, db, extractor, cache, events.New(db))
+	snapshots := service.NewSnapshotService(queries, blob.NewFileStore(appconfig.Config.SnapshotDir))
+	reprocessor := service.NewReprocessor(queries, recipes, snapshots, fetcher)
+
+	log.Info("Reprocessing recipes", "parserVersion", parsing.ParserVersion, "promptVersion", parsing.PromptVersion, "rollback", *rollback)
+	var result service.ReprocessResult
+	if *rollback {
+		result, err = reprocessor.Rollback(ctx, opts)
+	} else {
+		result, err = reprocessor.Reprocess(ctx, opts)
+	}
+	log.Info("Done", "picked", result.Picked, "updated", result.Updated, "failed", result.Failed)
+	return err
+}
@@ -0,0 +1,185 @@
+// Package events tells the open pages of a group when its recipes change.
+//
+// Events go through Postgres LISTEN/NOTIFY, so a change made on one server, or
+// by a command such as reprocess, reaches the pages served by every server.
+// Each server passes the events on to its own subscribers.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// channel is the Postgres notification channel events are sent on
+	channel = "recipe_events"
+	// reconnectDelay is how long to wait before listening again after the
+	// connection was lost
+	reconnectDelay = 5 * time.Second
+	// subscriberBuffer is how many events a subscriber can fall behind by
+	// before events are dropped for it
+	subscriberBuffer = 32
+)
+
+// Kind says what happened to a recipe
+type Kind string
+
+const (
+	RecipeCreated Kind = "recipe-created"
+	RecipeUpdated Kind = "recipe-updated" // Edited, or read or changed in the background
+	RecipeDeleted Kind = "recipe-deleted"
+)
+
+// Event is a change to one of a group's recipes
+type Event struct {
+	Kind     Kind `json:"kind"`
+	GroupID  int  `json:"group_id"`
+	RecipeID int  `json:"recipe_id"`
+}
+
+type Bus struct {
+	db *pgxpool.Pool
+
+	mu          sync.Mutex
+	subscribers map[int]map[chan Event]struct{} // By group ID
+	closed      bool
+}
+
+// New creates a bus that sends events through db. Without a database, events
+// only reach this process's subscribers.
+func New(db *pgxpool.Pool) *Bus {
+	return &Bus{
+		db:          db,
+		subscribers: map[int]map[chan Event]struct{}{},
+	}
+}
+
+// Publish sends e to the subscribers of its group on every server. They only
+// get it while some server is running Listen.
+func (b *Bus) Publish(ctx context.Context, e Event) error {
+	if b.db == nil {
+		b.deliver(e)
+		return nil
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = b.db.Exec(ctx, "SELECT pg_notify($1, $2)", channel, string(payload))
+	return err
+}
+
+// Subscribe gives the events for a group until cancel is called or the bus
+// stops, when the channel is closed. A subscriber that falls behind misses
+// events rather than holding up the others.
+func (b *Bus) Subscribe(groupID int) (events <-chan Event, cancel func()) {
+	ch := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subscribers[groupID] == nil {
+		b.subscribers[groupID] = map[chan Event]struct{}{}
+	}
+	b.subscribers[groupID][ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subscribers[groupID][ch]; !ok {
+				return // Already closed by Listen
+			}
+			delete(b.subscribers[groupID], ch)
+			if len(b.subscribers[groupID]) == 0 {
+				delete(b.subscribers, groupID)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Listen passes the events published on every server to this server's
+// subscribers until ctx is done, then closes their channels. A lost database
+// connection is opened again, events sent in between are missed.
+func (b *Bus) Listen(ctx context.Context) error {
+	defer b.close()
+	if b.db == nil {
+		<-ctx.Done()
+		return nil
+	}
+	slog.Info("Listening for recipe events")
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			slog.Info("Stopped listening for recipe events")
+			return nil
+		}
+		slog.Warn("Lost recipe event connection, listening again shortly", "error", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// listen delivers notifications from one connection until it fails
+func (b *Bus) listen(ctx context.Context) error {
+	conn, err := b.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// A listening connection can't go back to the pool
+	listener := conn.Hijack()
+	defer listener.Close(context.Background())
+
+	if _, err := listener.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	for {
+		notification, err := listener.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(notification.Payload), &e); err != nil {
+			slog.Warn("Could not read recipe event", "payload", notification.Payload, "error", err)
+			continue
+		}
+		b.deliver(e)
+	}
+}
+
+func (b *Bus) deliver(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[e.GroupID] {
+		select {
+		case ch <- e:
+		default:
+			slog.Warn("Recipe event subscriber fell behind, dropping event", "groupID", e.GroupID, "kind", e.Kind)
+		}
+	}
+}
+
+// close ends every subscription, so open event streams finish
+func (b *Bus) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for groupID, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+		delete(b.subscribers, groupID)
+	}
+}
@@ -0,0 +1,63 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/events"
+)
+
+func TestBus(t *testing.T) {
+	t.Run("delivers events to the group's subscribers only", func(t *testing.T) {
+		bus := events.New(nil)
+		group1, cancel1 := bus.Subscribe(1)
+		defer cancel1()
+		group2, cancel2 := bus.Subscribe(2)
+		defer cancel2()
+
+		event := events.Event{Kind: events.RecipeUpdated, GroupID: 1, RecipeID: 42}
+		is.NotError(t, bus.Publish(context.Background(), event))
+
+		is.Equal(t, event, <-group1)
+		is.Equal(t, 0, len(group2))
+	})
+
+	t.Run("closes the channel when cancelled", func(t *testing.T) {
+		bus := events.New(nil)
+		ch, cancel := bus.Subscribe(1)
+		cancel()
+		cancel()
+		_, ok := <-ch
+		is.True(t, !ok)
+		is.NotError(t, bus.Publish(context.Background(), events.Event{Kind: events.RecipeDeleted, GroupID: 1}))
+	})
+
+	t.Run("closes every subscription when it stops listening", func(t *testing.T) {
+		bus := events.New(nil)
+		ch, cancel := bus.Subscribe(1)
+		defer cancel()
+
+		ctx, stop := context.WithCancel(context.Background())
+		stop()
+		is.NotError(t, bus.Listen(ctx))
+		_, ok := <-ch
+		is.True(t, !ok)
+
+		late, _ := bus.Subscribe(1)
+		_, ok = <-late
+		is.True(t, !ok)
+	})
+
+	t.Run("drops events for subscribers that fall behind", func(t *testing.T) {
+		bus := events.New(nil)
+		ch, cancel := bus.Subscribe(1)
+		defer cancel()
+		for i := range 100 {
+			is.NotError(t, bus.Publish(context.Background(), events.Event{Kind: events.RecipeCreated, GroupID: 1, RecipeID: i}))
+		}
+		is.Equal(t, 32, len(ch))
+		is.Equal(t, 0, (<-ch).RecipeID)
+	})
+}
@@ -0,0 +1,24 @@
+package export
+
+import (
+	"io"
+
+	"recipeze/model"
+	"recipeze/parsing"
+)
+
+// WriteCooklang writes the recipe in Cooklang, with the times as they were
+// last saved and the photo the recipe shows
+func WriteCooklang(w io.Writer, recipe *model.Recipe) error {
+	main, subs := parts(recipe)
+	main.Image = image(recipe)
+	main.PrepTime = recipe.PrepTime.ISO8601()
+	main.CookTime = recipe.CookTime.ISO8601()
+	main.TotalTime = recipe.TotalTime.ISO8601()
+	return parsing.WriteCooklang(w, &parsing.Cooklang{
+		Title:       recipe.Name,
+		Description: recipe.Description,
+		Source:      recipe.Url,
+		Collection:  &parsing.RecipeCollection{Recipes: append([]parsing.Recipe{main}, subs...)},
+	})
+}
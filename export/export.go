@@ -0,0 +1,159 @@
+// Package export writes recipes out in formats other apps and people can read,
+// so a group's recipes are never stuck in Recipeze.
+package export
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"recipeze/model"
+	"recipeze/parsing"
+)
+
+// ErrUnknownFormat is returned for a format that recipes can't be exported in
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Format is a file format recipes are exported in
+type Format string
+
+const (
+	FormatJSONLD   Format = "jsonld"   // schema.org Recipe, as recipe sites embed it
+	FormatMarkdown Format = "markdown" // With YAML front matter, for notes apps
+	FormatCooklang Format = "cooklang" // https://cooklang.org
+)
+
+// Formats are the export formats in the order they are offered
+var Formats = []Format{FormatJSONLD, FormatMarkdown, FormatCooklang}
+
+// ParseFormat reads a format's name, as used in export links
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownFormat, name)
+}
+
+// Label is the format's name as shown to people
+func (f Format) Label() string {
+	switch f {
+	case FormatJSONLD:
+		return "JSON-LD"
+	case FormatMarkdown:
+		return "Markdown"
+	case FormatCooklang:
+		return "Cooklang"
+	}
+	return string(f)
+}
+
+// Extension is the file extension of a recipe in the format
+func (f Format) Extension() string {
+	switch f {
+	case FormatJSONLD:
+		return ".json"
+	case FormatMarkdown:
+		return ".md"
+	case FormatCooklang:
+		return ".cook"
+	}
+	return ""
+}
+
+// ContentType is the media type a recipe in the format is served as
+func (f Format) ContentType() string {
+	switch f {
+	case FormatJSONLD:
+		return "application/ld+json; charset=utf-8"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// Write writes one recipe in the format
+func Write(w io.Writer, format Format, recipe *model.Recipe) error {
+	switch format {
+	case FormatJSONLD:
+		return WriteJSONLD(w, recipe)
+	case FormatMarkdown:
+		return WriteMarkdown(w, recipe)
+	case FormatCooklang:
+		return WriteCooklang(w, recipe)
+	}
+	return fmt.Errorf("%w %q", ErrUnknownFormat, format)
+}
+
+// Zip writes many recipes as a zip with a file for each, named after the recipe
+func Zip(w io.Writer, format Format, recipes []model.Recipe) error {
+	archive := zip.NewWriter(w)
+	used := map[string]bool{}
+	for i := range recipes {
+		name := Filename(&recipes[i], format)
+		if used[name] {
+			// Recipes can share a name, the ID tells them apart
+			name = strings.TrimSuffix(name, format.Extension()) + "-" + strconv.Itoa(recipes[i].ID) + format.Extension()
+		}
+		used[name] = true
+
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if err := Write(file, format, &recipes[i]); err != nil {
+			return fmt.Errorf("recipe %d: %w", recipes[i].ID, err)
+		}
+	}
+	return archive.Close()
+}
+
+var slugRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Filename gives a file name for the recipe in the format, like "apple-pie.cook"
+func Filename(recipe *model.Recipe, format Format) string {
+	return Slug(recipe.Name, "recipe") + format.Extension()
+}
+
+// Slug turns a name into something safe for a file name, or fallback when
+// nothing of it is left
+func Slug(name, fallback string) string {
+	slug := strings.Trim(slugRe.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 80 {
+		slug = strings.TrimRight(slug[:80], "-")
+	}
+	if slug == "" {
+		return fallback
+	}
+	return slug
+}
+
+// parts gives the recipe's main part and any sub-recipes, like a sauce, that
+// go with it. Recipes whose data was never read get an empty main part.
+func parts(recipe *model.Recipe) (parsing.Recipe, []parsing.Recipe) {
+	if recipe.Data == nil || len(recipe.Data.Recipes) == 0 {
+		return parsing.Recipe{Name: recipe.Name}, nil
+	}
+	return recipe.Data.Recipes[0], recipe.Data.Recipes[1:]
+}
+
+// image gives the link to the recipe's photo, as found on its page
+func image(recipe *model.Recipe) string {
+	if recipe.ImageURL != "" {
+		return recipe.ImageURL
+	}
+	main, _ := parts(recipe)
+	return main.Image
+}
+
+// formatNumber writes an amount without float noise, like 0.33 rather than 0.3333333
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,207 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"maragu.dev/is"
+
+	"recipeze/export"
+	"recipeze/model"
+	"recipeze/parsing"
+)
+
+func amount(v float64) *float64 { return &v }
+
+// testRecipe is a cake with a frosting, a note and nutrition
+func testRecipe() *model.Recipe {
+	calories := 410.0
+	return &model.Recipe{
+		ID:          7,
+		Name:        "Carrot Cake",
+		Url:         "https://example.com/carrot-cake",
+		Description: "Our birthday cake",
+		ImageURL:    "https://example.com/cake.jpg",
+		PrepTime:    parsing.Duration(20 * time.Minute),
+		CookTime:    parsing.Duration(45 * time.Minute),
+		TotalTime:   parsing.Duration(65 * time.Minute),
+		Data: &parsing.RecipeCollection{Recipes: []parsing.Recipe{
+			{
+				Name:     "Carrot Cake",
+				Servings: 12,
+				Tags:     []string{"Dessert", "Birthday"},
+				Ingredients: []parsing.Ingredient{
+					{Amount: amount(2), Unit: "cup", Name: "flour"},
+					{Amount: amount(3), Name: "carrot", Notes: "grated"},
+					{Amount: amount(0.333333), Unit: "cup", Name: "vegetable oil"},
+					{Name: "salt"},
+				},
+				Instructions: []string{
+					"Mix the flour with the oil.",
+					"Fold in the carrots and bake for 45 minutes -- until a skewer comes out clean.",
+				},
+				Notes:     []string{"Keeps for 3 days."},
+				Nutrition: &parsing.Nutrition{Calories: &calories},
+			},
+			{
+				Name:         "Frosting",
+				Ingredients:  []parsing.Ingredient{{Amount: amount(8), Unit: "oz", Name: "cream cheese"}},
+				Instructions: []string{"Beat the cream cheese until smooth."},
+			},
+		}},
+	}
+}
+
+func TestWriteJSONLD(t *testing.T) {
+	var buf bytes.Buffer
+	is.NotError(t, export.WriteJSONLD(&buf, testRecipe()))
+
+	// What we write, our own page reader reads back
+	extracted, err := parsing.ParseRecipe("", []byte(`<script type="application/ld+json">`+buf.String()+`</script>`))
+	is.NotError(t, err)
+	is.Equal(t, "Carrot Cake", extracted.Title)
+	is.Equal(t, "2 cup flour|3 carrot, grated|⅓ cup vegetable oil|salt|8 oz cream cheese", strings.Join(extracted.Ingredients, "|"))
+	is.Equal(t, "PT20M", extracted.PrepTime)
+	is.Equal(t, "12 servings", extracted.Yield)
+	is.Equal(t, 2, len(extracted.Sections))
+	is.Equal(t, "Frosting", extracted.Sections[1].Name)
+	is.Equal(t, 410.0, *extracted.Nutrition.Calories)
+
+	var raw map[string]any
+	is.NotError(t, json.Unmarshal(buf.Bytes(), &raw))
+	is.Equal(t, "Recipe", raw["@type"])
+	is.Equal(t, "Dessert, Birthday", raw["keywords"])
+	is.Equal(t, 1, len(raw["comment"].([]any)))
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	is.NotError(t, export.WriteMarkdown(&buf, testRecipe()))
+	is.Equal(t, `---
+title: "Carrot Cake"
+source: "https://example.com/carrot-cake"
+image: "https://example.com/cake.jpg"
+servings: 12
+prep_time: "20 min"
+cook_time: "45 min"
+total_time: "1 hr 5 min"
+tags: ["Dessert", "Birthday"]
+---
+
+# Carrot Cake
+
+Our birthday cake
+
+## Ingredients
+
+- 2 cup flour
+- 3 carrot, grated
+- ⅓ cup vegetable oil
+- salt
+
+### Frosting
+
+- 8 oz cream cheese
+
+## Instructions
+
+1. Mix the flour with the oil.
+2. Fold in the carrots and bake for 45 minutes -- until a skewer comes out clean.
+
+### Frosting
+
+1. Beat the cream cheese until smooth.
+
+## Notes
+
+- Keeps for 3 days.
+
+## Nutrition per serving
+
+| Nutrient | Amount |
+| --- | --- |
+| Calories | 410 |
+`, buf.String())
+}
+
+func TestWriteCooklang(t *testing.T) {
+	var buf bytes.Buffer
+	is.NotError(t, export.WriteCooklang(&buf, testRecipe()))
+	is.Equal(t, `>> title: Carrot Cake
+>> description: Our birthday cake
+>> source: https://example.com/carrot-cake
+>> image: https://example.com/cake.jpg
+>> servings: 12
+>> prep time: 20 min
+>> cook time: 45 min
+>> total time: 1 hr 5 min
+>> tags: Dessert, Birthday
+
+@vegetable oil{0.33%cup}, @salt{}
+
+Mix the @flour{2%cup} with the oil.
+
+Fold in the @carrots{3}(grated) and bake for 45 minutes – until a skewer comes out clean.
+
+> Keeps for 3 days.
+
+== Frosting ==
+
+Beat the @cream cheese{8%oz} until smooth.
+`, buf.String())
+}
+
+func TestWrite_recipeWithoutData(t *testing.T) {
+	recipe := &model.Recipe{ID: 1, Name: "Still reading", Status: model.ExtractionPending}
+	for _, format := range export.Formats {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			is.NotError(t, export.Write(&buf, format, recipe))
+			is.True(t, strings.Contains(buf.String(), "Still reading"))
+		})
+	}
+}
+
+func TestZip(t *testing.T) {
+	first, second := testRecipe(), testRecipe()
+	second.ID = 8
+	var buf bytes.Buffer
+	is.NotError(t, export.Zip(&buf, export.FormatCooklang, []model.Recipe{*first, *second}))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	is.NotError(t, err)
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	is.Equal(t, "carrot-cake.cook,carrot-cake-8.cook", strings.Join(names, ","))
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := export.ParseFormat("markdown")
+	is.NotError(t, err)
+	is.Equal(t, export.FormatMarkdown, format)
+
+	_, err = export.ParseFormat("pdf")
+	is.True(t, errors.Is(err, export.ErrUnknownFormat))
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Grandma's Apple Pie!", "grandma-s-apple-pie"},
+		{"  Crème brûlée ", "cr-me-br-l-e"},
+		{"🍰", "recipe"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			is.Equal(t, test.want, export.Slug(test.name, "recipe"))
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"recipeze/model"
+	"recipeze/parsing"
+)
+
+// jsonLDRecipe is a schema.org Recipe, with fields in the order recipe sites
+// usually give them
+type jsonLDRecipe struct {
+	Context            string           `json:"@context"`
+	Type               string           `json:"@type"`
+	Name               string           `json:"name"`
+	Description        string           `json:"description,omitempty"`
+	URL                string           `json:"url,omitempty"`
+	Image              string           `json:"image,omitempty"`
+	Video              *jsonLDVideo     `json:"video,omitempty"`
+	RecipeYield        string           `json:"recipeYield,omitempty"`
+	PrepTime           string           `json:"prepTime,omitempty"`
+	CookTime           string           `json:"cookTime,omitempty"`
+	TotalTime          string           `json:"totalTime,omitempty"`
+	RecipeCuisine      []string         `json:"recipeCuisine,omitempty"`
+	Keywords           string           `json:"keywords,omitempty"`
+	RecipeIngredient   []string         `json:"recipeIngredient"`
+	RecipeInstructions []any            `json:"recipeInstructions"`
+	Nutrition          *jsonLDNutrition `json:"nutrition,omitempty"`
+	Comment            []jsonLDText     `json:"comment,omitempty"` // The recipe's notes
+}
+
+type jsonLDVideo struct {
+	Type       string `json:"@type"`
+	ContentURL string `json:"contentUrl"`
+}
+
+// jsonLDText is a HowToStep or a Comment
+type jsonLDText struct {
+	Type  string `json:"@type"`
+	Text  string `json:"text"`
+	Image string `json:"image,omitempty"`
+	Video string `json:"video,omitempty"`
+}
+
+type jsonLDSection struct {
+	Type            string `json:"@type"`
+	Name            string `json:"name"`
+	ItemListElement []any  `json:"itemListElement"`
+}
+
+type jsonLDNutrition struct {
+	Type                string `json:"@type"`
+	ServingSize         string `json:"servingSize,omitempty"`
+	Calories            string `json:"calories,omitempty"`
+	ProteinContent      string `json:"proteinContent,omitempty"`
+	FatContent          string `json:"fatContent,omitempty"`
+	CarbohydrateContent string `json:"carbohydrateContent,omitempty"`
+	SodiumContent       string `json:"sodiumContent,omitempty"`
+}
+
+// WriteJSONLD writes the recipe as a schema.org Recipe. Sub-recipes, like a
+// sauce, become named sections of the instructions and share the ingredients.
+func WriteJSONLD(w io.Writer, recipe *model.Recipe) error {
+	main, subs := parts(recipe)
+	out := jsonLDRecipe{
+		Context:            "https://schema.org",
+		Type:               "Recipe",
+		Name:               recipe.Name,
+		Description:        recipe.Description,
+		URL:                recipe.Url,
+		Image:              image(recipe),
+		PrepTime:           recipe.PrepTime.ISO8601(),
+		CookTime:           recipe.CookTime.ISO8601(),
+		TotalTime:          recipe.TotalTime.ISO8601(),
+		RecipeCuisine:      main.Cuisine,
+		RecipeIngredient:   []string{},
+		RecipeInstructions: jsonLDSteps(main),
+		Nutrition:          jsonLDNutritionOf(main.Nutrition),
+	}
+	if main.Video != "" {
+		out.Video = &jsonLDVideo{Type: "VideoObject", ContentURL: main.Video}
+	}
+	if main.Servings > 0 {
+		out.RecipeYield = fmt.Sprintf("%d servings", main.Servings)
+	}
+	if len(main.Tags) > 0 {
+		out.Keywords = strings.Join(main.Tags, ", ")
+	}
+	for _, part := range append([]parsing.Recipe{main}, subs...) {
+		for _, ingredient := range part.Ingredients {
+			out.RecipeIngredient = append(out.RecipeIngredient, parsing.FormatIngredient(ingredient))
+		}
+		for _, note := range part.Notes {
+			out.Comment = append(out.Comment, jsonLDText{Type: "Comment", Text: note})
+		}
+	}
+	for _, sub := range subs {
+		out.RecipeInstructions = append(out.RecipeInstructions, jsonLDSection{
+			Type:            "HowToSection",
+			Name:            sub.Name,
+			ItemListElement: jsonLDSteps(sub),
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// jsonLDSteps writes a part's instructions as HowToSteps, with the photos and
+// videos that go with them
+func jsonLDSteps(part parsing.Recipe) []any {
+	steps := make([]any, 0, len(part.Instructions))
+	for i, text := range part.Instructions {
+		step := jsonLDText{Type: "HowToStep", Text: text}
+		for _, media := range part.StepMedia {
+			if media.Step == i {
+				step.Image, step.Video = media.Image, media.Video
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+func jsonLDNutritionOf(n *parsing.Nutrition) *jsonLDNutrition {
+	if n.IsEmpty() {
+		return nil
+	}
+	amount := func(value *float64, unit string) string {
+		if value == nil {
+			return ""
+		}
+		return formatNumber(*value) + " " + unit
+	}
+	return &jsonLDNutrition{
+		Type:                "NutritionInformation",
+		ServingSize:         n.ServingSize,
+		Calories:            amount(n.Calories, "calories"),
+		ProteinContent:      amount(n.Protein, "g"),
+		FatContent:          amount(n.Fat, "g"),
+		CarbohydrateContent: amount(n.Carbohydrates, "g"),
+		SodiumContent:       amount(n.Sodium, "mg"),
+	}
+}
@@ -0,0 +1,127 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"recipeze/model"
+	"recipeze/parsing"
+)
+
+// WriteMarkdown writes the recipe as Markdown with YAML front matter, which
+// notes apps like Obsidian read as the recipe's properties
+func WriteMarkdown(w io.Writer, recipe *model.Recipe) error {
+	main, subs := parts(recipe)
+	b := bufio.NewWriter(w)
+
+	b.WriteString("---\n")
+	frontMatter(b, "title", recipe.Name)
+	frontMatter(b, "source", recipe.Url)
+	frontMatter(b, "image", image(recipe))
+	if main.Servings > 0 {
+		fmt.Fprintf(b, "servings: %d\n", main.Servings)
+	}
+	frontMatter(b, "prep_time", recipe.PrepTime.String())
+	frontMatter(b, "cook_time", recipe.CookTime.String())
+	frontMatter(b, "total_time", recipe.TotalTime.String())
+	frontMatterList(b, "cuisine", main.Cuisine)
+	frontMatterList(b, "tags", main.Tags)
+	b.WriteString("---\n\n")
+
+	fmt.Fprintf(b, "# %s\n", markdownText(recipe.Name))
+	if recipe.Description != "" {
+		fmt.Fprintf(b, "\n%s\n", markdownText(recipe.Description))
+	}
+
+	all := append([]parsing.Recipe{main}, subs...)
+	b.WriteString("\n## Ingredients\n")
+	for i, part := range all {
+		if len(part.Ingredients) == 0 {
+			continue
+		}
+		b.WriteString("\n")
+		if i > 0 {
+			fmt.Fprintf(b, "### %s\n\n", markdownText(part.Name))
+		}
+		for _, ingredient := range part.Ingredients {
+			fmt.Fprintf(b, "- %s\n", markdownText(parsing.FormatIngredient(ingredient)))
+		}
+	}
+
+	b.WriteString("\n## Instructions\n")
+	for i, part := range all {
+		if len(part.Instructions) == 0 {
+			continue
+		}
+		b.WriteString("\n")
+		if i > 0 {
+			fmt.Fprintf(b, "### %s\n\n", markdownText(part.Name))
+		}
+		for n, step := range part.Instructions {
+			fmt.Fprintf(b, "%d. %s\n", n+1, markdownText(step))
+		}
+	}
+
+	var notes []string
+	for _, part := range all {
+		notes = append(notes, part.Notes...)
+	}
+	if len(notes) > 0 {
+		b.WriteString("\n## Notes\n\n")
+		for _, note := range notes {
+			fmt.Fprintf(b, "- %s\n", markdownText(note))
+		}
+	}
+
+	if n := main.Nutrition; !n.IsEmpty() {
+		b.WriteString("\n## Nutrition per serving\n\n")
+		if n.ServingSize != "" {
+			fmt.Fprintf(b, "Serving size: %s\n\n", markdownText(n.ServingSize))
+		}
+		b.WriteString("| Nutrient | Amount |\n| --- | --- |\n")
+		for _, row := range []struct {
+			label, unit string
+			value       *float64
+		}{
+			{"Calories", "", n.Calories},
+			{"Protein", " g", n.Protein},
+			{"Fat", " g", n.Fat},
+			{"Carbohydrates", " g", n.Carbohydrates},
+			{"Sodium", " mg", n.Sodium},
+		} {
+			if row.value != nil {
+				fmt.Fprintf(b, "| %s | %s%s |\n", row.label, formatNumber(*row.value), row.unit)
+			}
+		}
+	}
+	return b.Flush()
+}
+
+// frontMatter writes a YAML string field, quoted so values like "10:30" or
+// "yes" stay strings
+func frontMatter(b *bufio.Writer, key, value string) {
+	if value != "" {
+		fmt.Fprintf(b, "%s: %s\n", key, strconv.Quote(value))
+	}
+}
+
+func frontMatterList(b *bufio.Writer, key string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	fmt.Fprintf(b, "%s: [%s]\n", key, strings.Join(quoted, ", "))
+}
+
+// markdownText keeps text on one line and stops it from being read as
+// Markdown, like a name with asterisks or angle brackets
+var markdownText = strings.NewReplacer(
+	"\r", " ", "\n", " ",
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "<", `\<`, "[", `\[`, "]", `\]`,
+).Replace
@@ -0,0 +1,358 @@
+// Package fetch downloads recipe pages and photos from the web, with limits on
+// time and size and without reaching into our own network.
+package fetch
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/html/charset"
+)
+
+// Reasons a fetch failed, worded so they can be shown to people
+var (
+	ErrInvalidURL        = errors.New("that is not a link to a web page")
+	ErrForbiddenAddress  = errors.New("that address can't be reached from Recipeze")
+	ErrTimeout           = errors.New("the site took too long to answer")
+	ErrUnreachable       = errors.New("the site could not be reached")
+	ErrTooManyRedirects  = errors.New("the site redirected too many times")
+	ErrBlocked           = errors.New("the site blocked us")
+	ErrNotFound          = errors.New("the site says the page doesn't exist")
+	ErrSiteError         = errors.New("the site had a problem")
+	ErrNotWebPage        = errors.New("the link is not a web page")
+	ErrTooLarge          = errors.New("the page is too large")
+	ErrUnsupportedFormat = errors.New("the site sent something we can't read")
+)
+
+// Error is a failed fetch. Reason is one of the Err values above, Err is what
+// went wrong underneath, for the logs.
+type Error struct {
+	URL    string
+	Reason error
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("fetch %s: %v", e.URL, e.Reason)
+	}
+	return fmt.Sprintf("fetch %s: %v: %v", e.URL, e.Reason, e.Err)
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Reason}
+	}
+	return []error{e.Reason, e.Err}
+}
+
+// Reason gives the reason a fetch failed, to show to people, or nil when err
+// did not come from a fetch
+func Reason(err error) error {
+	var fetchErr *Error
+	if errors.As(err, &fetchErr) {
+		return fetchErr.Reason
+	}
+	return nil
+}
+
+// Options are the limits a Fetcher works within. Zero values get the defaults.
+type Options struct {
+	Timeout      time.Duration // For the whole request, 15 seconds
+	MaxBytes     int64         // Of a page once decompressed, 5 MB
+	MaxRedirects int           // 5
+	UserAgent    string
+	// DomainDelay is the least time between two requests to the same site, 1 second
+	DomainDelay time.Duration
+	// AllowLoopback lets requests reach this machine, only for tests
+	AllowLoopback bool
+}
+
+// DefaultUserAgent says who we are, in the form sites expect from crawlers
+const DefaultUserAgent = "Mozilla/5.0 (compatible; Recipeze/1.0; +https://github.com/Noahdw/Recipeze)"
+
+// Fetcher downloads pages. It is safe to use from many goroutines.
+type Fetcher struct {
+	opts   Options
+	client *http.Client
+
+	mu   sync.Mutex
+	next map[string]time.Time // When each site may next be asked for something
+}
+
+// New creates a Fetcher with the given limits
+func New(opts Options) *Fetcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 15 * time.Second
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 5 << 20
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = 5
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = DefaultUserAgent
+	}
+	if opts.DomainDelay <= 0 {
+		opts.DomainDelay = time.Second
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+		// Checked on the address actually dialed, so a name that resolves to a
+		// private address, or changes to one after a check, is still refused
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkAddress(address, opts.AllowLoopback)
+		},
+	}
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       60 * time.Second,
+		// Compression is undone in decompress, to also accept brotli
+		DisableCompression: true,
+	}
+	f := &Fetcher{opts: opts, next: map[string]time.Time{}}
+	f.client = &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > opts.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			if err := checkURL(req.URL, opts.AllowLoopback); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// Page is a downloaded web page
+type Page struct {
+	URL  string // Where the page was found, after any redirects
+	HTML []byte // Decoded to UTF-8
+}
+
+// Page downloads an HTML page
+func (f *Fetcher) Page(ctx context.Context, pageURL string) (*Page, error) {
+	finalURL, contentType, body, err := f.get(ctx, pageURL, "text/html,application/xhtml+xml;q=0.9,*/*;q=0.5", f.opts.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, &Error{URL: pageURL, Reason: ErrNotWebPage, Err: fmt.Errorf("content type %q", contentType)}
+	}
+
+	// The charset comes from the header, or else from the page's meta tags.
+	// Without either, only the start of the page is looked at, so valid UTF-8
+	// is kept as it is rather than guessed to be Windows-1252.
+	encoding, name, certain := charset.DetermineEncoding(body, contentType)
+	if name != "utf-8" && (certain || !utf8.Valid(body)) {
+		decoded, err := encoding.NewDecoder().Bytes(body)
+		if err != nil {
+			return nil, &Error{URL: pageURL, Reason: ErrUnsupportedFormat, Err: fmt.Errorf("decoding %s: %w", name, err)}
+		}
+		body = decoded
+	}
+	return &Page{URL: finalURL, HTML: body}, nil
+}
+
+// Download fetches a file such as a photo, of at most maxBytes, and gives its
+// content type as the site named it
+func (f *Fetcher) Download(ctx context.Context, fileURL, accept string, maxBytes int64) ([]byte, string, error) {
+	_, contentType, body, err := f.get(ctx, fileURL, accept, maxBytes)
+	return body, contentType, err
+}
+
+// get makes a GET request within the fetcher's limits
+func (f *Fetcher) get(ctx context.Context, rawURL, accept string, maxBytes int64) (finalURL, contentType string, body []byte, err error) {
+	fail := func(reason, err error) (string, string, []byte, error) {
+		return "", "", nil, &Error{URL: rawURL, Reason: reason, Err: err}
+	}
+
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return fail(ErrInvalidURL, err)
+	}
+	if err := checkURL(parsed, f.opts.AllowLoopback); err != nil {
+		return fail(err, nil)
+	}
+	if err := f.wait(ctx, parsed.Hostname()); err != nil {
+		return fail(ErrTimeout, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return fail(ErrInvalidURL, err)
+	}
+	req.Header.Set("User-Agent", f.opts.UserAgent)
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Accept-Language", "en;q=0.9,*;q=0.5")
+	req.Header.Set("Accept-Encoding", "gzip, br")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fail(reasonOf(err), err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden,
+		resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusUnavailableForLegalReasons:
+		return fail(ErrBlocked, fmt.Errorf("status %s", resp.Status))
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusGone:
+		return fail(ErrNotFound, fmt.Errorf("status %s", resp.Status))
+	case resp.StatusCode != http.StatusOK:
+		return fail(ErrSiteError, fmt.Errorf("status %s", resp.Status))
+	}
+	if resp.ContentLength > maxBytes {
+		return fail(ErrTooLarge, fmt.Errorf("content length %d", resp.ContentLength))
+	}
+
+	reader, err := decompress(resp)
+	if err != nil {
+		return fail(ErrUnsupportedFormat, err)
+	}
+	body, err = io.ReadAll(io.LimitReader(reader, maxBytes+1))
+	if err != nil {
+		return fail(reasonOf(err), err)
+	}
+	if int64(len(body)) > maxBytes {
+		return fail(ErrTooLarge, fmt.Errorf("more than %d bytes", maxBytes))
+	}
+	return resp.Request.URL.String(), resp.Header.Get("Content-Type"), body, nil
+}
+
+// decompress undoes the content encoding the site chose
+func decompress(resp *http.Response) (io.Reader, error) {
+	switch encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(resp.Body)
+	case "br":
+		return brotli.NewReader(resp.Body), nil
+	default:
+		return nil, fmt.Errorf("content encoding %q", encoding)
+	}
+}
+
+// wait holds a request back until the site has had a moment since our last
+// one, so adding many recipes from one site doesn't hammer it
+func (f *Fetcher) wait(ctx context.Context, host string) error {
+	host = strings.ToLower(host)
+	f.mu.Lock()
+	now := time.Now()
+	start := now
+	if next, ok := f.next[host]; ok && next.After(now) {
+		start = next
+	}
+	f.next[host] = start.Add(f.opts.DomainDelay)
+	if len(f.next) > 1000 {
+		for h, next := range f.next {
+			if next.Before(now) {
+				delete(f.next, h)
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	if delay := start.Sub(now); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// checkURL refuses anything but web links, and links straight to an IP
+// address we must not reach
+func checkURL(u *url.URL, allowLoopback bool) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	host := strings.ToLower(u.Hostname())
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublic(addr, allowLoopback) {
+		return ErrForbiddenAddress
+	}
+	if !allowLoopback && (host == "localhost" || strings.HasSuffix(host, ".localhost")) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// checkAddress refuses to connect to a host:port that isn't on the public internet
+func checkAddress(address string, allowLoopback bool) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrForbiddenAddress, err)
+	}
+	if !isPublic(addrPort.Addr(), allowLoopback) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// sharedAddressSpace is carrier-grade NAT, private in all but name
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPublic tells if an address is on the public internet, rather than our
+// own machine, network or a cloud metadata service
+func isPublic(addr netip.Addr, allowLoopback bool) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		(allowLoopback || !addr.IsLoopback()) &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr) &&
+		!(addr.Is4() && addr.As4()[0] == 0)
+}
+
+// reasonOf works out why a request failed from the error the client gave
+func reasonOf(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrForbiddenAddress):
+		return ErrForbiddenAddress
+	case errors.Is(err, ErrTooManyRedirects):
+		return ErrTooManyRedirects
+	case errors.Is(err, ErrInvalidURL):
+		return ErrInvalidURL
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	}
+	return ErrUnreachable
+}
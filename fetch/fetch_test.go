@@ -0,0 +1,193 @@
+package fetch_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"maragu.dev/is"
+
+	"recipeze/fetch"
+)
+
+const recipePage = `<html><head><title>Soup</title></head><body>Crème fraîche</body></html>`
+
+// newServer serves a few pages that go wrong in different ways
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/recipe", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(recipePage))
+	})
+	mux.HandleFunc("/latin1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><meta charset=\"iso-8859-1\"></head><body>Cr\xe8me fra\xeeche</body></html>"))
+	})
+	mux.HandleFunc("/gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(recipePage))
+		gz.Close()
+	})
+	mux.HandleFunc("/brotli", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Encoding", "br")
+		br := brotli.NewWriter(w)
+		br.Write([]byte(recipePage))
+		br.Close()
+	})
+	mux.HandleFunc("/agent", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(r.UserAgent()))
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(bytes.Repeat([]byte("a"), 2048))
+	})
+	mux.HandleFunc("/pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4"))
+	})
+	mux.HandleFunc("/forbidden", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no bots", http.StatusForbidden)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/recipe", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newFetcher can reach the test server, which is on loopback
+func newFetcher() *fetch.Fetcher {
+	return fetch.New(fetch.Options{
+		AllowLoopback: true,
+		MaxBytes:      1024,
+		Timeout:       500 * time.Millisecond,
+		DomainDelay:   time.Millisecond,
+	})
+}
+
+func TestFetcher_Page(t *testing.T) {
+	server := newServer(t)
+	fetcher := newFetcher()
+	ctx := context.Background()
+
+	for _, path := range []string{"/recipe", "/latin1", "/gzip", "/brotli"} {
+		t.Run("reads "+path, func(t *testing.T) {
+			page, err := fetcher.Page(ctx, server.URL+path)
+			is.NotError(t, err)
+			is.True(t, strings.Contains(string(page.HTML), "Crème fraîche"))
+		})
+	}
+
+	t.Run("follows redirects", func(t *testing.T) {
+		page, err := fetcher.Page(ctx, server.URL+"/moved")
+		is.NotError(t, err)
+		is.Equal(t, server.URL+"/recipe", page.URL)
+	})
+
+	t.Run("says who it is", func(t *testing.T) {
+		page, err := fetcher.Page(ctx, server.URL+"/agent")
+		is.NotError(t, err)
+		is.Equal(t, fetch.DefaultUserAgent, string(page.HTML))
+	})
+
+	tests := []struct {
+		name string
+		url  string
+		want error
+	}{
+		{"not a link", "recipe soup", fetch.ErrInvalidURL},
+		{"not the web", "file:///etc/passwd", fetch.ErrInvalidURL},
+		{"blocked", server.URL + "/forbidden", fetch.ErrBlocked},
+		{"missing", server.URL + "/missing", fetch.ErrNotFound},
+		{"site error", server.URL + "/broken", fetch.ErrSiteError},
+		{"not a web page", server.URL + "/pdf", fetch.ErrNotWebPage},
+		{"too large", server.URL + "/big", fetch.ErrTooLarge},
+		{"redirect loop", server.URL + "/loop", fetch.ErrTooManyRedirects},
+		{"redirect to metadata service", server.URL + "/metadata", fetch.ErrForbiddenAddress},
+		{"slow", server.URL + "/slow", fetch.ErrTimeout},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := fetcher.Page(ctx, test.url)
+			is.Error(t, test.want, err)
+			is.Equal(t, test.want, fetch.Reason(err))
+		})
+	}
+}
+
+func TestFetcher_privateAddresses(t *testing.T) {
+	server := newServer(t)
+	fetcher := fetch.New(fetch.Options{})
+
+	for _, url := range []string{
+		server.URL + "/recipe",
+		"http://localhost/",
+		"http://10.0.0.1/",
+		"http://192.168.1.1/",
+		"http://[::1]/",
+		"http://[::ffff:127.0.0.1]/",
+		"http://0.0.0.0/",
+		"http://100.64.0.1/",
+	} {
+		t.Run(url, func(t *testing.T) {
+			_, err := fetcher.Page(context.Background(), url)
+			is.Error(t, fetch.ErrForbiddenAddress, err)
+		})
+	}
+}
+
+func TestFetcher_Download(t *testing.T) {
+	server := newServer(t)
+	data, contentType, err := newFetcher().Download(context.Background(), server.URL+"/pdf", "application/pdf", 100)
+	is.NotError(t, err)
+	is.Equal(t, "%PDF-1.4", string(data))
+	is.Equal(t, "application/pdf", contentType)
+}
+
+func TestFetcher_domainDelay(t *testing.T) {
+	server := newServer(t)
+	fetcher := fetch.New(fetch.Options{AllowLoopback: true, DomainDelay: 100 * time.Millisecond})
+
+	start := time.Now()
+	for range 3 {
+		_, err := fetcher.Page(context.Background(), server.URL+"/recipe")
+		is.NotError(t, err)
+	}
+	is.True(t, time.Since(start) >= 200*time.Millisecond)
+
+	t.Run("gives up when the caller does", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := fetcher.Page(ctx, server.URL+"/recipe")
+		is.True(t, errors.Is(err, fetch.ErrTimeout) || errors.Is(err, context.Canceled))
+	})
+}
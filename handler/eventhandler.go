@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// eventPingInterval is how often an idle event stream sends a comment, so
+// proxies don't close it
+const eventPingInterval = 30 * time.Second
+
+// streamGroupEvents sends changes to the group's recipes to an open recipe
+// page as Server-Sent Events, until the page closes or the server stops
+func (h *handler) streamGroupEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isUserActionAllowed(r.Context()) {
+			http.NotFound(w, r)
+			return
+		}
+		groupID, err := GetGroupID(r)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		// The stream stays open well past the server's timeouts
+		controller := http.NewResponseController(w)
+		if err := errors.Join(controller.SetReadDeadline(time.Time{}), controller.SetWriteDeadline(time.Time{})); err != nil {
+			slog.Info("Could not clear deadline", "path", r.URL.Path, "error", err)
+		}
+
+		events, cancel := h.events.Subscribe(groupID)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no") // Don't let nginx hold events back
+		w.WriteHeader(http.StatusOK)
+		if err := controller.Flush(); err != nil {
+			slog.Error("Could not start event stream", "error", err)
+			return
+		}
+
+		ping := time.NewTicker(eventPingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return // The server is stopping, the page connects again
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Kind, data)
+			case <-ping.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"recipeze/export"
+)
+
+// exportRecipe downloads one recipe in the format named in the path
+func (h *handler) exportRecipe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isUserActionAllowed(r.Context()) {
+			http.NotFound(w, r)
+			return
+		}
+		format, err := export.ParseFormat(chi.URLParam(r, "format"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		groupID, err := GetGroupID(r)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		recipeID, err := getRecipeID(r)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		recipe, err := h.GetRecipeByID(r.Context(), int32(recipeID))
+		if err != nil || recipe.GroupID != groupID {
+			http.NotFound(w, r)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := export.Write(&buf, format, recipe); err != nil {
+			slog.Error("Could not export recipe", "recipeID", recipeID, "format", format, "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		sendDownload(w, export.Filename(recipe, format), format.ContentType(), buf.Bytes())
+	}
+}
+
+// exportGroupRecipes downloads every recipe in the group as a zip
+func (h *handler) exportGroupRecipes() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isUserActionAllowed(r.Context()) {
+			http.NotFound(w, r)
+			return
+		}
+		format, err := export.ParseFormat(chi.URLParam(r, "format"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		groupID, err := GetGroupID(r)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		recipes, err := h.GetGroupRecipes(r.Context(), groupID)
+		if err != nil {
+			slog.Error("Could not get recipes", "error", err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		// Built in memory so a failure can still be reported as an error
+		var buf bytes.Buffer
+		if err := export.Zip(&buf, format, recipes); err != nil {
+			slog.Error("Could not export recipes", "groupID", groupID, "format", format, "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		slog.Info("Exported recipes", "groupID", groupID, "format", format, "count", len(recipes))
+		sendDownload(w, fmt.Sprintf("recipes-%s.zip", format), "application/zip", buf.Bytes())
+	}
+}
+
+// sendDownload sends data as a file the browser saves rather than shows
+func sendDownload(w http.ResponseWriter, filename, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := w.Write(data); err != nil {
+		slog.Info("Could not send download", "file", filename, "error", err)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"recipeze/events"
+	"recipeze/fetch"
 	rmiddleware "recipeze/middleware"
 	"recipeze/service"
 	"strconv"
@@ -26,20 +28,33 @@ type requestContext struct {
 type handler struct {
 	service.AuthService
 	service.RecipeService
+	service.ImageService
+	service.SnapshotService
+	service.ProcessingService
+	service.UsageService
+	fetcher *fetch.Fetcher // Downloads the pages of recipes added by link
+	events  *events.Bus    // Changes to recipes, streamed to the group's open pages
 }
 
-func NewHandler(auth service.AuthService, recipe service.RecipeService) *handler {
+func NewHandler(auth service.AuthService, recipe service.RecipeService, images service.ImageService, snapshots service.SnapshotService, processing service.ProcessingService, usage service.UsageService, fetcher *fetch.Fetcher, bus *events.Bus) *handler {
 	return &handler{
-		AuthService:   auth,
-		RecipeService: recipe,
+		AuthService:       auth,
+		RecipeService:     recipe,
+		ImageService:      images,
+		SnapshotService:   snapshots,
+		ProcessingService: processing,
+		UsageService:      usage,
+		fetcher:           fetcher,
+		events:            bus,
 	}
 }
 
-func InitRouting(r chi.Router, auth service.AuthService, recipe service.RecipeService) {
+func InitRouting(r chi.Router, auth service.AuthService, recipe service.RecipeService, images service.ImageService, snapshots service.SnapshotService, processing service.ProcessingService, usage service.UsageService, fetcher *fetch.Fetcher, bus *events.Bus) {
 	mw := rmiddleware.NewAuthMiddleware(auth)
-	h := NewHandler(auth, recipe)
+	h := NewHandler(auth, recipe, images, snapshots, processing, usage, fetcher, bus)
 	h.RouteHome(r, mw)
 	h.RouteRecipe(r, mw)
+	h.RouteImages(r, mw)
 }
 
 func (h *handler) adapt(fn adaptFunc) http.HandlerFunc {
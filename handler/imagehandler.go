@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"recipeze/blob"
+	mw "recipeze/middleware"
+)
+
+func (h *handler) RouteImages(r chi.Router, m *mw.AuthMiddleware) {
+	r.Group(func(r chi.Router) {
+		r.Use(m.Authenticate) // Must be logged in
+
+		// Cached recipe photos and thumbnails
+		r.Get("/images/r/{name}", h.getRecipeImage())
+	})
+}
+
+// getRecipeImage serves a cached photo. Names are hashes of the content, so
+// browsers can keep them forever.
+func (h *handler) getRecipeImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		etag := strconv.Quote(name)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		image, info, err := h.GetRecipeImage(r.Context(), name)
+		if errors.Is(err, blob.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			slog.Error("Could not get recipe image", "name", name, "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer image.Close()
+
+		w.Header().Set("Content-Type", info.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+		w.Header().Set("ETag", etag)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if _, err := io.Copy(w, image); err != nil {
+			slog.Info("Could not send recipe image", "name", name, "error", err)
+		}
+	}
+}
@@ -3,20 +3,28 @@ package handler
 import (
 	//"context"
 
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	. "maragu.dev/gomponents"
 
+	"recipeze/fetch"
+	"recipeze/importer"
 	mw "recipeze/middleware"
 	"recipeze/model"
 	"recipeze/parsing"
 	"recipeze/repo"
+	"recipeze/service"
 	"recipeze/ui"
 
-	"github.com/imroc/req/v3"
 	"golang.org/x/net/context"
 	"golang.org/x/net/html"
 	hx "maragu.dev/gomponents-htmx/http"
@@ -39,8 +47,36 @@ func (h *handler) RouteRecipe(r chi.Router, m *mw.AuthMiddleware) {
 		r.Get("/recipes", h.getRecipes())
 		// Add new recipe to a group
 		r.Post("/recipes", h.addNewRecipe())
+		// Add a recipe from pasted text
+		r.Post("/recipes/paste", h.addPastedRecipe())
+		// Find the recipes in a PDF, then add the ones picked
+		r.Post("/recipes/pdf", h.readRecipePDF())
+		r.Post("/recipes/pdf/add", h.addPDFRecipes())
+		// Import an export from another recipe manager
+		r.Post("/recipes/import", h.importRecipes())
+		// Check and correct a recipe's data before saving it
+		r.Get("/recipes/review/{recipe_id}", h.reviewRecipe())
+		r.Post("/recipes/review/{recipe_id}", h.saveReviewedRecipe())
 		// Get single recipe (for detail view)
 		r.Get("/recipe/{recipe_id}", h.getRecipeDetailView())
+		// Get a recipe's entry in the recipe list
+		r.Get("/recipe/{recipe_id}/item", h.getRecipeListItem())
+		// Stream changes to the group's recipes to its open pages
+		r.Get("/events", h.streamGroupEvents())
+		// Change the units recipes are shown in
+		r.Post("/recipe/{recipe_id}/units", h.setRecipeUnits())
+		// Download a recipe, or all of the group's, as JSON-LD, Markdown or Cooklang
+		r.Get("/recipe/{recipe_id}/export/{format}", h.exportRecipe())
+		r.Get("/recipes/export/{format}", h.exportGroupRecipes())
+		// Read a recipe again after it failed
+		r.Post("/recipe/{recipe_id}/retry", h.retryRecipe())
+		// Show the copy of the recipe's page saved when it was added
+		r.Get("/recipe/{recipe_id}/snapshot", h.getRecipeSnapshot())
+		r.Get("/recipe/{recipe_id}/snapshot/page", h.getRecipeSnapshotPage())
+		// Show a recipe scaled to a number of servings
+		r.Get("/recipe/{recipe_id}/scale", h.scaleRecipe())
+		// Show the group's admins what reading recipes with the LLM cost
+		r.Get("/usage", h.getGroupUsage())
 		// Show modal for adding a new recipe
 		r.Get("/recipes/new", h.showNewRecipeModal())
 		// Delete a recipe from a group
@@ -90,7 +126,7 @@ func (h *handler) deleteRecipe() http.HandlerFunc {
 			selectedID = recipe.ID
 		}
 
-		mainContent := ui.RecipeDetailPartial(&recipe, groupID)
+		mainContent := ui.RecipeDetailPartial(&recipe, groupID, detailOptions(ctx))
 
 		// Second part updates another element out-of-band
 		listContent := Div(
@@ -113,14 +149,28 @@ func (h *handler) getRecipes() http.HandlerFunc {
 		if err != nil {
 			return nil, ErrDefault
 		}
-		recipes, err := h.GetGroupRecipes(ctx.context(), groupID)
-		if err != nil {
-			return nil, ErrDefault
+		var recipes []model.Recipe
+		if maxTime := ctx.queryParam("max_time"); maxTime != "" {
+			// Quickest first, optionally capped at a number of minutes
+			minutes, err := strconv.Atoi(maxTime)
+			if err != nil || minutes <= 0 || minutes > int(time.Duration(parsing.MaxDuration)/time.Minute) {
+				return nil, ErrDefault
+			}
+			recipes, err = h.GetGroupRecipesByTotalTime(ctx.context(), groupID, time.Duration(minutes)*time.Minute)
+			if err != nil {
+				return nil, ErrDefault
+			}
+		} else {
+			recipes, err = h.GetGroupRecipes(ctx.context(), groupID)
+			if err != nil {
+				return nil, ErrDefault
+			}
 		}
 
-		// If HTMX request, return just the list
+		// If HTMX request, return just the list, keeping the selected recipe highlighted
 		if hx.IsRequest(ctx.r.Header) {
-			return ui.RecipeListPartial(recipes, 0, groupID), nil
+			selectedID, _ := strconv.Atoi(ctx.queryParam("selected"))
+			return ui.RecipeListPartial(recipes, selectedID, groupID), nil
 		}
 		group := model.Group{
 			ID:      groupID,
@@ -128,23 +178,20 @@ func (h *handler) getRecipes() http.HandlerFunc {
 			Members: []model.GroupMember{},
 		}
 
-		users, err := h.GetGroupUsers(ctx.context(), groupID)
+		user := mw.GetUserFromContext(ctx.context())
+		members, err := h.GetGroupMembers(ctx.context(), groupID)
 		if err != nil {
 			return nil, ErrDefault
 		}
-
-		for _, user := range users { // Don't like this.
-			mg := model.GroupMember{
-				ID:      user.ID,
-				Name:    user.Name,
-				Email:   user.Email,
-				IsAdmin: false,
+		group.Members = members
+		for _, member := range members {
+			if member.ID == user.ID && member.IsAdmin {
+				group.IsAdmin = true
 			}
-			group.Members = append(group.Members, mg)
 		}
 
 		// Otherwise return full page
-		return ui.RecipePage(ui.PageProps{IncludeHeader: true}, recipes, group), nil
+		return ui.RecipePage(ui.PageProps{IncludeHeader: true}, recipes, group, detailOptions(ctx)), nil
 	})
 }
 
@@ -173,7 +220,7 @@ func (h *handler) updateRecipeDetails() http.HandlerFunc {
 		err = h.UpdateRecipe(ctx.context(), repo.UpdateRecipeParams{
 			ID:          int32(recipeID),
 			Name:        repo.StringPG(ctx.r.FormValue("name")),
-			Url:         repo.StringPG(ctx.r.FormValue("url")),
+			Url:         repo.NullStringPG(ctx.r.FormValue("url")),
 			Description: repo.StringPG(ctx.r.FormValue("description")),
 		})
 		if err != nil {
@@ -186,7 +233,7 @@ func (h *handler) updateRecipeDetails() http.HandlerFunc {
 			return nil, ErrDefault
 		}
 
-		mainContent := ui.RecipeDetailPartial(recipe, groupID)
+		mainContent := ui.RecipeDetailPartial(recipe, groupID, detailOptions(ctx))
 		recipes, err := h.GetGroupRecipes(ctx.context(), groupID)
 		if err != nil {
 			slog.Error("Could not get recipes")
@@ -205,26 +252,52 @@ func (h *handler) updateRecipeDetails() http.HandlerFunc {
 	})
 }
 
+// fetchErrorPartial tells the user why a recipe's page couldn't be read. The
+// link form swaps into the recipe list, so the message goes to the detail pane.
+func fetchErrorPartial(ctx requestContext, err error) Node {
+	ctx.w.Header().Set("HX-Retarget", "#recipe-detail")
+	reason := fetch.Reason(err)
+	if reason == nil {
+		reason = fetch.ErrUnreachable
+	}
+	message := fmt.Sprintf("We couldn't add that recipe, %s.", reason)
+	switch reason {
+	case fetch.ErrBlocked, fetch.ErrTimeout, fetch.ErrUnreachable:
+		message += " You can paste the recipe's text instead."
+	case fetch.ErrNotWebPage:
+		message += " If it's a PDF, download it and add it from the PDF tab."
+	}
+	return ui.ErrorPartial(message)
+}
+
+// addRecipeTimeout is how long adding a recipe by link may take, most of it
+// spent waiting for the recipe's site
+const addRecipeTimeout = 30 * time.Second
+
 func (h *handler) addNewRecipe() http.HandlerFunc {
 	return h.adapt(func(ctx requestContext) (Node, error) {
 		if !isUserActionAllowed(ctx.context()) {
 			return nil, ErrDefault
 		}
 		ctx.r.ParseForm()
-		url := ctx.r.FormValue("url")
+		url := strings.TrimSpace(ctx.r.FormValue("url"))
 
 		groupID, err := GetGroupID(ctx.r)
 		if err != nil {
 			return nil, ErrDefault
 		}
+		extendDeadline(ctx, addRecipeTimeout)
 
-		resp := req.MustGet(url)
-
-		doc, err := html.Parse(resp.Body)
+		page, err := h.fetcher.Page(ctx.context(), url)
 		if err != nil {
-			// ehh, maybe do nothing?
+			slog.Info("Could not fetch recipe page", "url", url, "error", err)
+			return fetchErrorPartial(ctx, err), nil
+		}
+		doc, err := html.Parse(bytes.NewReader(page.HTML))
+		if err != nil {
+			slog.Info("Could not parse recipe page", "url", url, "error", err)
+			return fetchErrorPartial(ctx, &fetch.Error{URL: url, Reason: fetch.ErrNotWebPage, Err: err}), nil
 		}
-		defer ctx.r.Body.Close()
 
 		meta := extractMeta(doc)
 		user := mw.GetUserFromContext(ctx.context())
@@ -234,27 +307,16 @@ func (h *handler) addNewRecipe() http.HandlerFunc {
 			return nil, ErrDefault
 		}
 
-		//extractedRecipe, err := parsing.ParseRecipe(resp.Bytes())
-		//if err != nil {
-		//slog.Error("Could not parse recipe", "error", err.Error())
-
-		text := parsing.HtmlToText(resp.Bytes())
-		go func() {
-			data := parsing.RecipeTextToJsonString(text)
-			if data == "" {
-				slog.Error("Could not get recipe data from LLM")
-				return
-			}
-			err := h.UpdateRecipeWithJSON(context.Background(), data, id) // FIXME - use better ctx
-			if err != nil {
-				slog.Error("Could not update db with recipe data", "error", err)
-				return
-			}
-			slog.Info("updated recipe with LLM data", "recipeID", id)
-		}()
+		// Keep a copy of the page, for when the site changes or goes away. The
+		// jobs read the recipe and its photo from it, and fetch the page again
+		// if it could not be saved.
+		if err := h.SaveSnapshot(ctx.context(), id, page.URL, page.HTML); err != nil {
+			slog.Error("Could not save recipe page", "recipeID", id, "error", err)
+		}
+		if err := h.ProcessRecipePage(ctx.context(), id); err != nil {
+			slog.Error("Could not queue reading recipe", "recipeID", id, "error", err)
+		}
 
-		//return nil, ErrDefault
-		//}
 		slog.Info("Added recipe", "userID", user.ID)
 
 		recipe, err := h.GetRecipeByID(ctx.context(), int32(id))
@@ -275,7 +337,7 @@ func (h *handler) addNewRecipe() http.HandlerFunc {
 		listContent := Div(
 			ID("recipe-detail"),
 			Attr("hx-swap-oob", "true"), // Out-of-band swap
-			ui.RecipeDetailPartial(recipe, groupID),
+			ui.RecipeDetailPartial(recipe, groupID, detailOptions(ctx)),
 		)
 
 		// Combine both parts in the response
@@ -283,6 +345,370 @@ func (h *handler) addNewRecipe() http.HandlerFunc {
 	})
 }
 
+// pastedTextWait is how long reading pasted text is waited for before the review
+// editor is shown, text that needs the LLM is finished in the background
+const pastedTextWait = 2 * time.Second
+
+// waitForRecipe checks back on a recipe being read in the background until it
+// is done or failed, or wait is up
+func (h *handler) waitForRecipe(ctx context.Context, recipeID int, wait time.Duration) {
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) {
+		recipe, err := h.GetRecipeByID(ctx, int32(recipeID))
+		if err != nil || (recipe.Status != model.ExtractionPending && recipe.Status != model.ExtractionRunning) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func (h *handler) addPastedRecipe() http.HandlerFunc {
+	return h.adapt(func(ctx requestContext) (Node, error) {
+		if !isUserActionAllowed(ctx.context()) {
+			return nil, ErrDefault
+		}
+		groupID, err := GetGroupID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		ctx.r.ParseForm()
+		text := strings.TrimSpace(ctx.r.FormValue("text"))
+		if text == "" {
+			return ui.ErrorPartial("Paste a recipe to add it."), nil
+		}
+		if len(text) > ui.MaxPastedRecipeLength {
+			return ui.ErrorPartial("That recipe is too long, try pasting just the recipe."), nil
+		}
+
+		name := "Pasted recipe"
+		if parsed, _ := parsing.ParseRecipeText(text); parsed != nil && parsed.Title != "" {
+			name = parsed.Title
+		}
+		user := mw.GetUserFromContext(ctx.context())
+		id, err := h.AddRecipe(ctx.context(), "", name, "", "", user.ID, groupID)
+		if err != nil {
+			slog.Error("Could not add pasted recipe", "error", err.Error())
+			return nil, ErrDefault
+		}
+		slog.Info("Added pasted recipe", "userID", user.ID)
+
+		// Clear text is read at once, the review editor waits for the rest
+		if err := h.ProcessRecipeText(ctx.context(), id, text); err != nil {
+			slog.Error("Could not queue reading pasted recipe", "recipeID", id, "error", err)
+		}
+		h.waitForRecipe(ctx.context(), id, pastedTextWait)
+
+		recipe, err := h.GetRecipeByID(ctx.context(), int32(id))
+		if err != nil {
+			slog.Error("Could not get recipe", "error", err.Error())
+			return nil, ErrDefault
+		}
+		recipes, err := h.GetGroupRecipes(ctx.context(), groupID)
+		if err != nil {
+			slog.Error("Could not get recipes", "error", err.Error())
+			return nil, ErrDefault
+		}
+
+		listContent := Div(
+			ID("recipe-list"),
+			Attr("hx-swap-oob", "true"), // Out-of-band swap
+			ui.RecipeListPartial(recipes, id, groupID),
+		)
+		return Div(ui.RecipeReviewPartial(recipe, groupID), listContent), nil
+	})
+}
+
+func (h *handler) readRecipePDF() http.HandlerFunc {
+	return h.adapt(func(ctx requestContext) (Node, error) {
+		if !isUserActionAllowed(ctx.context()) {
+			return nil, ErrDefault
+		}
+		groupID, err := GetGroupID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+
+		ctx.r.Body = http.MaxBytesReader(ctx.w, ctx.r.Body, ui.MaxPDFBytes+(1<<20))
+		if err := ctx.r.ParseMultipartForm(1 << 20); err != nil {
+			return ui.ErrorPartial(fmt.Sprintf("Choose a PDF of up to %d MB.", ui.MaxPDFBytes>>20)), nil
+		}
+		file, header, err := ctx.r.FormFile("file")
+		if err != nil {
+			return ui.ErrorPartial("Choose a PDF to add recipes from."), nil
+		}
+		defer file.Close()
+
+		pages, err := parsing.PDFPages(file, header.Size)
+		if err != nil {
+			slog.Info("Could not read PDF", "file", header.Filename, "error", err)
+			return ui.ErrorPartial("We couldn't read any text in that PDF. Scanned pages can't be read yet."), nil
+		}
+		texts := parsing.SplitRecipeText(pages)
+		if len(texts) > ui.MaxPDFRecipes {
+			texts = texts[:ui.MaxPDFRecipes]
+		}
+
+		recipes := make([]ui.PDFRecipe, 0, len(texts))
+		for _, text := range texts {
+			if len(text) > ui.MaxPastedRecipeLength {
+				text = strings.ToValidUTF8(text[:ui.MaxPastedRecipeLength], "")
+			}
+			parsed, _ := parsing.ParseRecipeText(text)
+			recipes = append(recipes, ui.PDFRecipe{Text: text, Parsed: parsed})
+		}
+		slog.Info("Read PDF", "file", header.Filename, "pages", len(pages), "recipes", len(recipes))
+		return ui.PDFReviewPartial(groupID, header.Filename, recipes), nil
+	})
+}
+
+func (h *handler) addPDFRecipes() http.HandlerFunc {
+	return h.adapt(func(ctx requestContext) (Node, error) {
+		if !isUserActionAllowed(ctx.context()) {
+			return nil, ErrDefault
+		}
+		groupID, err := GetGroupID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		if err := ctx.r.ParseForm(); err != nil {
+			return nil, ErrDefault
+		}
+		texts := ctx.r.Form["text"]
+		if len(texts) == 0 {
+			return ui.ErrorPartial("Choose at least one recipe to add."), nil
+		}
+		if len(texts) > ui.MaxPDFRecipes {
+			texts = texts[:ui.MaxPDFRecipes]
+		}
+
+		user := mw.GetUserFromContext(ctx.context())
+		var firstID int
+		for _, text := range texts {
+			text = strings.TrimSpace(text)
+			if text == "" || len(text) > ui.MaxPastedRecipeLength {
+				continue
+			}
+			name := "Recipe from PDF"
+			if parsed, _ := parsing.ParseRecipeText(text); parsed != nil && parsed.Title != "" {
+				name = parsed.Title
+			}
+			id, err := h.AddRecipe(ctx.context(), "", name, "", "", user.ID, groupID)
+			if err != nil {
+				slog.Error("Could not add recipe from PDF", "error", err.Error())
+				return nil, ErrDefault
+			}
+			if firstID == 0 {
+				firstID = id
+			}
+			if err := h.ProcessRecipeText(ctx.context(), id, text); err != nil {
+				slog.Error("Could not queue reading recipe from PDF", "recipeID", id, "error", err)
+			}
+		}
+		if firstID == 0 {
+			return ui.ErrorPartial("Choose at least one recipe to add."), nil
+		}
+		slog.Info("Added recipes from PDF", "userID", user.ID, "count", len(texts))
+
+		recipe, err := h.GetRecipeByID(ctx.context(), int32(firstID))
+		if err != nil {
+			slog.Error("Could not get recipe", "error", err.Error())
+			return nil, ErrDefault
+		}
+		recipes, err := h.GetGroupRecipes(ctx.context(), groupID)
+		if err != nil {
+			slog.Error("Could not get recipes", "error", err.Error())
+			return nil, ErrDefault
+		}
+
+		listContent := Div(
+			ID("recipe-list"),
+			Attr("hx-swap-oob", "true"), // Out-of-band swap
+			ui.RecipeListPartial(recipes, firstID, groupID),
+		)
+		return Div(ui.RecipeDetailPartial(recipe, groupID, detailOptions(ctx)), listContent), nil
+	})
+}
+
+// importTimeout is how long an export has to upload and be added, much longer
+// than other requests get
+const importTimeout = 5 * time.Minute
+
+// extendDeadline gives a slow request longer than the server's usual timeouts
+func extendDeadline(ctx requestContext, timeout time.Duration) {
+	controller := http.NewResponseController(ctx.w)
+	deadline := time.Now().Add(timeout)
+	if err := errors.Join(controller.SetReadDeadline(deadline), controller.SetWriteDeadline(deadline)); err != nil {
+		slog.Info("Could not extend deadline", "path", ctx.r.URL.Path, "error", err)
+	}
+}
+
+func (h *handler) importRecipes() http.HandlerFunc {
+	return h.adapt(func(ctx requestContext) (Node, error) {
+		if !isUserActionAllowed(ctx.context()) {
+			return nil, ErrDefault
+		}
+		groupID, err := GetGroupID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		extendDeadline(ctx, importTimeout)
+
+		ctx.r.Body = http.MaxBytesReader(ctx.w, ctx.r.Body, ui.MaxImportBytes+(1<<20))
+		if err := ctx.r.ParseMultipartForm(32 << 20); err != nil {
+			return ui.ErrorPartial(fmt.Sprintf("Choose an export of up to %d MB.", ui.MaxImportBytes>>20)), nil
+		}
+		user := mw.GetUserFromContext(ctx.context())
+		target := groupID
+		if group := ctx.r.FormValue("group"); group != "" {
+			target, err = strconv.Atoi(group)
+			if err != nil {
+				return nil, ErrDefault
+			}
+			if ok, err := h.IsUserInGroup(ctx.context(), target, user.ID); err != nil || !ok {
+				return nil, ErrDefault
+			}
+		}
+		file, header, err := ctx.r.FormFile("file")
+		if err != nil {
+			return ui.ErrorPartial("Choose an export file to import."), nil
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, ErrDefault
+		}
+
+		format, items, err := importer.Read(header.Filename, data)
+		if err != nil {
+			slog.Info("Could not read export", "file", header.Filename, "error", err)
+			if errors.Is(err, importer.ErrUnknownFormat) {
+				return ui.ErrorPartial("We can't read that file. Export from Paprika, Mealie, Tandoor, MealMaster or Recipe Keeper and upload the file it gives you, or upload a .cook file or a zip of them."), nil
+			}
+			return ui.ErrorPartial(fmt.Sprintf("We couldn't read that export: %v", err)), nil
+		}
+
+		results := make([]ui.ImportResult, 0, len(items))
+		imported := 0
+		for _, item := range items {
+			result := ui.ImportResult{Name: item.Name, Problems: item.Problems}
+			id, err := h.ImportRecipe(ctx.context(), item, user.ID, target)
+			if err != nil {
+				slog.Error("Could not import recipe", "name", item.Name, "error", err)
+				result.Error = "Something went wrong saving this recipe."
+				results = append(results, result)
+				continue
+			}
+			result.RecipeID = id
+			imported++
+			// Photos are thumbnailed by the job queue, after the report is shown
+			if err := h.ProcessImportedPhoto(ctx.context(), id, item.Photo, item.ImageURL); err != nil {
+				slog.Info("Could not queue imported photo", "recipeID", id, "error", err)
+			}
+			results = append(results, result)
+		}
+		slog.Info("Imported recipes", "userID", user.ID, "groupID", target, "format", format, "count", imported)
+
+		report := ui.ImportReportPartial(target, string(format), results)
+		if target != groupID {
+			return report, nil
+		}
+		recipes, err := h.GetGroupRecipes(ctx.context(), groupID)
+		if err != nil {
+			slog.Error("Could not get recipes", "error", err.Error())
+			return nil, ErrDefault
+		}
+		listContent := Div(
+			ID("recipe-list"),
+			Attr("hx-swap-oob", "true"), // Out-of-band swap
+			ui.RecipeListPartial(recipes, 0, groupID),
+		)
+		return Div(report, listContent), nil
+	})
+}
+
+func (h *handler) reviewRecipe() http.HandlerFunc {
+	return h.adapt(func(ctx requestContext) (Node, error) {
+		if !isUserActionAllowed(ctx.context()) {
+			return nil, ErrDefault
+		}
+		recipeID, err := getRecipeID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		groupID, err := GetGroupID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		recipe, err := h.GetRecipeByID(ctx.context(), int32(recipeID))
+		if err != nil || recipe.GroupID != groupID {
+			return ui.ErrorPartial("Recipe not found"), nil
+		}
+		return ui.RecipeReviewPartial(recipe, groupID), nil
+	})
+}
+
+func (h *handler) saveReviewedRecipe() http.HandlerFunc {
+	return h.adapt(func(ctx requestContext) (Node, error) {
+		if !isUserActionAllowed(ctx.context()) {
+			return nil, ErrDefault
+		}
+		recipeID, err := getRecipeID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		groupID, err := GetGroupID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		existing, err := h.GetRecipeByID(ctx.context(), int32(recipeID))
+		if err != nil || existing.GroupID != groupID {
+			return ui.ErrorPartial("Recipe not found"), nil
+		}
+		if err := ctx.r.ParseForm(); err != nil {
+			return nil, ErrDefault
+		}
+
+		servings, _ := strconv.Atoi(ctx.r.FormValue("servings"))
+		err = h.SaveReviewedRecipe(ctx.context(), recipeID, service.ReviewedRecipe{
+			Name:         strings.TrimSpace(ctx.r.FormValue("name")),
+			Servings:     max(0, min(servings, ui.MaxServings)),
+			Ingredients:  formLines(ctx.r.FormValue("ingredients")),
+			Instructions: formLines(ctx.r.FormValue("instructions")),
+			Notes:        formLines(ctx.r.FormValue("notes")),
+		})
+		if err != nil {
+			slog.Error("Could not save reviewed recipe", "recipeID", recipeID, "error", err)
+			return nil, ErrDefault
+		}
+
+		recipe, err := h.GetRecipeByID(ctx.context(), int32(recipeID))
+		if err != nil {
+			return ui.ErrorPartial("Recipe not found"), nil
+		}
+		listContent := Div(
+			ID(fmt.Sprintf("recipe-list-item-%d", recipe.ID)),
+			Attr("hx-swap-oob", "true"), // Out-of-band swap
+			ui.RecipeListItemPartial(recipe, recipeID, groupID),
+		)
+		return Div(ui.RecipeDetailPartial(recipe, groupID, detailOptions(ctx)), listContent), nil
+	})
+}
+
+// formLines splits a textarea into its non-empty lines
+func formLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
 func (h *handler) getRecipeDetailView() http.HandlerFunc {
 	return h.adapt(func(ctx requestContext) (Node, error) {
 		if !isUserActionAllowed(ctx.context()) {
@@ -304,7 +730,7 @@ func (h *handler) getRecipeDetailView() http.HandlerFunc {
 			return ui.ErrorPartial("Recipe not found"), nil
 		}
 
-		mainContent := ui.RecipeDetailPartial(recipe, groupID)
+		mainContent := ui.RecipeDetailPartial(recipe, groupID, detailOptions(ctx))
 
 		//recipes, err := s.GetRecipes(r.Context())
 		listItemID := fmt.Sprintf("recipe-list-item-%d", recipe.ID)
@@ -320,6 +746,132 @@ func (h *handler) getRecipeDetailView() http.HandlerFunc {
 	})
 }
 
+// getRecipeListItem shows a recipe's entry in the recipe list, highlighted with
+// ?selected=true
+func (h *handler) getRecipeListItem() http.HandlerFunc {
+	return h.adapt(func(ctx requestContext) (Node, error) {
+		if !isUserActionAllowed(ctx.context()) {
+			return nil, ErrDefault
+		}
+		recipeID, err := getRecipeID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		groupID, err := GetGroupID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		recipe, err := h.GetRecipeByID(ctx.context(), int32(recipeID))
+		if err != nil || recipe.GroupID != groupID {
+			return nil, nil // Gone, so is its entry
+		}
+
+		selectedID := 0
+		if ctx.queryParam("selected") == "true" {
+			selectedID = recipe.ID
+		}
+		return ui.RecipeListItemPartial(recipe, selectedID, groupID), nil
+	})
+}
+
+// retryRecipe reads a recipe added by link again, after it failed
+func (h *handler) retryRecipe() http.HandlerFunc {
+	return h.adapt(func(ctx requestContext) (Node, error) {
+		if !isUserActionAllowed(ctx.context()) {
+			return nil, ErrDefault
+		}
+		recipeID, err := getRecipeID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		groupID, err := GetGroupID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		recipe, err := h.GetRecipeByID(ctx.context(), int32(recipeID))
+		if err != nil || recipe.GroupID != groupID {
+			return ui.ErrorPartial("Recipe not found"), nil
+		}
+
+		if err := h.RetryRecipe(ctx.context(), recipeID); err != nil {
+			slog.Error("Could not retry recipe", "recipeID", recipeID, "error", err)
+			return ui.ErrorPartial("We couldn't try that recipe again, please try later."), nil
+		}
+		slog.Info("Retrying recipe", "recipeID", recipeID)
+
+		recipe, err = h.GetRecipeByID(ctx.context(), int32(recipeID))
+		if err != nil {
+			return ui.ErrorPartial("Recipe not found"), nil
+		}
+		return ui.RecipeDetailPartial(recipe, groupID, detailOptions(ctx)), nil
+	})
+}
+
+func (h *handler) setRecipeUnits() http.HandlerFunc {
+	return h.adapt(func(ctx requestContext) (Node, error) {
+		if !isUserActionAllowed(ctx.context()) {
+			return nil, ErrDefault
+		}
+		recipeID, err := getRecipeID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		groupID, err := GetGroupID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		recipe, err := h.GetRecipeByID(ctx.context(), int32(recipeID))
+		if err != nil || recipe.GroupID != groupID {
+			return ui.ErrorPartial("Recipe not found"), nil
+		}
+		ctx.r.ParseForm()
+
+		user := mw.GetUserFromContext(ctx.context())
+		user.Units = parsing.ParseUnitSystem(ctx.r.FormValue("units"))
+		err = h.SetUnitSystem(ctx.context(), user.ID, user.Units)
+		if err != nil {
+			slog.Error("Could not save unit preference", "error", err)
+			return nil, ErrDefault
+		}
+		opts := detailOptions(ctx)
+		opts.Servings = servingsParam(ctx.r.FormValue("servings"))
+		return ui.RecipeDetailPartial(recipe, groupID, opts), nil
+	})
+}
+
+func (h *handler) scaleRecipe() http.HandlerFunc {
+	return h.adapt(func(ctx requestContext) (Node, error) {
+		if !isUserActionAllowed(ctx.context()) {
+			return nil, ErrDefault
+		}
+		recipeID, err := getRecipeID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		groupID, err := GetGroupID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+
+		recipe, err := h.GetRecipeByID(ctx.context(), int32(recipeID))
+		if err != nil || recipe.GroupID != groupID {
+			return ui.ErrorPartial("Recipe not found"), nil
+		}
+		opts := detailOptions(ctx)
+		opts.Servings = servingsParam(ctx.queryParam("servings"))
+		return ui.RecipeDetailPartial(recipe, groupID, opts), nil
+	})
+}
+
+// servingsParam reads a servings count, 0 meaning the recipe's own
+func servingsParam(value string) int {
+	servings, err := strconv.Atoi(value)
+	if err != nil || servings < 1 {
+		return 0
+	}
+	return min(servings, ui.MaxServings)
+}
+
 func (h *handler) updateRecipe() http.HandlerFunc {
 	slog.Info("recipe update selected")
 	return h.adapt(func(ctx requestContext) (Node, error) {
@@ -353,7 +905,16 @@ func (h *handler) showNewRecipeModal() http.HandlerFunc {
 		if err != nil {
 			return nil, ErrDefault
 		}
-		return ui.RecipeModal(group_id), nil
+		mode := ctx.queryParam("mode")
+		var groups []model.Group
+		if mode == ui.RecipeModalImport {
+			user := mw.GetUserFromContext(ctx.context())
+			if groups, err = h.GetUserGroups(ctx.context(), user.ID); err != nil {
+				slog.Error("Could not get groups", "error", err.Error())
+				return nil, ErrDefault
+			}
+		}
+		return ui.RecipeModal(group_id, mode, groups), nil
 	})
 }
 
@@ -403,7 +964,7 @@ func (h *handler) sendInvite() http.HandlerFunc {
 		listContent := Div(
 			ID("recipe-detail"),
 			Attr("hx-swap-oob", "true"), // Out-of-band swap
-			ui.RecipeDetailPartial(recipe, groupID),
+			ui.RecipeDetailPartial(recipe, groupID, detailOptions(ctx)),
 		)
 
 		// Combine both parts in the response
@@ -411,6 +972,15 @@ func (h *handler) sendInvite() http.HandlerFunc {
 	})
 }
 
+// detailOptions gives how the logged in user wants recipes presented
+func detailOptions(ctx requestContext) ui.DetailOptions {
+	var opts ui.DetailOptions
+	if user := mw.GetUserFromContext(ctx.context()); user != nil {
+		opts.Units = user.Units
+	}
+	return opts
+}
+
 func isUserActionAllowed(ctx context.Context) bool {
 	authorizedAny := ctx.Value(mw.CtxGroupAuthorizedKey{})
 	authorized, ok := authorizedAny.(bool)
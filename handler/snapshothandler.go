@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	. "maragu.dev/gomponents"
+
+	"recipeze/parsing"
+	"recipeze/service"
+	"recipeze/ui"
+)
+
+// snapshotPolicy keeps a saved page from running anything, even if something
+// got past parsing.SanitizeSnapshot. Links may still open in a new tab.
+const snapshotPolicy = "sandbox allow-popups allow-popups-to-escape-sandbox; script-src 'none'; object-src 'none'; frame-ancestors 'self'"
+
+// getRecipeSnapshot shows the copy of a recipe's page saved when it was added,
+// or the page's text with ?view=text
+func (h *handler) getRecipeSnapshot() http.HandlerFunc {
+	return h.adapt(func(ctx requestContext) (Node, error) {
+		if !isUserActionAllowed(ctx.context()) {
+			return nil, ErrDefault
+		}
+		groupID, err := GetGroupID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		recipeID, err := getRecipeID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		recipe, err := h.GetRecipeByID(ctx.context(), int32(recipeID))
+		if err != nil || recipe.GroupID != groupID {
+			return nil, ErrDefault
+		}
+
+		snapshot, err := h.GetSnapshot(ctx.context(), recipeID)
+		if err != nil && !errors.Is(err, service.ErrNoSnapshot) {
+			slog.Error("Could not get saved recipe page", "recipeID", recipeID, "error", err)
+			return nil, ErrDefault
+		}
+		props := ui.PageProps{IncludeHeader: true, GroupID: groupID}
+		return ui.SnapshotPage(props, recipe, snapshot, ctx.r.URL.Query().Get("view") == "text"), nil
+	})
+}
+
+// getRecipeSnapshotPage sends the saved page itself, for the frame on the
+// snapshot page
+func (h *handler) getRecipeSnapshotPage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isUserActionAllowed(r.Context()) {
+			http.NotFound(w, r)
+			return
+		}
+		groupID, err := GetGroupID(r)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		recipeID, err := getRecipeID(r)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		recipe, err := h.GetRecipeByID(r.Context(), int32(recipeID))
+		if err != nil || recipe.GroupID != groupID {
+			http.NotFound(w, r)
+			return
+		}
+
+		snapshot, err := h.GetSnapshot(r.Context(), recipeID)
+		if errors.Is(err, service.ErrNoSnapshot) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			slog.Error("Could not get saved recipe page", "recipeID", recipeID, "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		page, err := parsing.SanitizeSnapshot(snapshot.HTML, snapshot.URL)
+		if err != nil {
+			slog.Error("Could not clean saved recipe page", "recipeID", recipeID, "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+		w.Header().Set("Content-Security-Policy", snapshotPolicy)
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "private, max-age=3600")
+		if _, err := w.Write(page); err != nil {
+			slog.Info("Could not send saved recipe page", "recipeID", recipeID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	. "maragu.dev/gomponents"
+
+	mw "recipeze/middleware"
+	"recipeze/ui"
+)
+
+// getGroupUsage shows a group's admins what the LLM has cost it
+func (h *handler) getGroupUsage() http.HandlerFunc {
+	return h.adapt(func(ctx requestContext) (Node, error) {
+		if !isUserActionAllowed(ctx.context()) {
+			return nil, ErrDefault
+		}
+		groupID, err := GetGroupID(ctx.r)
+		if err != nil {
+			return nil, ErrDefault
+		}
+		user := mw.GetUserFromContext(ctx.context())
+		isAdmin, err := h.IsGroupAdmin(ctx.context(), groupID, user.ID)
+		if err != nil || !isAdmin {
+			return nil, ErrDefault
+		}
+
+		report, err := h.GetGroupUsage(ctx.context(), groupID)
+		if err != nil {
+			slog.Error("Could not get LLM usage", "groupID", groupID, "error", err)
+			return nil, ErrDefault
+		}
+		return ui.UsagePage(ui.PageProps{IncludeHeader: true, GroupID: groupID}, groupID, report), nil
+	})
+}
@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"path"
+	"strings"
+
+	"recipeze/parsing"
+)
+
+// cooklangPhotoExtensions are the photos Cooklang apps look for next to a
+// recipe, named like it: "Pancakes.jpg" for "Pancakes.cook"
+var cooklangPhotoExtensions = []string{".jpg", ".jpeg", ".png", ".webp"}
+
+// readCooklangArchive reads a zipped folder of Cooklang files, with the photos
+// that sit next to them
+func readCooklangArchive(archive *zipArchive) ([]Item, error) {
+	var items []Item
+	for _, file := range archive.File {
+		if tooMany(items) {
+			break
+		}
+		if !strings.EqualFold(path.Ext(file.Name), ".cook") || strings.HasPrefix(file.Name, "__MACOSX/") {
+			continue
+		}
+		data, err := archive.read(file)
+		if err != nil {
+			return nil, err
+		}
+		item := readCooklang(file.Name, data)
+		if isURL(item.ImageURL) {
+			items = append(items, item)
+			continue
+		}
+		base := strings.TrimSuffix(file.Name, path.Ext(file.Name))
+		candidates := make([]string, 0, len(cooklangPhotoExtensions)+1)
+		if item.ImageURL != "" {
+			candidates = append(candidates, path.Join(path.Dir(file.Name), item.ImageURL))
+		}
+		for _, ext := range cooklangPhotoExtensions {
+			candidates = append(candidates, base+ext)
+		}
+		item.ImageURL = ""
+		for _, name := range candidates {
+			if photo := archive.find(name); photo != nil {
+				if item.Photo, err = archive.read(photo); err != nil {
+					item.problem("Couldn't read the photo: %v", err)
+				}
+				break
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// readCooklang reads one Cooklang file. The file's name stands in for the
+// title, since Cooklang files often leave it out.
+func readCooklang(filename string, data []byte) Item {
+	name := strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+	recipe, err := parsing.ParseCooklang(string(data))
+	if err != nil {
+		item := Item{Name: name}
+		item.problem("Couldn't read this recipe: %v", err)
+		return item
+	}
+	if recipe.Title != "" {
+		name = recipe.Title
+	}
+	main := &recipe.Collection.Recipes[0]
+	item := Item{
+		Name:        name,
+		Description: recipe.Description,
+		ImageURL:    main.Image,
+		Collection:  recipe.Collection,
+	}
+	if isURL(recipe.Source) {
+		item.SourceURL = recipe.Source
+	} else if recipe.Source != "" {
+		main.Notes = append(main.Notes, "Source: "+recipe.Source)
+	}
+	if !isURL(main.Image) {
+		main.Image = ""
+	}
+	return item
+}
@@ -0,0 +1,323 @@
+// Package importer reads the export files of other recipe managers, so a
+// collection can be moved to Recipeze in one go.
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"recipeze/parsing"
+)
+
+// ErrUnknownFormat is returned for files that are not an export this package reads
+var ErrUnknownFormat = errors.New("not an export from a recipe manager we can read")
+
+const (
+	// MaxItems is the most recipes read from one export
+	MaxItems = 2000
+	// maxEntryBytes limits each file unpacked from an archive, and
+	// maxUnpackedBytes all of them together, nested archives included, so a
+	// small archive can't unpack into something huge
+	maxEntryBytes    = 20 << 20
+	maxUnpackedBytes = 200 << 20
+
+	// The sizes of the recipes table's columns
+	maxNameLength        = 255
+	maxURLLength         = 255
+	maxDescriptionLength = 10000
+)
+
+// Format is the recipe manager an export came from
+type Format string
+
+const (
+	FormatPaprika      Format = "Paprika"
+	FormatMealie       Format = "Mealie"
+	FormatTandoor      Format = "Tandoor"
+	FormatMealMaster   Format = "MealMaster"
+	FormatRecipeKeeper Format = "Recipe Keeper"
+	FormatCooklang     Format = "Cooklang"
+)
+
+// Item is one recipe read from an export
+type Item struct {
+	Name        string
+	SourceURL   string
+	Description string
+	ImageURL    string // Photo to download, when the export links to one
+	Photo       []byte // Photo packed in the export
+	Collection  *parsing.RecipeCollection
+	Problems    []string // What could not be carried over, for the import report
+}
+
+// problem notes something about the recipe that could not be carried over
+func (i *Item) problem(format string, args ...any) {
+	i.Problems = append(i.Problems, fmt.Sprintf(format, args...))
+}
+
+// Read works out which recipe manager made the export and reads its recipes
+func Read(filename string, data []byte) (Format, []Item, error) {
+	var format Format
+	var items []Item
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("PK")):
+		format, items, err = readArchive(data)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		format = FormatPaprika
+		var item Item
+		item, err = readPaprikaRecipe(data)
+		items = []Item{item}
+	case strings.EqualFold(path.Ext(filename), ".cook"):
+		format = FormatCooklang
+		item := readCooklang(filename, data)
+		if !isURL(item.ImageURL) {
+			// A photo next to the file was not uploaded with it
+			item.ImageURL = ""
+		}
+		items = []Item{item}
+	case isJSON(data):
+		format, items, err = readJSON(data)
+	case bytes.Contains(data, []byte("Meal-Master")) || bytes.HasPrefix(bytes.TrimSpace(data), []byte("MMMMM")):
+		format = FormatMealMaster
+		items, err = readMealMaster(data)
+	case bytes.Contains(data, []byte(`itemprop="recipeIngredients"`)):
+		format = FormatRecipeKeeper
+		items, err = readRecipeKeeper(data, nil)
+	default:
+		return "", nil, fmt.Errorf("%s: %w", filename, ErrUnknownFormat)
+	}
+	if err != nil {
+		return format, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if len(items) == 0 {
+		return format, nil, fmt.Errorf("%s: no recipes found in the %s export", filename, format)
+	}
+	if len(items) > MaxItems {
+		return format, nil, fmt.Errorf("%s: has %d recipes, at most %d can be imported at once", filename, len(items), MaxItems)
+	}
+	for i := range items {
+		finish(&items[i])
+	}
+	return format, items, nil
+}
+
+// readArchive tells the zip exports apart by the files in them
+func readArchive(data []byte) (Format, []Item, error) {
+	unpacked := int64(0)
+	archive, err := openZip(data, &unpacked)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not open archive: %w", err)
+	}
+	for _, file := range archive.File {
+		name := strings.ToLower(file.Name)
+		switch {
+		case strings.HasSuffix(name, ".paprikarecipe"):
+			items, err := readPaprika(archive)
+			return FormatPaprika, items, err
+		case strings.HasSuffix(name, ".cook"):
+			items, err := readCooklangArchive(archive)
+			return FormatCooklang, items, err
+		case path.Base(name) == "recipes.html":
+			items, err := readRecipeKeeperArchive(archive)
+			return FormatRecipeKeeper, items, err
+		case strings.HasSuffix(name, ".zip") || path.Base(name) == "recipe.json":
+			items, err := readTandoorArchive(archive, false)
+			return FormatTandoor, items, err
+		case strings.HasSuffix(name, ".json"):
+			items, err := readMealieArchive(archive)
+			return FormatMealie, items, err
+		}
+	}
+	return "", nil, ErrUnknownFormat
+}
+
+// zipArchive is an uploaded archive, or one inside it. Everything unpacked
+// from an upload counts towards one maxUnpackedBytes.
+type zipArchive struct {
+	*zip.Reader
+	unpacked *int64 // Bytes unpacked from the upload so far
+}
+
+// openZip opens an archive whose files count towards unpacked
+func openZip(data []byte, unpacked *int64) (*zipArchive, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return &zipArchive{Reader: reader, unpacked: unpacked}, nil
+}
+
+// read unpacks one file from the archive
+func (a *zipArchive) read(file *zip.File) ([]byte, error) {
+	left := maxUnpackedBytes - *a.unpacked
+	if left <= 0 {
+		return nil, fmt.Errorf("the export unpacks to more than %d bytes", maxUnpackedBytes)
+	}
+	r, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.Name, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, min(maxEntryBytes, left)+1))
+	*a.unpacked += int64(len(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.Name, err)
+	}
+	if len(data) > maxEntryBytes {
+		return nil, fmt.Errorf("%s is larger than %d bytes", file.Name, maxEntryBytes)
+	}
+	if int64(len(data)) > left {
+		return nil, fmt.Errorf("the export unpacks to more than %d bytes", maxUnpackedBytes)
+	}
+	return data, nil
+}
+
+// open unpacks an archive inside this one
+func (a *zipArchive) open(file *zip.File) (*zipArchive, error) {
+	data, err := a.read(file)
+	if err != nil {
+		return nil, err
+	}
+	return openZip(data, a.unpacked)
+}
+
+// find gives the file at name in the archive, ignoring case
+func (a *zipArchive) find(name string) *zip.File {
+	name = strings.TrimPrefix(path.Clean(name), "/")
+	for _, file := range a.File {
+		if strings.EqualFold(file.Name, name) {
+			return file
+		}
+	}
+	return nil
+}
+
+// tooMany tells if there are more items than can be imported, after which
+// reading more of the export is wasted
+func tooMany(items []Item) bool {
+	return len(items) > MaxItems
+}
+
+// finish makes every item safe to store: a name, fields that fit their
+// columns, the ingredient aisles and a note of times that could not be read
+func finish(item *Item) {
+	item.Name = truncate(strings.TrimSpace(item.Name), maxNameLength)
+	item.Description = truncate(item.Description, maxDescriptionLength)
+	if len(item.SourceURL) > maxURLLength {
+		item.problem("The source link is too long to keep: %s", item.SourceURL)
+		item.SourceURL = ""
+	}
+	if len(item.ImageURL) > maxURLLength {
+		item.ImageURL = ""
+	}
+	if item.Collection == nil || len(item.Collection.Recipes) == 0 {
+		item.Collection = &parsing.RecipeCollection{Recipes: []parsing.Recipe{{Ingredients: []parsing.Ingredient{}}}}
+	}
+	recipe := &item.Collection.Recipes[0]
+	if item.Name == "" {
+		item.Name = "Imported recipe"
+		item.problem("Recipe has no name")
+	}
+	if recipe.Name == "" {
+		recipe.Name = item.Name
+	}
+	if len(recipe.Ingredients) == 0 {
+		item.problem("No ingredients were found")
+	}
+	for _, field := range []struct{ label, value string }{
+		{"prep time", recipe.PrepTime},
+		{"cook time", recipe.CookTime},
+		{"total time", recipe.TotalTime},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if _, err := parsing.ParseDuration(field.value); err != nil {
+			item.problem("Couldn't read the %s %q", field.label, field.value)
+		}
+	}
+	item.Collection.Categorize()
+}
+
+// ingredients parses ingredient lines, keeping lines that aren't ingredients,
+// like "For the sauce:", as notes
+func ingredients(item *Item, lines []string) ([]parsing.Ingredient, []string) {
+	result := []parsing.Ingredient{}
+	var notes []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		ingredient := parsing.ParseIngredient(line)
+		heading := strings.HasSuffix(line, ":") && ingredient.Amount == nil
+		if ingredient.Name == "" || heading {
+			item.problem("Couldn't read the ingredient %q, it was kept in the notes", line)
+			notes = append(notes, line)
+			continue
+		}
+		result = append(result, ingredient)
+	}
+	return result, notes
+}
+
+// structuredIngredient reads an ingredient that the export already split into
+// parts, through the same parser as lines so units are named alike
+func structuredIngredient(amount *float64, unit, food, note string) parsing.Ingredient {
+	line := strings.TrimSpace(unit + " " + food)
+	if amount != nil {
+		line = strconv.FormatFloat(*amount, 'f', -1, 64) + " " + line
+	}
+	ingredient := parsing.ParseIngredient(line)
+	if ingredient.Name == "" {
+		ingredient.Name = strings.TrimSpace(food)
+	}
+	if note = strings.TrimSpace(note); note != "" {
+		ingredient.Notes = strings.TrimSpace(strings.TrimPrefix(ingredient.Notes+", "+note, ", "))
+	}
+	return ingredient
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
+
+// lines splits text into its non-empty lines
+func lines(text string) []string {
+	var result []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// minutes writes a number of minutes as a duration the parser reads back
+func minutes(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("PT%dM", n)
+}
+
+// isURL tells if a recipe's source is a link rather than the name of a book
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+func isJSON(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
@@ -0,0 +1,294 @@
+package importer_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/importer"
+	"recipeze/parsing"
+)
+
+// zipFiles packs files, by name, into a zip archive
+func zipFiles(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for name, data := range files {
+		f, err := w.Create(name)
+		is.NotError(t, err)
+		_, err = f.Write(data)
+		is.NotError(t, err)
+	}
+	is.NotError(t, w.Close())
+	return b.Bytes()
+}
+
+func gzipJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	is.NotError(t, json.NewEncoder(w).Encode(v))
+	is.NotError(t, w.Close())
+	return b.Bytes()
+}
+
+func readFile(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	is.NotError(t, err)
+	return data
+}
+
+// ingredientLines writes a recipe's ingredients back out as lines
+func ingredientLines(recipe parsing.Recipe) string {
+	var lines []string
+	for _, ingredient := range recipe.Ingredients {
+		lines = append(lines, parsing.FormatIngredient(ingredient))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestRead_paprika(t *testing.T) {
+	photo := []byte("not really a jpeg")
+	archive := zipFiles(t, map[string][]byte{
+		"Pancakes.paprikarecipe": gzipJSON(t, map[string]any{
+			"name":             "Pancakes",
+			"ingredients":      "1 cup flour\n2 eggs\n\nFor serving:",
+			"directions":       "Whisk everything.\n\nFry until golden.",
+			"notes":            "Double it for a crowd.",
+			"source":           "Smitten Kitchen",
+			"source_url":       "https://example.com/pancakes",
+			"servings":         "4 servings",
+			"prep_time":        "10 min",
+			"cook_time":        "ages",
+			"categories":       []string{"Breakfast", "Quick"},
+			"nutritional_info": "Calories: 220\nProtein: 6 g",
+			"photo_data":       base64.StdEncoding.EncodeToString(photo),
+		}),
+		"Broken.paprikarecipe": []byte("not gzip"),
+	})
+
+	format, items, err := importer.Read("My Recipes.paprikarecipes", archive)
+	is.NotError(t, err)
+	is.Equal(t, importer.FormatPaprika, format)
+	is.Equal(t, 2, len(items))
+
+	var pancakes, broken importer.Item
+	for _, item := range items {
+		if item.Name == "Pancakes" {
+			pancakes = item
+		} else {
+			broken = item
+		}
+	}
+	recipe := pancakes.Collection.Recipes[0]
+	is.Equal(t, "https://example.com/pancakes", pancakes.SourceURL)
+	is.Equal(t, "1 cup flour\n2 egg", ingredientLines(recipe))
+	is.Equal(t, "Whisk everything.|Fry until golden.", strings.Join(recipe.Instructions, "|"))
+	is.Equal(t, "Double it for a crowd.|For serving:|Source: Smitten Kitchen|Nutrition: Calories: 220, Protein: 6 g", strings.Join(recipe.Notes, "|"))
+	is.Equal(t, "Breakfast,Quick", strings.Join(recipe.Tags, ","))
+	is.Equal(t, 4, recipe.Servings)
+	is.Equal(t, string(photo), string(pancakes.Photo))
+	is.Equal(t, `Couldn't read the ingredient "For serving:", it was kept in the notes|Couldn't read the cook time "ages"`, strings.Join(pancakes.Problems, "|"))
+
+	is.Equal(t, "Broken", broken.Name)
+	is.True(t, len(broken.Problems) > 0)
+}
+
+func TestRead_mealie(t *testing.T) {
+	photo := []byte("webp bytes")
+	archive := zipFiles(t, map[string][]byte{
+		"recipes/chicken-tikka/chicken-tikka.json":        readFile(t, "mealie.json"),
+		"recipes/chicken-tikka/images/original.webp":      photo,
+		"recipes/chicken-tikka/images/min-original.webp":  []byte("small"),
+		"recipes/chicken-tikka/images/tiny-original.webp": []byte("tiny"),
+	})
+
+	format, items, err := importer.Read("mealie.zip", archive)
+	is.NotError(t, err)
+	is.Equal(t, importer.FormatMealie, format)
+	is.Equal(t, 1, len(items))
+
+	item := items[0]
+	recipe := item.Collection.Recipes[0]
+	is.Equal(t, "Chicken Tikka", item.Name)
+	is.Equal(t, "https://example.com/chicken-tikka", item.SourceURL)
+	is.Equal(t, "Weeknight curry.", item.Description)
+	is.Equal(t, "2 lb chicken thigh, boneless\n1 cup plain yogurt", ingredientLines(recipe))
+	is.Equal(t, "Marinate the chicken in the yogurt.|Grill until charred.", strings.Join(recipe.Instructions, "|"))
+	is.Equal(t, "For the sauce:|Tip: Marinate overnight.", strings.Join(recipe.Notes, "|"))
+	is.Equal(t, "Dinner,Curry,Spicy", strings.Join(recipe.Tags, ","))
+	is.Equal(t, 4, recipe.Servings)
+	is.Equal(t, "1 hour", recipe.CookTime)
+	is.Equal(t, 420.0, *recipe.Nutrition.Calories)
+	is.Equal(t, string(photo), string(item.Photo))
+	is.Equal(t, 2, len(item.Problems)) // The sauce heading and the total time
+}
+
+func TestRead_tandoor(t *testing.T) {
+	inner := zipFiles(t, map[string][]byte{
+		"recipe.json": readFile(t, "tandoor.json"),
+		"image.jpg":   []byte("jpeg bytes"),
+	})
+	archive := zipFiles(t, map[string][]byte{"1.zip": inner})
+
+	format, items, err := importer.Read("export.zip", archive)
+	is.NotError(t, err)
+	is.Equal(t, importer.FormatTandoor, format)
+	is.Equal(t, 1, len(items))
+
+	item := items[0]
+	recipe := item.Collection.Recipes[0]
+	is.Equal(t, "Focaccia", item.Name)
+	is.Equal(t, "https://example.com/focaccia", item.SourceURL)
+	is.Equal(t, "500 g bread flour\nsalt, to taste\n3 tbsp olive oil", ingredientLines(recipe))
+	is.Equal(t, 3, len(recipe.Instructions))
+	is.Equal(t, "PT30M", recipe.PrepTime)
+	is.Equal(t, "PT120M", recipe.CookTime)
+	is.Equal(t, 8, recipe.Servings)
+	is.Equal(t, "jpeg bytes", string(item.Photo))
+	is.Equal(t, 0, len(item.Problems))
+}
+
+func TestRead_tandoorNested(t *testing.T) {
+	inner := zipFiles(t, map[string][]byte{"recipe.json": readFile(t, "tandoor.json")})
+	twice := zipFiles(t, map[string][]byte{"2.zip": inner})
+	archive := zipFiles(t, map[string][]byte{"1.zip": inner, "nested.zip": twice})
+
+	_, items, err := importer.Read("export.zip", archive)
+	is.NotError(t, err)
+	is.Equal(t, 2, len(items))
+	names := map[string][]string{}
+	for _, item := range items {
+		names[item.Name] = item.Problems
+	}
+	is.Equal(t, 0, len(names["Focaccia"]))
+	is.True(t, len(names["2"]) > 0)
+	is.Equal(t, "Couldn't open this recipe: it is packed more than once", names["2"][0])
+}
+
+func TestRead_unpackedSize(t *testing.T) {
+	// Each file is under the limit for one file, together they are too much
+	files := map[string][]byte{}
+	large := make([]byte, 19<<20)
+	for i := range 11 {
+		files[fmt.Sprintf("%d.paprikarecipe", i)] = large
+	}
+
+	_, _, err := importer.Read("export.zip", zipFiles(t, files))
+	is.True(t, err != nil)
+	is.True(t, strings.Contains(err.Error(), "unpacks to more than"))
+}
+
+func TestRead_tandoorJSON(t *testing.T) {
+	format, items, err := importer.Read("recipe.json", readFile(t, "tandoor.json"))
+	is.NotError(t, err)
+	is.Equal(t, importer.FormatTandoor, format)
+	is.Equal(t, "Focaccia", items[0].Name)
+}
+
+func TestRead_mealMaster(t *testing.T) {
+	format, items, err := importer.Read("recipes.mmf", readFile(t, "mealmaster.txt"))
+	is.NotError(t, err)
+	is.Equal(t, importer.FormatMealMaster, format)
+	is.Equal(t, 2, len(items))
+
+	crisp := items[0].Collection.Recipes[0]
+	is.Equal(t, "Apple Crisp", items[0].Name)
+	is.Equal(t, 6, crisp.Servings)
+	is.Equal(t, "Desserts,Fruits", strings.Join(crisp.Tags, ","))
+	is.Equal(t, "6 apple, peeled and sliced\n1 cup rolled oats\n½ cup brown sugar\n1 tsp cinnamon\n4 tbsp butter, cold and cut into small pieces\n1 pinch salt", ingredientLines(crisp))
+	is.Equal(t, "Heat the oven to 350F. Put the apples in a buttered dish.|Rub the oats, sugar, cinnamon and butter together and scatter over the apples. Bake for 40 minutes.", strings.Join(crisp.Instructions, "|"))
+
+	tea := items[1].Collection.Recipes[0]
+	is.Equal(t, "Iced Tea", items[1].Name)
+	is.Equal(t, 4, tea.Servings)
+	is.Equal(t, "4 tea bag\n1 quart water", ingredientLines(tea))
+}
+
+func TestRead_recipeKeeper(t *testing.T) {
+	archive := zipFiles(t, map[string][]byte{
+		"recipes.html":      readFile(t, "recipekeeper.html"),
+		"images/banana.jpg": []byte("banana photo"),
+	})
+
+	format, items, err := importer.Read("RecipeKeeper.zip", archive)
+	is.NotError(t, err)
+	is.Equal(t, importer.FormatRecipeKeeper, format)
+	is.Equal(t, 2, len(items))
+
+	bread := items[0].Collection.Recipes[0]
+	is.Equal(t, "Banana Bread", items[0].Name)
+	is.Equal(t, "3 ripe banana\n2 cup flour\n1 tsp baking soda", ingredientLines(bread))
+	is.Equal(t, "Mash the bananas.|Stir in the rest and bake.", strings.Join(bread.Instructions, "|"))
+	is.Equal(t, "Freezes well.|Source: Grandma's notebook", strings.Join(bread.Notes, "|"))
+	is.Equal(t, "Breakfast,Baking", strings.Join(bread.Tags, ","))
+	is.Equal(t, "PT15M", bread.PrepTime)
+	is.Equal(t, 1, bread.Servings)
+	is.Equal(t, "banana photo", string(items[0].Photo))
+
+	is.Equal(t, "https://example.com/pancakes", items[1].SourceURL)
+
+	t.Run("notes photos missing from an HTML upload", func(t *testing.T) {
+		_, items, err := importer.Read("recipes.html", readFile(t, "recipekeeper.html"))
+		is.NotError(t, err)
+		is.Equal(t, 1, len(items[0].Problems))
+	})
+}
+
+func TestRead_cooklang(t *testing.T) {
+	archive := zipFiles(t, map[string][]byte{
+		"Breakfast/Pancakes.cook": readFile(t, "pancakes.cook"),
+		"Breakfast/Pancakes.jpg":  []byte("pancake photo"),
+		"Dinner/Tomato Soup.cook": []byte("Simmer @tomatoes{6} with @stock{500%ml}.\n"),
+		"Dinner/Empty.cook":       []byte("-- nothing here yet\n"),
+		"config/aisle.conf":       []byte("[produce]\ntomatoes\n"),
+	})
+
+	format, items, err := importer.Read("recipes.zip", archive)
+	is.NotError(t, err)
+	is.Equal(t, importer.FormatCooklang, format)
+	is.Equal(t, 3, len(items))
+
+	byName := map[string]importer.Item{}
+	for _, item := range items {
+		byName[item.Name] = item
+	}
+	pancakes := byName["Fluffy Pancakes"]
+	recipe := pancakes.Collection.Recipes[0]
+	is.Equal(t, "https://example.com/pancakes", pancakes.SourceURL)
+	is.Equal(t, "1 cup flour\n2 tsp baking powder\n2 egg\n1 tbsp butter", ingredientLines(recipe))
+	is.Equal(t, "Fry ladlefuls in butter for 2 minutes a side.", recipe.Instructions[1])
+	is.Equal(t, "Keep them warm in a low oven.|Cookware: bowl", strings.Join(recipe.Notes, "|"))
+	is.Equal(t, "10 minutes", recipe.PrepTime)
+	is.Equal(t, 4, recipe.Servings)
+	is.Equal(t, "pancake photo", string(pancakes.Photo))
+	is.Equal(t, 0, len(pancakes.Problems))
+
+	soup := byName["Tomato Soup"]
+	is.Equal(t, "6 tomato\n500 ml stock", ingredientLines(soup.Collection.Recipes[0]))
+	is.Equal(t, 2, len(byName["Empty"].Problems))
+
+	t.Run("reads a single file", func(t *testing.T) {
+		format, items, err := importer.Read("pancakes.cook", readFile(t, "pancakes.cook"))
+		is.NotError(t, err)
+		is.Equal(t, importer.FormatCooklang, format)
+		is.Equal(t, "Fluffy Pancakes", items[0].Name)
+		is.Equal(t, 0, len(items[0].Photo))
+	})
+}
+
+func TestRead_unknown(t *testing.T) {
+	_, _, err := importer.Read("notes.txt", []byte("just some notes"))
+	is.True(t, errors.Is(err, importer.ErrUnknownFormat))
+}
@@ -0,0 +1,247 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"recipeze/parsing"
+)
+
+// mealieRecipe is a recipe in a Mealie export. The export is a zip with a
+// folder for each recipe holding its JSON and an images folder.
+type mealieRecipe struct {
+	Name               string            `json:"name"`
+	Description        string            `json:"description"`
+	OrgURL             string            `json:"orgURL"`
+	RecipeYield        string            `json:"recipeYield"`
+	RecipeServings     float64           `json:"recipeServings"`
+	PrepTime           string            `json:"prepTime"`
+	PerformTime        string            `json:"performTime"`
+	CookTime           string            `json:"cookTime"`
+	TotalTime          string            `json:"totalTime"`
+	RecipeCategory     []mealieTag       `json:"recipeCategory"`
+	Tags               []mealieTag       `json:"tags"`
+	RecipeIngredient   []json.RawMessage `json:"recipeIngredient"`
+	RecipeInstructions []json.RawMessage `json:"recipeInstructions"`
+	Notes              []struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	} `json:"notes"`
+	Nutrition map[string]any `json:"nutrition"`
+}
+
+// mealieTag is a category or tag, which older exports give as plain strings
+type mealieTag struct {
+	Name string `json:"name"`
+}
+
+func (t *mealieTag) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &t.Name); err == nil {
+		return nil
+	}
+	var tag struct {
+		Name string `json:"name"`
+	}
+	err := json.Unmarshal(data, &tag)
+	t.Name = tag.Name
+	return err
+}
+
+// mealieIngredient is a parsed ingredient, older exports give ingredients as lines
+type mealieIngredient struct {
+	Title    string   `json:"title"` // Starts a group of ingredients
+	Quantity *float64 `json:"quantity"`
+	Unit     *struct {
+		Name string `json:"name"`
+	} `json:"unit"`
+	Food *struct {
+		Name string `json:"name"`
+	} `json:"food"`
+	Note         string `json:"note"`
+	Display      string `json:"display"`
+	OriginalText string `json:"originalText"`
+}
+
+// mealieStep is an instruction, older exports give steps as strings
+type mealieStep struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// tandoorOrMealie tells a single recipe's JSON apart by the fields each uses
+type tandoorOrMealie struct {
+	Steps            json.RawMessage `json:"steps"`
+	RecipeIngredient json.RawMessage `json:"recipeIngredient"`
+}
+
+// readJSON reads a recipe, or a list of recipes, exported on its own as JSON
+func readJSON(data []byte) (Format, []Item, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		raw = []json.RawMessage{data}
+	}
+	var format Format
+	var items []Item
+	for _, recipe := range raw {
+		var probe tandoorOrMealie
+		if err := json.Unmarshal(recipe, &probe); err != nil {
+			return "", nil, fmt.Errorf("could not read JSON: %w", err)
+		}
+		var item Item
+		var err error
+		switch {
+		case probe.Steps != nil:
+			format = FormatTandoor
+			item, err = readTandoorRecipe(recipe)
+		case probe.RecipeIngredient != nil:
+			format = FormatMealie
+			item, err = readMealieRecipe(recipe)
+		default:
+			return "", nil, ErrUnknownFormat
+		}
+		if err != nil {
+			return format, nil, err
+		}
+		items = append(items, item)
+	}
+	return format, items, nil
+}
+
+func readMealieArchive(archive *zipArchive) ([]Item, error) {
+	var items []Item
+	for _, file := range archive.File {
+		if tooMany(items) {
+			break
+		}
+		if !strings.EqualFold(path.Ext(file.Name), ".json") {
+			continue
+		}
+		data, err := archive.read(file)
+		if err != nil {
+			return nil, err
+		}
+		item, err := readMealieRecipe(data)
+		if err != nil {
+			item = Item{Name: strings.TrimSuffix(path.Base(file.Name), path.Ext(file.Name))}
+			item.problem("Couldn't read this recipe: %v", err)
+			items = append(items, item)
+			continue
+		}
+		// The photo is kept next to the recipe as images/original.webp
+		for _, ext := range []string{".webp", ".jpg", ".jpeg", ".png"} {
+			if photo := archive.find(path.Join(path.Dir(file.Name), "images", "original"+ext)); photo != nil {
+				if item.Photo, err = archive.read(photo); err != nil {
+					item.problem("Couldn't read the photo")
+				}
+				break
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func readMealieRecipe(data []byte) (Item, error) {
+	var mealie mealieRecipe
+	if err := json.Unmarshal(data, &mealie); err != nil {
+		return Item{}, fmt.Errorf("not a Mealie recipe: %w", err)
+	}
+
+	item := Item{
+		Name:        mealie.Name,
+		Description: strings.TrimSpace(mealie.Description),
+	}
+	if isURL(mealie.OrgURL) {
+		item.SourceURL = mealie.OrgURL
+	}
+	recipe := parsing.Recipe{
+		Name:        mealie.Name,
+		PrepTime:    mealie.PrepTime,
+		CookTime:    mealie.PerformTime,
+		TotalTime:   mealie.TotalTime,
+		Servings:    int(mealie.RecipeServings),
+		Ingredients: []parsing.Ingredient{},
+	}
+	if recipe.CookTime == "" {
+		recipe.CookTime = mealie.CookTime
+	}
+	if recipe.Servings == 0 {
+		recipe.Servings = parsing.ParseServings(mealie.RecipeYield)
+	}
+	for _, tag := range append(mealie.RecipeCategory, mealie.Tags...) {
+		if tag.Name != "" {
+			recipe.Tags = append(recipe.Tags, tag.Name)
+		}
+	}
+
+	for _, raw := range mealie.RecipeIngredient {
+		var line string
+		if json.Unmarshal(raw, &line) == nil {
+			parsed, extra := ingredients(&item, []string{line})
+			recipe.Ingredients = append(recipe.Ingredients, parsed...)
+			recipe.Notes = append(recipe.Notes, extra...)
+			continue
+		}
+		var ingredient mealieIngredient
+		if err := json.Unmarshal(raw, &ingredient); err != nil {
+			item.problem("Couldn't read an ingredient")
+			continue
+		}
+		switch {
+		case ingredient.Food != nil && ingredient.Food.Name != "":
+			var unit string
+			if ingredient.Unit != nil {
+				unit = ingredient.Unit.Name
+			}
+			recipe.Ingredients = append(recipe.Ingredients, structuredIngredient(ingredient.Quantity, unit, ingredient.Food.Name, ingredient.Note))
+		default:
+			// Ingredients Mealie never parsed keep the whole line in the note
+			line := firstNonEmpty(ingredient.OriginalText, ingredient.Display, ingredient.Note)
+			if ingredient.Quantity != nil && *ingredient.Quantity > 0 && line == ingredient.Note {
+				line = strconv.FormatFloat(*ingredient.Quantity, 'f', -1, 64) + " " + line
+			}
+			parsed, extra := ingredients(&item, []string{line})
+			recipe.Ingredients = append(recipe.Ingredients, parsed...)
+			recipe.Notes = append(recipe.Notes, extra...)
+		}
+	}
+
+	for _, raw := range mealie.RecipeInstructions {
+		var step mealieStep
+		if json.Unmarshal(raw, &step.Text) != nil {
+			if err := json.Unmarshal(raw, &step); err != nil {
+				item.problem("Couldn't read a step")
+				continue
+			}
+		}
+		recipe.Instructions = append(recipe.Instructions, lines(step.Text)...)
+	}
+	for _, note := range mealie.Notes {
+		text := strings.TrimSpace(note.Text)
+		if note.Title != "" {
+			text = strings.TrimSpace(note.Title + ": " + text)
+		}
+		if text != "" {
+			recipe.Notes = append(recipe.Notes, text)
+		}
+	}
+	if len(mealie.Nutrition) > 0 {
+		// Mealie uses schema.org's names for nutrients
+		recipe.Nutrition = parsing.NewNutrition(func(property string) any { return mealie.Nutrition[property] })
+	}
+
+	item.Collection = &parsing.RecipeCollection{Recipes: []parsing.Recipe{recipe}}
+	return item, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
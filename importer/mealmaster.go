@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"slices"
+	"strings"
+
+	"recipeze/parsing"
+)
+
+var (
+	// Recipes start with "MMMMM----- Recipe via Meal-Master" or a line of dashes
+	mealMasterStartRe = regexp.MustCompile(`^(MMMMM|-----).*Meal-Master`)
+	// Groups of ingredients are headed like "MMMMM-----FILLING-----"
+	mealMasterHeadingRe = regexp.MustCompile(`^(MMMMM|-----)-*([^-].*?)-*$`)
+	mealMasterFieldRe   = regexp.MustCompile(`^\s*(Title|Categories|Yield|Servings)\s*:\s*(.*)$`)
+	// Ingredients are in columns, seven for the amount and two for the unit
+	mealMasterIngredientRe = regexp.MustCompile(`^([ \d./-]{7}) ([A-Za-z ]{2}) (.+)$`)
+)
+
+// mealMasterUnits are MealMaster's two letter units
+var mealMasterUnits = map[string]string{
+	"x": "", "ea": "", "sm": "small", "md": "medium", "lg": "large",
+	"cn": "can", "pk": "package", "pn": "pinch", "dr": "drop", "ds": "dash",
+	"ct": "carton", "bn": "bunch", "sl": "slice", "t": "tsp", "ts": "tsp",
+	"tb": "tbsp", "T": "tbsp", "fl": "fl oz", "c": "cup", "pt": "pint",
+	"qt": "quart", "ga": "gallon", "oz": "oz", "lb": "lb", "ml": "ml",
+	"cb": "cubic cm", "cl": "cl", "dl": "dl", "l": "l", "mg": "mg",
+	"cg": "cg", "dg": "dg", "g": "g", "kg": "kg",
+}
+
+// readMealMaster reads a MealMaster text file, which may hold many recipes
+func readMealMaster(data []byte) ([]Item, error) {
+	var items []Item
+	var current *mealMasterRecipe
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntryBytes)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		switch {
+		case mealMasterStartRe.MatchString(line):
+			if current != nil {
+				items = append(items, current.item())
+			}
+			current = &mealMasterRecipe{}
+		case current == nil:
+			continue
+		case line == "MMMMM" || line == "-----":
+			items = append(items, current.item())
+			current = nil
+		default:
+			current.add(line)
+		}
+	}
+	if current != nil {
+		items = append(items, current.item())
+	}
+	return items, scanner.Err()
+}
+
+// mealMasterRecipe collects a recipe's lines as they are read
+type mealMasterRecipe struct {
+	title, yield  string
+	categories    []string
+	ingredients   []string
+	paragraphs    []string
+	paragraph     []string
+	inDirections  bool
+	sawIngredient bool
+}
+
+func (m *mealMasterRecipe) add(line string) {
+	if !m.inDirections {
+		if match := mealMasterFieldRe.FindStringSubmatch(line); match != nil && !m.sawIngredient {
+			switch match[1] {
+			case "Title":
+				m.title = match[2]
+			case "Categories":
+				for _, category := range strings.Split(match[2], ",") {
+					if category = strings.TrimSpace(category); category != "" && category != "None" {
+						m.categories = append(m.categories, category)
+					}
+				}
+			default:
+				m.yield = match[2]
+			}
+			return
+		}
+		if strings.TrimSpace(line) == "" || mealMasterHeadingRe.MatchString(line) {
+			return
+		}
+		if m.addIngredients(line) {
+			m.sawIngredient = true
+			return
+		}
+		m.inDirections = true
+	}
+
+	if strings.TrimSpace(line) == "" {
+		m.endParagraph()
+		return
+	}
+	m.paragraph = append(m.paragraph, strings.TrimSpace(line))
+}
+
+// addIngredients reads an ingredient line, which may hold two ingredients side
+// by side. It tells if the line was one.
+func (m *mealMasterRecipe) addIngredients(line string) bool {
+	var columns []string
+	if len(line) > 41 && mealMasterIngredientRe.MatchString(line[41:]) {
+		columns = []string{strings.TrimRight(line[:41], " "), line[41:]}
+	} else {
+		columns = []string{line}
+	}
+	found := slices.Clone(m.ingredients)
+	for _, column := range columns {
+		match := mealMasterIngredientRe.FindStringSubmatch(column)
+		if match == nil {
+			return false
+		}
+		amount, unit, name := strings.TrimSpace(match[1]), strings.TrimSpace(match[2]), strings.TrimSpace(match[3])
+		// Long ingredients carry on in the next line's name, after a dash
+		if amount == "" && unit == "" && strings.HasPrefix(name, "-") && len(found) > 0 {
+			found[len(found)-1] += " " + strings.TrimSpace(strings.TrimPrefix(name, "-"))
+			continue
+		}
+		word, ok := mealMasterUnits[unit]
+		if !ok && unit != "" {
+			return false
+		}
+		// MealMaster puts notes after a semicolon, as in "Apples; sliced"
+		name = strings.Replace(name, ";", ",", 1)
+		found = append(found, strings.Join(strings.Fields(amount+" "+word+" "+name), " "))
+	}
+	m.ingredients = found
+	return true
+}
+
+func (m *mealMasterRecipe) endParagraph() {
+	if len(m.paragraph) > 0 {
+		m.paragraphs = append(m.paragraphs, strings.Join(m.paragraph, " "))
+		m.paragraph = nil
+	}
+}
+
+func (m *mealMasterRecipe) item() Item {
+	m.endParagraph()
+	item := Item{Name: m.title}
+	recipe := parsing.Recipe{
+		Name:         m.title,
+		Servings:     parsing.ParseServings(m.yield),
+		Tags:         m.categories,
+		Instructions: m.paragraphs,
+	}
+	recipe.Ingredients, recipe.Notes = ingredients(&item, m.ingredients)
+	item.Collection = &parsing.RecipeCollection{Recipes: []parsing.Recipe{recipe}}
+	return item
+}
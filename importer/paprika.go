@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"recipeze/parsing"
+)
+
+// paprikaRecipe is a recipe in a Paprika export. A .paprikarecipes file is a
+// zip holding a gzipped JSON .paprikarecipe file for each recipe.
+type paprikaRecipe struct {
+	Name            string   `json:"name"`
+	Ingredients     string   `json:"ingredients"`
+	Directions      string   `json:"directions"`
+	Notes           string   `json:"notes"`
+	Description     string   `json:"description"`
+	Source          string   `json:"source"`
+	SourceURL       string   `json:"source_url"`
+	Servings        string   `json:"servings"`
+	PrepTime        string   `json:"prep_time"`
+	CookTime        string   `json:"cook_time"`
+	TotalTime       string   `json:"total_time"`
+	Categories      []string `json:"categories"`
+	NutritionalInfo string   `json:"nutritional_info"`
+	ImageURL        string   `json:"image_url"`
+	PhotoData       string   `json:"photo_data"` // Base64
+}
+
+func readPaprika(archive *zipArchive) ([]Item, error) {
+	var items []Item
+	for _, file := range archive.File {
+		if tooMany(items) {
+			break
+		}
+		if !strings.HasSuffix(strings.ToLower(file.Name), ".paprikarecipe") {
+			continue
+		}
+		data, err := archive.read(file)
+		if err != nil {
+			return nil, err
+		}
+		item, err := readPaprikaRecipe(data)
+		if err != nil {
+			// One damaged recipe shouldn't stop the rest
+			item = Item{Name: strings.TrimSuffix(file.Name, ".paprikarecipe")}
+			item.problem("Couldn't read this recipe: %v", err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func readPaprikaRecipe(data []byte) (Item, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return Item{}, fmt.Errorf("not a Paprika recipe: %w", err)
+	}
+	defer r.Close()
+	unpacked, err := io.ReadAll(io.LimitReader(r, maxEntryBytes))
+	if err != nil {
+		return Item{}, fmt.Errorf("not a Paprika recipe: %w", err)
+	}
+	var paprika paprikaRecipe
+	if err := json.Unmarshal(unpacked, &paprika); err != nil {
+		return Item{}, fmt.Errorf("not a Paprika recipe: %w", err)
+	}
+
+	item := Item{
+		Name:        paprika.Name,
+		Description: strings.TrimSpace(paprika.Description),
+		ImageURL:    paprika.ImageURL,
+	}
+	recipe := parsing.Recipe{
+		Name:         paprika.Name,
+		PrepTime:     paprika.PrepTime,
+		CookTime:     paprika.CookTime,
+		TotalTime:    paprika.TotalTime,
+		Servings:     parsing.ParseServings(paprika.Servings),
+		Instructions: lines(paprika.Directions),
+		Notes:        lines(paprika.Notes),
+		Tags:         paprika.Categories,
+		Image:        paprika.ImageURL,
+	}
+	var extra []string
+	recipe.Ingredients, extra = ingredients(&item, lines(paprika.Ingredients))
+	recipe.Notes = append(recipe.Notes, extra...)
+
+	switch {
+	case isURL(paprika.SourceURL):
+		item.SourceURL = paprika.SourceURL
+	case isURL(paprika.Source):
+		item.SourceURL = paprika.Source
+	}
+	if paprika.Source != "" && paprika.Source != item.SourceURL {
+		recipe.Notes = append(recipe.Notes, "Source: "+paprika.Source)
+	}
+	if info := lines(paprika.NutritionalInfo); len(info) > 0 {
+		// Paprika keeps nutrition as free text, so it stays readable as a note
+		recipe.Notes = append(recipe.Notes, "Nutrition: "+strings.Join(info, ", "))
+	}
+	if paprika.PhotoData != "" {
+		photo, err := base64.StdEncoding.DecodeString(paprika.PhotoData)
+		if err != nil {
+			item.problem("Couldn't read the photo")
+		} else {
+			item.Photo = photo
+		}
+	}
+	item.Collection = &parsing.RecipeCollection{Recipes: []parsing.Recipe{recipe}}
+	return item, nil
+}
@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"recipeze/parsing"
+)
+
+// readRecipeKeeperArchive reads a Recipe Keeper export, a zip with every recipe
+// in recipes.html and the photos in an images folder
+func readRecipeKeeperArchive(archive *zipArchive) ([]Item, error) {
+	for _, file := range archive.File {
+		if path.Base(strings.ToLower(file.Name)) != "recipes.html" {
+			continue
+		}
+		data, err := archive.read(file)
+		if err != nil {
+			return nil, err
+		}
+		return readRecipeKeeper(data, func(src string) ([]byte, error) {
+			photo := archive.find(path.Join(path.Dir(file.Name), src))
+			if photo == nil {
+				return nil, fmt.Errorf("%s is not in the export", src)
+			}
+			return archive.read(photo)
+		})
+	}
+	return nil, ErrUnknownFormat
+}
+
+// readRecipeKeeper reads the recipes in Recipe Keeper's HTML, which marks each
+// field with an itemprop. photo opens the images the recipes link to, it is
+// nil when the HTML was uploaded without them.
+func readRecipeKeeper(data []byte, photo func(src string) ([]byte, error)) ([]Item, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not read HTML: %w", err)
+	}
+
+	var items []Item
+	doc.Find(".recipe-details").EachWithBreak(func(_ int, details *goquery.Selection) bool {
+		if tooMany(items) {
+			return false
+		}
+		prop := func(name string) string {
+			field := details.Find(fmt.Sprintf("[itemprop=%q]", name)).First()
+			if content, ok := field.Attr("content"); ok {
+				return strings.TrimSpace(content)
+			}
+			return strings.Join(strings.Fields(field.Text()), " ")
+		}
+		paragraphs := func(name string) []string {
+			var result []string
+			details.Find(fmt.Sprintf("[itemprop=%q]", name)).Each(func(_ int, field *goquery.Selection) {
+				html, err := field.Html()
+				if err != nil {
+					return
+				}
+				// Lines are paragraphs or breaks within one
+				html = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "</p>", "</p>\n").Replace(html)
+				fragment, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+				if err != nil {
+					return
+				}
+				result = append(result, lines(fragment.Text())...)
+			})
+			return result
+		}
+
+		item := Item{Name: prop("name")}
+		recipe := parsing.Recipe{
+			Name:         item.Name,
+			PrepTime:     prop("prepTime"),
+			CookTime:     prop("cookTime"),
+			Servings:     parsing.ParseServings(prop("recipeYield")),
+			Instructions: paragraphs("recipeDirections"),
+			Notes:        paragraphs("recipeNotes"),
+		}
+		var extra []string
+		recipe.Ingredients, extra = ingredients(&item, paragraphs("recipeIngredients"))
+		recipe.Notes = append(recipe.Notes, extra...)
+
+		for _, name := range []string{"recipeCourse", "recipeCategory"} {
+			details.Find(fmt.Sprintf("[itemprop=%q]", name)).Each(func(_ int, field *goquery.Selection) {
+				if tag := strings.TrimSpace(field.Text()); tag != "" {
+					recipe.Tags = append(recipe.Tags, tag)
+				}
+			})
+		}
+		if source := prop("recipeSource"); isURL(source) {
+			item.SourceURL = source
+		} else if source != "" {
+			recipe.Notes = append(recipe.Notes, "Source: "+source)
+		}
+
+		if src, ok := details.Find("img.recipe-photo, img[itemprop='image']").First().Attr("src"); ok && src != "" {
+			switch {
+			case isURL(src):
+				item.ImageURL = src
+			case photo == nil:
+				item.problem("The photo wasn't uploaded with the recipes, upload the whole export to keep it")
+			default:
+				data, err := photo(src)
+				if err != nil {
+					item.problem("Couldn't read the photo: %v", err)
+				}
+				item.Photo = data
+			}
+		}
+		item.Collection = &parsing.RecipeCollection{Recipes: []parsing.Recipe{recipe}}
+		items = append(items, item)
+		return true
+	})
+	return items, nil
+}
@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"recipeze/parsing"
+)
+
+// tandoorRecipe is a recipe in a Tandoor export. The export is a zip holding a
+// zip for each recipe, each with a recipe.json and the photo.
+type tandoorRecipe struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Keywords    []struct {
+		Name string `json:"name"`
+	} `json:"keywords"`
+	Steps []struct {
+		Name        string `json:"name"`
+		Instruction string `json:"instruction"`
+		Ingredients []struct {
+			Food *struct {
+				Name string `json:"name"`
+			} `json:"food"`
+			Unit *struct {
+				Name string `json:"name"`
+			} `json:"unit"`
+			Amount   float64 `json:"amount"`
+			Note     string  `json:"note"`
+			IsHeader bool    `json:"is_header"`
+			NoAmount bool    `json:"no_amount"`
+		} `json:"ingredients"`
+	} `json:"steps"`
+	WorkingTime  int    `json:"working_time"` // Minutes
+	WaitingTime  int    `json:"waiting_time"`
+	Servings     int    `json:"servings"`
+	ServingsText string `json:"servings_text"`
+	SourceURL    string `json:"source_url"`
+}
+
+// readTandoorArchive reads the recipes in a Tandoor export, or in one of the
+// recipe zips in it when nested. Recipe zips hold no further zips.
+func readTandoorArchive(archive *zipArchive, nested bool) ([]Item, error) {
+	var items []Item
+	for _, file := range archive.File {
+		if tooMany(items) {
+			break
+		}
+		switch {
+		case strings.EqualFold(path.Ext(file.Name), ".zip"):
+			name := strings.TrimSuffix(path.Base(file.Name), ".zip")
+			if nested {
+				item := Item{Name: name}
+				item.problem("Couldn't open this recipe: it is packed more than once")
+				items = append(items, item)
+				continue
+			}
+			inner, err := archive.open(file)
+			if errors.Is(err, zip.ErrFormat) {
+				item := Item{Name: name}
+				item.problem("Couldn't open this recipe: %v", err)
+				items = append(items, item)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			recipes, err := readTandoorArchive(inner, true)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, recipes...)
+		case path.Base(file.Name) == "recipe.json":
+			data, err := archive.read(file)
+			if err != nil {
+				return nil, err
+			}
+			item, err := readTandoorRecipe(data)
+			if err != nil {
+				item = Item{Name: path.Dir(file.Name)}
+				item.problem("Couldn't read this recipe: %v", err)
+				items = append(items, item)
+				continue
+			}
+			// The photo sits next to the recipe as image.jpg or image.png
+			for _, other := range archive.File {
+				if path.Dir(other.Name) == path.Dir(file.Name) && strings.HasPrefix(path.Base(other.Name), "image.") {
+					if item.Photo, err = archive.read(other); err != nil {
+						item.problem("Couldn't read the photo")
+					}
+					break
+				}
+			}
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func readTandoorRecipe(data []byte) (Item, error) {
+	var tandoor tandoorRecipe
+	if err := json.Unmarshal(data, &tandoor); err != nil {
+		return Item{}, fmt.Errorf("not a Tandoor recipe: %w", err)
+	}
+
+	item := Item{
+		Name:        tandoor.Name,
+		Description: strings.TrimSpace(tandoor.Description),
+	}
+	if isURL(tandoor.SourceURL) {
+		item.SourceURL = tandoor.SourceURL
+	}
+	recipe := parsing.Recipe{
+		Name:        tandoor.Name,
+		PrepTime:    minutes(tandoor.WorkingTime),
+		CookTime:    minutes(tandoor.WaitingTime),
+		Servings:    tandoor.Servings,
+		Ingredients: []parsing.Ingredient{},
+	}
+	for _, keyword := range tandoor.Keywords {
+		recipe.Tags = append(recipe.Tags, keyword.Name)
+	}
+	for _, step := range tandoor.Steps {
+		for _, ingredient := range step.Ingredients {
+			if ingredient.IsHeader || ingredient.Food == nil {
+				continue
+			}
+			var amount *float64
+			if !ingredient.NoAmount && ingredient.Amount > 0 {
+				amount = &ingredient.Amount
+			}
+			var unit string
+			if ingredient.Unit != nil {
+				unit = ingredient.Unit.Name
+			}
+			recipe.Ingredients = append(recipe.Ingredients, structuredIngredient(amount, unit, ingredient.Food.Name, ingredient.Note))
+		}
+		recipe.Instructions = append(recipe.Instructions, lines(step.Instruction)...)
+	}
+	item.Collection = &parsing.RecipeCollection{Recipes: []parsing.Recipe{recipe}}
+	return item, nil
+}
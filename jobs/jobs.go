@@ -0,0 +1,414 @@
+// Package jobs runs background work from a queue in Postgres, so it survives
+// restarts and is retried when it fails.
+//
+// Workers lease a job while they run it and keep renewing the lease, so a job
+// whose worker died is picked up again once its lease runs out. A worker only
+// records how a job went while it still holds the lease, so it can't overwrite
+// a job another worker has taken over. A failed job is retried with a growing
+// delay, and after its last attempt it is kept as dead for someone to look at,
+// as is a job whose worker died during its last attempt.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"recipeze/repo"
+)
+
+// States a job moves through
+const (
+	StateQueued  = "queued"
+	StateRunning = "running"
+	StateDone    = "done"
+	StateDead    = "dead" // Out of attempts, kept for someone to look at
+)
+
+// Job is a piece of work as a Handler sees it
+type Job struct {
+	ID          int64
+	Kind        string
+	Payload     []byte // JSON, see Job.Decode
+	Attempt     int    // Starts at 1
+	MaxAttempts int
+}
+
+// Decode reads the job's payload into v
+func (j *Job) Decode(v any) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// LastAttempt tells if the job is dead when this attempt fails
+func (j *Job) LastAttempt() bool {
+	return j.Attempt >= j.MaxAttempts
+}
+
+// Handler does the work for one kind of job. An error retries the job, unless
+// it is wrapped with Permanent or Delay.
+type Handler func(ctx context.Context, job *Job) error
+
+// permanentError is a failure retrying will not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a failure that retrying will not fix, so the job is dead at once
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent tells if err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// delayError puts a job off until a set time
+type delayError struct {
+	err   error
+	until time.Time
+}
+
+func (e *delayError) Error() string { return e.err.Error() }
+func (e *delayError) Unwrap() error { return e.err }
+
+// Delay puts the job back in the queue until a set time, such as when a limit
+// it ran into resets. The attempt doesn't count towards MaxAttempts.
+func Delay(err error, until time.Time) error {
+	if err == nil {
+		return nil
+	}
+	return &delayError{err: err, until: until}
+}
+
+// Options tune a Queue, zero values take the defaults
+type Options struct {
+	Workers      int           // Jobs run at the same time, 4 by default
+	PollInterval time.Duration // How often idle workers look for jobs, 2s by default
+	Lease        time.Duration // How long a job is held without renewing, 2 minutes by default
+	DrainTimeout time.Duration // How long running jobs get to finish on shutdown, 30s by default
+	MaxAttempts  int           // Attempts for jobs that don't say, 3 by default
+}
+
+// EnqueueOptions change how one job is queued
+type EnqueueOptions struct {
+	// Key stops the job from being queued while another job with the same key
+	// is queued or running, such as "extract:42"
+	Key         string
+	MaxAttempts int
+}
+
+type Queue struct {
+	queries *repo.Queries
+	opts    Options
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	wake chan struct{} // Tells an idle worker a job was just queued
+}
+
+// New creates a queue that keeps its jobs through queries
+func New(queries *repo.Queries, opts Options) *Queue {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = 2 * time.Minute
+	}
+	if opts.DrainTimeout <= 0 {
+		opts.DrainTimeout = 30 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	return &Queue{
+		queries:  queries,
+		opts:     opts,
+		handlers: map[string]Handler{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler for a kind of job. Workers only take jobs of the
+// kinds they have handlers for, so registering after Run has started is fine.
+func (q *Queue) Register(kind string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = handler
+}
+
+// Enqueue queues a job with payload, which is stored as JSON
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any, opts EnqueueOptions) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not encode %s job: %w", kind, err)
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = q.opts.MaxAttempts
+	}
+	err = q.queries.EnqueueJob(ctx, repo.EnqueueJobParams{
+		Kind:        kind,
+		DedupeKey:   repo.NullStringPG(opts.Key),
+		Payload:     data,
+		MaxAttempts: int32(opts.MaxAttempts),
+	})
+	if err != nil {
+		return fmt.Errorf("could not queue %s job: %w", kind, err)
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run works through jobs until ctx is done, then gives running jobs
+// Options.DrainTimeout to finish. Jobs still running after that are cancelled
+// and put back in the queue for next time.
+func (q *Queue) Run(ctx context.Context) error {
+	slog.Info("Starting job workers", "workers", q.opts.Workers)
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+
+	var wg sync.WaitGroup
+	for range q.opts.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, jobCtx)
+		}()
+	}
+
+	<-ctx.Done()
+	slog.Info("Stopping job workers, waiting for running jobs")
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(q.opts.DrainTimeout):
+		slog.Warn("Jobs still running after drain timeout, cancelling them", "timeout", q.opts.DrainTimeout)
+		cancelJobs()
+		<-done
+	}
+	slog.Info("Stopped job workers")
+	return nil
+}
+
+// work takes jobs one at a time until ctx is done
+func (q *Queue) work(ctx, jobCtx context.Context) {
+	for {
+		job, err := q.claim(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Error("Could not claim job", "error", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.wake:
+			case <-time.After(q.opts.PollInterval):
+			}
+			continue
+		}
+		q.run(jobCtx, job)
+	}
+}
+
+// claim leases the next job that is due, or gives nil when there is none
+func (q *Queue) claim(ctx context.Context) (*repo.Job, error) {
+	kinds := q.kinds()
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+	// A job that keeps taking its worker down would otherwise be reclaimed forever
+	buried, err := q.queries.BuryAbandonedJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if buried > 0 {
+		slog.Error("Buried jobs whose worker stopped during their last attempt", "count", buried)
+	}
+	job, err := q.queries.ClaimJob(ctx, repo.ClaimJobParams{
+		LeaseSeconds: int32(q.opts.Lease.Seconds()),
+		Kinds:        kinds,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (q *Queue) kinds() []string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	kinds := make([]string, 0, len(q.handlers))
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// run runs a claimed job and records how it went
+func (q *Queue) run(ctx context.Context, row *repo.Job) {
+	q.mu.RLock()
+	handler := q.handlers[row.Kind]
+	q.mu.RUnlock()
+
+	job := &Job{
+		ID:          row.ID,
+		Kind:        row.Kind,
+		Payload:     row.Payload,
+		Attempt:     int(row.Attempts),
+		MaxAttempts: int(row.MaxAttempts),
+	}
+	log := slog.With("jobID", job.ID, "kind", job.Kind, "attempt", job.Attempt)
+
+	stopRenewing := q.renewLease(ctx, job.ID, row.LeasedUntil)
+	err := runHandler(ctx, handler, job)
+	lease := stopRenewing()
+
+	// The job's own context may be cancelled by now, recording the outcome still has to happen
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	var recorded int64
+	var delay *delayError
+	switch {
+	case err == nil:
+		recorded, err = q.queries.CompleteJob(recordCtx, repo.CompleteJobParams{
+			ID:          job.ID,
+			LeasedUntil: lease,
+		})
+	case ctx.Err() != nil:
+		log.Info("Job cancelled by shutdown, putting it back", "error", err)
+		recorded, err = q.queries.ReleaseJob(recordCtx, repo.ReleaseJobParams{
+			ID:          job.ID,
+			LeasedUntil: lease,
+		})
+	case errors.As(err, &delay):
+		log.Info("Job put off", "error", err, "until", delay.until)
+		recorded, err = q.queries.DelayJob(recordCtx, repo.DelayJobParams{
+			LastError:   repo.StringPG(err.Error()),
+			RunAt:       pgtype.Timestamptz{Time: delay.until, Valid: true},
+			ID:          job.ID,
+			LeasedUntil: lease,
+		})
+	case IsPermanent(err) || job.LastAttempt():
+		log.Error("Job failed for good", "error", err)
+		recorded, err = q.queries.BuryJob(recordCtx, repo.BuryJobParams{
+			LastError:   repo.StringPG(err.Error()),
+			ID:          job.ID,
+			LeasedUntil: lease,
+		})
+	default:
+		delay := Backoff(job.Attempt)
+		log.Warn("Job failed, retrying", "error", err, "delay", delay)
+		recorded, err = q.queries.RetryJob(recordCtx, repo.RetryJobParams{
+			LastError:    repo.StringPG(err.Error()),
+			DelaySeconds: int32(delay.Seconds()),
+			ID:           job.ID,
+			LeasedUntil:  lease,
+		})
+	}
+	switch {
+	case err != nil:
+		log.Error("Could not record job outcome", "error", err)
+	case recorded == 0:
+		log.Warn("Job was taken over by another worker after its lease ran out, dropping this outcome")
+	}
+}
+
+// runHandler runs the job's handler, turning a panic into an error so one bad
+// job can't take the workers down
+func runHandler(ctx context.Context, handler Handler, job *Job) (err error) {
+	if handler == nil {
+		return Permanent(fmt.Errorf("no handler for %q jobs", job.Kind))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// renewLease keeps a running job leased until the returned func is called,
+// which gives the lease the job is held under by then
+func (q *Queue) renewLease(ctx context.Context, id int64, lease pgtype.Timestamptz) func() pgtype.Timestamptz {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(q.opts.Lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				renewed, err := q.queries.ExtendJobLease(ctx, repo.ExtendJobLeaseParams{
+					LeaseSeconds: int32(q.opts.Lease.Seconds()),
+					ID:           id,
+					LeasedUntil:  lease,
+				})
+				switch {
+				case errors.Is(err, pgx.ErrNoRows):
+					slog.Warn("Job lease was lost to another worker", "jobID", id)
+					return
+				case err != nil && ctx.Err() == nil:
+					slog.Warn("Could not renew job lease", "jobID", id, "error", err)
+				case err == nil:
+					lease = renewed
+				}
+			}
+		}
+	}()
+	return func() pgtype.Timestamptz {
+		cancel()
+		<-done
+		return lease
+	}
+}
+
+// Backoff is how long to wait before the next attempt after attempt failed:
+// 30 seconds doubling each time, up to an hour, give or take a tenth so jobs
+// that failed together don't retry together
+func Backoff(attempt int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempt && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	delay = min(delay, time.Hour)
+	jitter := time.Duration(rand.Int64N(int64(delay)/5)) - delay/10
+	return delay + jitter
+}
@@ -0,0 +1,60 @@
+package jobs_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"maragu.dev/is"
+
+	"recipeze/jobs"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{8, time.Hour}, // 64 minutes, capped
+		{50, time.Hour},
+	}
+	for _, test := range tests {
+		t.Run(test.want.String(), func(t *testing.T) {
+			for range 20 {
+				got := jobs.Backoff(test.attempt)
+				is.True(t, got >= test.want*9/10)
+				is.True(t, got <= test.want*11/10)
+			}
+		})
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	err := errors.New("no such recipe")
+	is.Error(t, err, jobs.Permanent(err))
+	is.True(t, jobs.IsPermanent(fmt.Errorf("reading recipe: %w", jobs.Permanent(err))))
+	is.True(t, !jobs.IsPermanent(err))
+	is.NotError(t, jobs.Permanent(nil))
+}
+
+func TestDelay(t *testing.T) {
+	err := errors.New("budget used up")
+	delayed := jobs.Delay(err, time.Now().Add(time.Hour))
+	is.Error(t, err, delayed)
+	is.True(t, !jobs.IsPermanent(delayed))
+	is.NotError(t, jobs.Delay(nil, time.Now()))
+}
+
+func TestJob(t *testing.T) {
+	job := &jobs.Job{Payload: []byte(`{"recipe_id":42}`), Attempt: 3, MaxAttempts: 3}
+	var payload struct {
+		RecipeID int `json:"recipe_id"`
+	}
+	is.NotError(t, job.Decode(&payload))
+	is.Equal(t, 42, payload.RecipeID)
+	is.True(t, job.LastAttempt())
+}
@@ -1,7 +1,21 @@
 // Package model has domain models used throughout the application.
 package model
 
-import "recipeze/parsing"
+import (
+	"time"
+
+	"recipeze/parsing"
+)
+
+// ExtractionStatus tracks getting a recipe's data out of its page
+type ExtractionStatus string
+
+const (
+	ExtractionPending ExtractionStatus = "pending" // Waiting for a worker, or to be tried again
+	ExtractionRunning ExtractionStatus = "running"
+	ExtractionDone    ExtractionStatus = "done"
+	ExtractionFailed  ExtractionStatus = "failed"
+)
 
 type Recipe struct {
 	ID          int
@@ -11,6 +25,23 @@ type Recipe struct {
 	ImageURL    string
 	GroupID     int
 	Data        *parsing.RecipeCollection
+	DataSource  string // Which extraction path produced Data
+	PrepTime    parsing.Duration
+	CookTime    parsing.Duration
+	TotalTime   parsing.Duration
+	Status      ExtractionStatus
+	StatusError string // Why extraction failed, or is waiting
+	ImageKey    string // Name of our cached copy of the photo, empty until it is downloaded
+	ThumbKey    string
+}
+
+// Snapshot is the copy of a recipe's page kept when the recipe was saved
+type Snapshot struct {
+	RecipeID int
+	URL      string // Where the page was, after redirects
+	HTML     []byte
+	Text     string // The page's readable text, as extraction saw it
+	SavedAt  time.Time
 }
 
 type User struct {
@@ -18,12 +49,14 @@ type User struct {
 	Name          string
 	Email         string
 	SetupComplete bool
+	Units         parsing.UnitSystem // How recipes are measured for this user
 }
 
 type Group struct {
 	ID      int
 	Name    string
 	Members []GroupMember
+	IsAdmin bool // Whether the user looking at the group administers it
 }
 
 type GroupMember struct {
@@ -32,3 +65,45 @@ type GroupMember struct {
 	Email   string
 	IsAdmin bool
 }
+
+// LLMUsage is what a group has spent on the LLM, for its admins. Costs are in
+// millionths of a dollar.
+type LLMUsage struct {
+	Since   time.Time // Start of the budget month the totals cover
+	Today   int64     // Spent since the budget day began
+	Month   int64
+	Budget  LLMBudget
+	Members []MemberLLMUsage // Who spent the month's total, most first
+	Recent  []LLMCall
+}
+
+// LLMBudget is what a group and each of its users may spend, zero being no limit
+type LLMBudget struct {
+	GroupDaily   int64
+	GroupMonthly int64
+	UserDaily    int64
+	UserMonthly  int64
+}
+
+// MemberLLMUsage is what one user's recipes cost a group
+type MemberLLMUsage struct {
+	Name         string // Empty for calls not made for anyone
+	Calls        int
+	InputTokens  int64
+	OutputTokens int64
+	Cost         int64
+}
+
+// LLMCall is one call to the LLM, made while reading a recipe
+type LLMCall struct {
+	Model        string
+	RecipeID     int // Zero once the recipe is deleted
+	RecipeName   string
+	UserName     string
+	InputTokens  int
+	OutputTokens int
+	Latency      time.Duration
+	Cost         int64
+	Succeeded    bool
+	At           time.Time
+}
@@ -0,0 +1,58 @@
+package parsing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/liushuangls/go-anthropic/v2"
+)
+
+const defaultAnthropicModel = string(anthropic.ModelClaude3Dot5HaikuLatest)
+
+// anthropicExtractor uses the Anthropic messages API
+type anthropicExtractor struct {
+	client *anthropic.Client
+	model  string
+}
+
+func newAnthropicExtractor(cfg ExtractorConfig) *anthropicExtractor {
+	model := cfg.Model
+	if model == "" {
+		model = defaultAnthropicModel
+	}
+	return &anthropicExtractor{
+		client: anthropic.NewClient(cfg.APIKey),
+		model:  model,
+	}
+}
+
+func (a *anthropicExtractor) Complete(ctx context.Context, prompt string) (*Completion, error) {
+	resp, err := a.client.CreateMessages(ctx, anthropic.MessagesRequest{
+		Model: anthropic.Model(a.model),
+		Messages: []anthropic.Message{
+			anthropic.NewUserTextMessage(prompt),
+		},
+		MaxTokens: 2000,
+	})
+	if err != nil {
+		var e *anthropic.APIError
+		if errors.As(err, &e) {
+			return nil, fmt.Errorf("messages error, type: %s, message: %s", e.Type, e.Message)
+		}
+		return nil, fmt.Errorf("messages error: %w", err)
+	}
+	if len(resp.Content) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+
+	return &Completion{
+		Text:         resp.Content[0].GetText(),
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+	}, nil
+}
+
+func (a *anthropicExtractor) Model() string {
+	return BackendAnthropic + "/" + a.model
+}
@@ -0,0 +1,27 @@
+package parsing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// ExtractionCache keeps what the LLM read from a recipe's text, so a popular
+// recipe saved by several groups, or a page saved again, is only paid for once
+type ExtractionCache interface {
+	// Get gives the collection cached under key, or nil when there is none
+	Get(ctx context.Context, key string) (*RecipeCollection, error)
+
+	// Put caches what model read under key
+	Put(ctx context.Context, key, model string, collection *RecipeCollection) error
+}
+
+// CacheKey identifies what the LLM would read from text: the text itself, as
+// HtmlToText cleaned it for pages, the model and the prompt version
+func CacheKey(text []byte, model string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00", PromptVersion, model)
+	h.Write(text)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -1,3 +1,149 @@
 package parsing
 
-var spiceMap map[string]any
+import (
+	_ "embed"
+	"strings"
+)
+
+// Aisle is the part of a grocery store an ingredient is bought in
+type Aisle string
+
+const (
+	AisleProduce Aisle = "produce"
+	AisleMeat    Aisle = "meat"
+	AisleDairy   Aisle = "dairy"
+	AisleFrozen  Aisle = "frozen"
+	AislePantry  Aisle = "pantry"
+	AisleSpices  Aisle = "spices"
+	AisleOther   Aisle = "other"
+)
+
+// AisleOrder is the order a shopper usually walks through a store
+var AisleOrder = []Aisle{AisleProduce, AisleMeat, AisleDairy, AislePantry, AisleSpices, AisleFrozen, AisleOther}
+
+// Label gives the aisle name shown to users
+func (a Aisle) Label() string {
+	switch a {
+	case AisleProduce:
+		return "Produce"
+	case AisleMeat:
+		return "Meat & seafood"
+	case AisleDairy:
+		return "Dairy & eggs"
+	case AisleFrozen:
+		return "Frozen"
+	case AislePantry:
+		return "Pantry"
+	case AisleSpices:
+		return "Spices & seasonings"
+	default:
+		return "Other"
+	}
+}
+
+//go:embed spices.txt
+var spicesTxt string
+
+//go:embed aisles.txt
+var aislesTxt string
+
+var spiceMap = loadSpices(spicesTxt)
+
+// aisleTerms maps every known ingredient name to its aisle
+var aisleTerms = loadAisles(aislesTxt, spiceMap)
+
+// aisleTermList is the names in aisleTerms, longest first
+var aisleTermList = sortTerms(aisleTerms)
+
+func loadSpices(list string) map[string]any {
+	spices := map[string]any{}
+	for _, line := range strings.Split(list, "\n") {
+		if line = strings.ToLower(strings.TrimSpace(line)); line != "" {
+			spices[line] = struct{}{}
+		}
+	}
+	return spices
+}
+
+// loadAisles reads "[aisle]" headers followed by one ingredient per line
+func loadAisles(list string, spices map[string]any) map[string]Aisle {
+	terms := map[string]Aisle{}
+	for spice := range spices {
+		terms[spice] = AisleSpices
+	}
+	aisle := AisleOther
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			aisle = Aisle(strings.Trim(line, "[]"))
+		default:
+			terms[line] = aisle
+		}
+	}
+	return terms
+}
+
+// Categorize finds the aisle for an ingredient name. The longest known name
+// found in it wins, so "garlic powder" is a spice while "garlic" is produce.
+func Categorize(name string) Aisle {
+	name = strings.ToLower(name)
+	best := longestTerm(name, aisleTermList)
+	if best == "" {
+		return AisleOther
+	}
+	aisle := aisleTerms[best]
+	// Fresh herbs are sold with the vegetables, dried ones with the spices
+	if aisle == AisleSpices && containsWord(name, "fresh") {
+		return AisleProduce
+	}
+	return aisle
+}
+
+// AisleOf gives the aisle of an ingredient, using its Category when it names one
+func AisleOf(ingredient Ingredient) Aisle {
+	category := Aisle(ingredient.Category)
+	for _, aisle := range AisleOrder {
+		if aisle == category {
+			return aisle
+		}
+	}
+	return Categorize(ingredient.Name)
+}
+
+// Categorize fills the Category of every ingredient in the collection
+func (c *RecipeCollection) Categorize() {
+	if c == nil {
+		return
+	}
+	for i := range c.Recipes {
+		for j := range c.Recipes[i].Ingredients {
+			ingredient := &c.Recipes[i].Ingredients[j]
+			ingredient.Category = string(Categorize(ingredient.Name))
+		}
+	}
+}
+
+// AisleGroup is the ingredients bought in one aisle
+type AisleGroup struct {
+	Aisle       Aisle
+	Ingredients []Ingredient
+}
+
+// GroupByAisle sorts ingredients into aisles in store walk order, keeping
+// the recipe's order within each aisle
+func GroupByAisle(ingredients []Ingredient) []AisleGroup {
+	byAisle := map[Aisle][]Ingredient{}
+	for _, ingredient := range ingredients {
+		aisle := AisleOf(ingredient)
+		byAisle[aisle] = append(byAisle[aisle], ingredient)
+	}
+	var groups []AisleGroup
+	for _, aisle := range AisleOrder {
+		if len(byAisle[aisle]) > 0 {
+			groups = append(groups, AisleGroup{Aisle: aisle, Ingredients: byAisle[aisle]})
+		}
+	}
+	return groups
+}
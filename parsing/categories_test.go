@@ -0,0 +1,62 @@
+package parsing_test
+
+import (
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+func TestCategorize(t *testing.T) {
+	tests := []struct {
+		name  string
+		aisle parsing.Aisle
+	}{
+		{"yellow onion", parsing.AisleProduce},
+		{"garlic", parsing.AisleProduce},
+		{"garlic powder", parsing.AisleSpices},
+		{"fresh basil", parsing.AisleProduce},
+		{"basil", parsing.AisleSpices},
+		{"kosher salt", parsing.AisleSpices},
+		{"boneless skinless chicken thigh", parsing.AisleMeat},
+		{"chicken stock", parsing.AislePantry},
+		{"whole milk", parsing.AisleDairy},
+		{"coconut milk", parsing.AislePantry},
+		{"egg", parsing.AisleDairy},
+		{"frozen pea", parsing.AisleFrozen},
+		{"all-purpose flour", parsing.AislePantry},
+		{"red bell pepper", parsing.AisleProduce},
+		{"freshly ground black pepper", parsing.AisleSpices},
+		{"walnuts", parsing.AislePantry},
+		{"garlic butter", parsing.AisleDairy},
+		{"dragon fruit powder", parsing.AisleOther},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			is.Equal(t, test.aisle, parsing.Categorize(test.name))
+		})
+	}
+
+	t.Run("ingredient parser fills the category", func(t *testing.T) {
+		is.Equal(t, "produce", parsing.ParseIngredient("2 carrots, diced").Category)
+	})
+}
+
+func TestGroupByAisle(t *testing.T) {
+	var ingredients []parsing.Ingredient
+	for _, line := range []string{"1 tsp salt", "2 cups flour", "1 onion", "1 cup milk", "2 carrots"} {
+		ingredients = append(ingredients, parsing.ParseIngredient(line))
+	}
+	// An LLM category that is not an aisle is replaced
+	ingredients[1].Category = "baking"
+
+	groups := parsing.GroupByAisle(ingredients)
+	is.Equal(t, 4, len(groups))
+	is.Equal(t, parsing.AisleProduce, groups[0].Aisle)
+	is.Equal(t, "onion", groups[0].Ingredients[0].Name)
+	is.Equal(t, "carrot", groups[0].Ingredients[1].Name)
+	is.Equal(t, parsing.AisleDairy, groups[1].Aisle)
+	is.Equal(t, parsing.AislePantry, groups[2].Aisle)
+	is.Equal(t, parsing.AisleSpices, groups[3].Aisle)
+}
@@ -0,0 +1,332 @@
+package parsing
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// UnitSystem is the measuring system a user wants recipes shown in
+type UnitSystem string
+
+const (
+	UnitsOriginal UnitSystem = ""       // As written in the recipe
+	UnitsMetric   UnitSystem = "metric" // Grams, milliliters, Celsius
+	UnitsUS       UnitSystem = "us"     // Cups, spoons, ounces, Fahrenheit
+)
+
+// ParseUnitSystem reads a unit system from a form value, defaulting to the original units
+func ParseUnitSystem(s string) UnitSystem {
+	switch UnitSystem(strings.ToLower(s)) {
+	case UnitsMetric:
+		return UnitsMetric
+	case UnitsUS:
+		return UnitsUS
+	default:
+		return UnitsOriginal
+	}
+}
+
+// Milliliters in each volume unit
+var volumeUnits = map[string]float64{
+	"ml": 1, "cl": 10, "dl": 100, "l": 1000,
+	"tsp": 4.92892, "tbsp": 14.7868, "fl oz": 29.5735, "cup": 236.588,
+	"pint": 473.176, "quart": 946.353, "gallon": 3785.41,
+}
+
+// Grams in each mass unit
+var massUnits = map[string]float64{
+	"mg": 0.001, "g": 1, "kg": 1000,
+	"oz": 28.3495, "lb": 453.592,
+}
+
+// gramsPerCup is the density of ingredients usually measured by volume in US
+// recipes and by weight elsewhere. Liquids are left out on purpose, they are
+// converted between volume units instead.
+var gramsPerCup = map[string]float64{
+	"all-purpose flour": 125, "flour": 125, "bread flour": 127, "cake flour": 114,
+	"whole wheat flour": 120, "almond flour": 96, "rye flour": 102, "self-rising flour": 125,
+	"sugar": 200, "granulated sugar": 200, "white sugar": 200, "caster sugar": 225,
+	"brown sugar": 213, "light brown sugar": 213, "dark brown sugar": 213,
+	"powdered sugar": 120, "icing sugar": 120, "confectioners' sugar": 120, "confectioners sugar": 120,
+	"butter": 227, "unsalted butter": 227, "salted butter": 227,
+	"honey": 340, "maple syrup": 322, "molasses": 337, "peanut butter": 258,
+	"cocoa powder": 85, "cornstarch": 128, "baking powder": 192, "baking soda": 220,
+	"salt": 288, "table salt": 288, "kosher salt": 240, "sea salt": 288,
+	"rice": 185, "white rice": 185, "brown rice": 190, "rolled oat": 90, "oat": 90,
+	"quinoa": 170, "couscous": 173, "lentil": 192,
+	"chocolate chip": 170, "raisin": 150, "walnut": 117, "pecan": 109, "almond": 143,
+	"shredded cheese": 113, "cheddar cheese": 113, "parmesan cheese": 100, "parmesan": 100,
+	"cream cheese": 232, "sour cream": 230, "greek yogurt": 245, "yogurt": 245,
+	"breadcrumb": 108, "panko": 60, "shredded coconut": 85,
+}
+
+// densityTerms is the names in gramsPerCup, longest first
+var densityTerms = sortTerms(gramsPerCup)
+
+// densityFor finds the density entry with the longest name contained in the ingredient name
+func densityFor(name string) (float64, bool) {
+	name = strings.ToLower(name)
+	best := longestTerm(name, densityTerms)
+	if best == "" {
+		return 0, false
+	}
+	return gramsPerCup[best], true
+}
+
+// sortTerms gives the names of terms longest first, and in alphabetical order
+// when they are as long
+func sortTerms[V any](terms map[string]V) []string {
+	sorted := slices.Collect(maps.Keys(terms))
+	slices.SortFunc(sorted, func(a, b string) int {
+		return cmp.Or(len(b)-len(a), strings.Compare(a, b))
+	})
+	return sorted
+}
+
+// longestTerm finds the longest of the sorted terms found as a word in name.
+// Of terms as long, the one further on in name wins, since the last words
+// usually say what an ingredient is: "garlic butter" is butter.
+func longestTerm(name string, terms []string) string {
+	best, bestAt := "", -1
+	for _, term := range terms {
+		if len(term) < len(best) {
+			break
+		}
+		if !containsWord(name, term) {
+			continue
+		}
+		if at := strings.LastIndex(name, term); at > bestAt {
+			best, bestAt = term, at
+		}
+	}
+	return best
+}
+
+func containsWord(s, word string) bool {
+	idx := strings.Index(s, word)
+	for idx >= 0 {
+		end := idx + len(word)
+		before := idx == 0 || !isLetter(s[idx-1])
+		// Let plurals like "walnuts" match "walnut"
+		if end < len(s) && s[end] == 's' {
+			end++
+		}
+		after := end == len(s) || !isLetter(s[end])
+		if before && after {
+			return true
+		}
+		next := strings.Index(s[idx+1:], word)
+		if next < 0 {
+			break
+		}
+		idx += next + 1
+	}
+	return false
+}
+
+func isLetter(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// ConvertIngredient converts the amount of an ingredient into the given
+// system. Ingredients without an amount, with a unit that has no conversion
+// such as "clove", or already in the right system are returned unchanged.
+func ConvertIngredient(ingredient Ingredient, system UnitSystem) Ingredient {
+	if ingredient.Amount == nil || system == UnitsOriginal {
+		return ingredient
+	}
+	amount := *ingredient.Amount
+	unit := NormalizeUnit(ingredient.Unit)
+
+	var value float64
+	var newUnit string
+	switch system {
+	case UnitsMetric:
+		if ml, ok := volumeUnits[unit]; ok {
+			if isMetricUnit(unit) {
+				return ingredient
+			}
+			if density, ok := densityFor(ingredient.Name); ok {
+				value, newUnit = metricMass(amount * ml / volumeUnits["cup"] * density)
+			} else {
+				value, newUnit = metricVolume(amount * ml)
+			}
+		} else if grams, ok := massUnits[unit]; ok {
+			if isMetricUnit(unit) {
+				return ingredient
+			}
+			value, newUnit = metricMass(amount * grams)
+		} else {
+			return ingredient
+		}
+	case UnitsUS:
+		if ml, ok := volumeUnits[unit]; ok {
+			if !isMetricUnit(unit) {
+				return ingredient
+			}
+			value, newUnit = usVolume(amount * ml)
+		} else if grams, ok := massUnits[unit]; ok {
+			if !isMetricUnit(unit) {
+				return ingredient
+			}
+			// US recipes measure dry goods like flour by volume
+			if density, ok := densityFor(ingredient.Name); ok {
+				value, newUnit = usVolume(amount * grams / density * volumeUnits["cup"])
+			} else {
+				value, newUnit = usMass(amount * grams)
+			}
+		} else {
+			return ingredient
+		}
+	default:
+		return ingredient
+	}
+
+	ingredient.Amount = &value
+	ingredient.Unit = newUnit
+	return ingredient
+}
+
+// NormalizeUnit maps a unit alias like "Tablespoons" to the unit we store
+func NormalizeUnit(unit string) string {
+	unit = strings.TrimSuffix(strings.TrimSpace(unit), ".")
+	if canonical, ok := unitAliases[unit]; ok {
+		return canonical
+	}
+	if canonical, ok := unitAliases[strings.ToLower(unit)]; ok {
+		return canonical
+	}
+	return strings.ToLower(unit)
+}
+
+func isMetricUnit(unit string) bool {
+	switch unit {
+	case "mg", "g", "kg", "ml", "cl", "dl", "l":
+		return true
+	}
+	return false
+}
+
+func metricMass(grams float64) (float64, string) {
+	if grams >= 1000 {
+		return roundTo(grams/1000, 0.05), "kg"
+	}
+	return roundMetric(grams), "g"
+}
+
+func metricVolume(ml float64) (float64, string) {
+	if ml >= 1000 {
+		return roundTo(ml/1000, 0.05), "l"
+	}
+	return roundMetric(ml), "ml"
+}
+
+func usVolume(ml float64) (float64, string) {
+	switch {
+	case ml < volumeUnits["tbsp"]:
+		return roundTo(ml/volumeUnits["tsp"], 0.125), "tsp"
+	case ml < volumeUnits["cup"]/4:
+		return roundTo(ml/volumeUnits["tbsp"], 0.5), "tbsp"
+	default:
+		return roundTo(ml/volumeUnits["cup"], 0.125), "cup"
+	}
+}
+
+func usMass(grams float64) (float64, string) {
+	if grams >= massUnits["lb"] {
+		return roundTo(grams/massUnits["lb"], 0.25), "lb"
+	}
+	return roundTo(grams/massUnits["oz"], 0.25), "oz"
+}
+
+// roundMetric keeps small amounts precise and rounds larger ones to what a scale shows
+func roundMetric(value float64) float64 {
+	switch {
+	case value < 10:
+		return roundTo(value, 0.5)
+	case value < 100:
+		return math.Round(value)
+	default:
+		return roundTo(value, 5)
+	}
+}
+
+func roundTo(value, step float64) float64 {
+	rounded := math.Round(value/step) * step
+	if rounded == 0 && value > 0 {
+		return step
+	}
+	// Drop floating point noise like 1.2000000000000002
+	return math.Round(rounded*1000) / 1000
+}
+
+// temperatureRe finds temperatures like "350°F", "180 degrees C", "200 Celsius"
+// and "350 F". A bare unit letter has to be a capital, so "20 c flour" is a
+// cup.
+var temperatureRe = regexp.MustCompile(`(?i)\b(\d{2,3})(?:\s*(?:°|º|degrees?)\s*(F|C|Fahrenheit|Celsius)\b|\s*(Fahrenheit|Celsius)\b|(?-i:\s*([FC]))\b)`)
+
+// ConvertTemperatures rewrites oven temperatures in an instruction into the
+// given system, rounded the way oven dials are marked. Steps that already give
+// the temperature in both systems are left alone.
+func ConvertTemperatures(text string, system UnitSystem) string {
+	if system == UnitsOriginal {
+		return text
+	}
+	matches := temperatureRe.FindAllStringSubmatch(text, -1)
+	var hasF, hasC bool
+	for _, m := range matches {
+		if isFahrenheit(temperatureUnit(m)) {
+			hasF = true
+		} else {
+			hasC = true
+		}
+	}
+	if hasF && hasC {
+		return text
+	}
+
+	return temperatureRe.ReplaceAllStringFunc(text, func(match string) string {
+		m := temperatureRe.FindStringSubmatch(match)
+		degrees, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return match
+		}
+		fahrenheit := isFahrenheit(temperatureUnit(m))
+		switch {
+		case system == UnitsMetric && fahrenheit:
+			celsius := (degrees - 32) * 5 / 9
+			return fmt.Sprintf("%d°C", int(roundTo(celsius, temperatureStep(celsius, 100, 10))))
+		case system == UnitsUS && !fahrenheit:
+			f := degrees*9/5 + 32
+			return fmt.Sprintf("%d°F", int(roundTo(f, temperatureStep(f, 250, 25))))
+		}
+		return match
+	})
+}
+
+func temperatureUnit(match []string) string {
+	for _, unit := range match[2:] {
+		if unit != "" {
+			return unit
+		}
+	}
+	return ""
+}
+
+func isFahrenheit(unit string) bool {
+	return strings.HasPrefix(strings.ToLower(unit), "f")
+}
+
+// temperatureStep rounds oven temperatures coarsely and everything else finely
+func temperatureStep(degrees, ovenAbove, ovenStep float64) float64 {
+	if degrees >= ovenAbove {
+		return ovenStep
+	}
+	return 1
+}
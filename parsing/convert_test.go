@@ -0,0 +1,81 @@
+package parsing_test
+
+import (
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+func TestConvertIngredient(t *testing.T) {
+	tests := []struct {
+		line   string
+		units  parsing.UnitSystem
+		amount float64
+		unit   string
+	}{
+		{"1 cup all-purpose flour", parsing.UnitsMetric, 125, "g"},
+		{"2 tbsp butter", parsing.UnitsMetric, 28, "g"},
+		{"1 cup milk", parsing.UnitsMetric, 235, "ml"},
+		{"5 cups chicken stock", parsing.UnitsMetric, 1.2, "l"},
+		{"1 lb ground beef", parsing.UnitsMetric, 455, "g"},
+		{"1 tsp baking soda", parsing.UnitsMetric, 4.5, "g"},
+		{"250 g flour", parsing.UnitsUS, 2, "cup"},
+		{"500 g chicken thighs", parsing.UnitsUS, 1, "lb"},
+		{"100 g chocolate", parsing.UnitsUS, 3.5, "oz"},
+		{"30 ml lemon juice", parsing.UnitsUS, 2, "tbsp"},
+		{"5 ml vanilla extract", parsing.UnitsUS, 1, "tsp"},
+	}
+	for _, test := range tests {
+		t.Run(test.line+" to "+string(test.units), func(t *testing.T) {
+			converted := parsing.ConvertIngredient(parsing.ParseIngredient(test.line), test.units)
+			is.NotNil(t, converted.Amount)
+			is.Equal(t, test.amount, *converted.Amount)
+			is.Equal(t, test.unit, converted.Unit)
+		})
+	}
+
+	t.Run("leaves units without a conversion alone", func(t *testing.T) {
+		converted := parsing.ConvertIngredient(parsing.ParseIngredient("3 cloves garlic"), parsing.UnitsMetric)
+		is.Equal(t, 3.0, *converted.Amount)
+		is.Equal(t, "clove", converted.Unit)
+	})
+
+	t.Run("leaves ingredients already in the system alone", func(t *testing.T) {
+		converted := parsing.ConvertIngredient(parsing.ParseIngredient("200 g sugar"), parsing.UnitsMetric)
+		is.Equal(t, 200.0, *converted.Amount)
+		is.Equal(t, "g", converted.Unit)
+	})
+
+	t.Run("does not change the original amount", func(t *testing.T) {
+		ingredient := parsing.ParseIngredient("1 cup sugar")
+		parsing.ConvertIngredient(ingredient, parsing.UnitsMetric)
+		is.Equal(t, 1.0, *ingredient.Amount)
+	})
+}
+
+func TestConvertTemperatures(t *testing.T) {
+	tests := []struct {
+		text     string
+		units    parsing.UnitSystem
+		expected string
+	}{
+		{"Preheat the oven to 350°F.", parsing.UnitsMetric, "Preheat the oven to 180°C."},
+		{"Bake at 425 degrees F for 20 minutes.", parsing.UnitsMetric, "Bake at 220°C for 20 minutes."},
+		{"Heat oven to 400F", parsing.UnitsMetric, "Heat oven to 200°C"},
+		{"Bake at 350 F for 20 minutes", parsing.UnitsMetric, "Bake at 180°C for 20 minutes"},
+		{"Heat to 180 C.", parsing.UnitsUS, "Heat to 350°F."},
+		{"Add 20 c flour.", parsing.UnitsUS, "Add 20 c flour."},
+		{"Preheat the oven to 180°C.", parsing.UnitsUS, "Preheat the oven to 350°F."},
+		{"Roast at 200 Celsius", parsing.UnitsUS, "Roast at 400°F"},
+		{"Preheat the oven to 350°F (180°C).", parsing.UnitsMetric, "Preheat the oven to 350°F (180°C)."},
+		{"Add 2 C flour and bake at 350°F.", parsing.UnitsUS, "Add 2 C flour and bake at 350°F."},
+		{"Preheat the oven to 350°F.", parsing.UnitsOriginal, "Preheat the oven to 350°F."},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			is.Equal(t, test.expected, parsing.ConvertTemperatures(test.text, test.units))
+		})
+	}
+}
@@ -0,0 +1,487 @@
+package parsing
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNoCooklangRecipe is returned for Cooklang files with no steps, ingredients or notes
+var ErrNoCooklangRecipe = errors.New("no recipe found in the Cooklang file")
+
+// Cooklang is a recipe as a Cooklang file (https://cooklang.org) holds it. The
+// name, description and source are kept in the file's metadata, and the rest
+// is read into the collection: sections become sub-recipes and the cookware
+// the steps use is listed in a note.
+type Cooklang struct {
+	Title       string
+	Description string
+	Source      string
+	Collection  *RecipeCollection
+}
+
+var (
+	cooklangBlockCommentRe = regexp.MustCompile(`(?s)\[-.*?-\]`)
+	// Names of more than one word run up to the braces, like "@olive oil{2%tbsp}"
+	cooklangComponentRe = regexp.MustCompile(`^([@#~])([@&?+\-]*)([^@#~{}\n]*?)\{([^}]*)\}(?:\(([^)]*)\))?`)
+	cooklangWordRe      = regexp.MustCompile(`^([@#])([@&?+\-]*)([\p{L}\p{N}_\-]+)(?:\(([^)]*)\))?`)
+	cooklangLeftoverRe  = regexp.MustCompile(`^[\s,;.&]*(and)?[\s,;.]*$`)
+)
+
+// ParseCooklang reads a Cooklang file
+func ParseCooklang(text string) (*Cooklang, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = cooklangBlockCommentRe.ReplaceAllString(text, "")
+
+	recipe := &Cooklang{Collection: &RecipeCollection{Recipes: []Recipe{{Ingredients: []Ingredient{}}}}}
+	part := 0
+	cookware := [][]string{nil}
+	text = cooklangFrontMatter(text, recipe)
+
+	var paragraph []string
+	endParagraph := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		current := &recipe.Collection.Recipes[part]
+		step, ingredients, tools := cooklangStep(strings.Join(paragraph, " "))
+		for _, ingredient := range ingredients {
+			current.Ingredients = addCooklangIngredient(current.Ingredients, ingredient)
+		}
+		cookware[part] = append(cookware[part], tools...)
+		// A step that only lists ingredients, as exports write the ones no
+		// step names, is not an instruction
+		if leftover := cooklangComponentText(step, ingredients); len(ingredients) == 0 || !cooklangLeftoverRe.MatchString(leftover) {
+			current.Instructions = append(current.Instructions, step)
+		}
+		paragraph = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(cooklangLineComment(line))
+		switch {
+		case line == "":
+			endParagraph()
+		case strings.HasPrefix(line, ">>"):
+			endParagraph()
+			key, value, _ := strings.Cut(strings.TrimPrefix(line, ">>"), ":")
+			recipe.metadata(key, value)
+		case strings.HasPrefix(line, ">"):
+			endParagraph()
+			if note := strings.TrimSpace(cooklangUnescape(strings.TrimPrefix(line, ">"))); note != "" {
+				recipe.Collection.Recipes[part].Notes = append(recipe.Collection.Recipes[part].Notes, note)
+			}
+		case strings.HasPrefix(line, "="):
+			endParagraph()
+			name := strings.TrimSpace(strings.Trim(line, "="))
+			recipe.Collection.Recipes = append(recipe.Collection.Recipes, Recipe{Name: name, Ingredients: []Ingredient{}})
+			cookware = append(cookware, nil)
+			part = len(recipe.Collection.Recipes) - 1
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	endParagraph()
+
+	empty := true
+	for i := range recipe.Collection.Recipes {
+		current := &recipe.Collection.Recipes[i]
+		if tools := uniqueFold(cookware[i]); len(tools) > 0 {
+			current.Notes = append(current.Notes, "Cookware: "+strings.Join(tools, ", "))
+		}
+		if len(current.Ingredients) > 0 || len(current.Instructions) > 0 || len(current.Notes) > 0 {
+			empty = false
+		}
+	}
+	if empty {
+		return nil, ErrNoCooklangRecipe
+	}
+	recipe.Collection.Recipes[0].Name = recipe.Title
+	return recipe, nil
+}
+
+// cooklangFrontMatter reads the YAML front matter newer Cooklang files start
+// with, and gives the rest of the file
+func cooklangFrontMatter(text string, recipe *Cooklang) string {
+	if !strings.HasPrefix(text, "---\n") {
+		return text
+	}
+	block, rest, ok := strings.Cut(text[len("---\n"):], "\n---")
+	if !ok {
+		return text
+	}
+	var key string
+	var list []string
+	flush := func() {
+		if key != "" && list != nil {
+			recipe.metadata(key, strings.Join(list, ", "))
+		}
+		list = nil
+	}
+	for _, line := range strings.Split(block, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if item, ok := strings.CutPrefix(trimmed, "- "); ok && key != "" {
+			list = append(list, strings.Trim(strings.TrimSpace(item), `"'`))
+			continue
+		}
+		k, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		flush()
+		key = k
+		value = strings.TrimSpace(value)
+		if value == "" {
+			list = []string{}
+			continue
+		}
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			var items []string
+			for _, item := range strings.Split(strings.Trim(value, "[]"), ",") {
+				items = append(items, strings.Trim(strings.TrimSpace(item), `"'`))
+			}
+			value = strings.Join(items, ", ")
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		recipe.metadata(key, strings.Trim(value, "'"))
+	}
+	flush()
+	_, rest, _ = strings.Cut(rest, "\n")
+	return rest
+}
+
+// metadata stores one ">> key: value" line of the file
+func (c *Cooklang) metadata(key, value string) {
+	main := &c.Collection.Recipes[0]
+	value = strings.TrimSpace(value)
+	list := func() []string {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	switch strings.ToLower(strings.NewReplacer("_", " ", "-", " ").Replace(strings.TrimSpace(key))) {
+	case "title", "name":
+		c.Title = value
+	case "description", "introduction":
+		c.Description = value
+	case "source", "source.url", "url":
+		c.Source = value
+	case "image", "picture":
+		main.Image = value
+	case "servings", "serves", "yield":
+		main.Servings = ParseServings(value)
+	case "prep time", "preptime", "time.prep":
+		main.PrepTime = value
+	case "cook time", "cooktime", "time.cook":
+		main.CookTime = value
+	case "total time", "time", "duration":
+		main.TotalTime = value
+	case "cuisine":
+		main.Cuisine = list()
+	case "tags", "course", "category":
+		main.Tags = append(main.Tags, list()...)
+	}
+}
+
+// cooklangLineComment drops a "-- comment" from the end of a line
+func cooklangLineComment(line string) string {
+	if i := strings.Index(line, "--"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+// cooklangStep reads a step's text, giving it with the markup taken out along
+// with the ingredients and cookware it names
+func cooklangStep(text string) (string, []Ingredient, []string) {
+	var step strings.Builder
+	var ingredients []Ingredient
+	var cookware []string
+	for i := 0; i < len(text); {
+		c := text[i]
+		if c == '\\' && i+1 < len(text) && strings.IndexByte("@#~", text[i+1]) >= 0 {
+			step.WriteByte(text[i+1])
+			i += 2
+			continue
+		}
+		if c != '@' && c != '#' && c != '~' {
+			step.WriteByte(c)
+			i++
+			continue
+		}
+
+		var kind, modifiers, name, quantity, note string
+		if match := cooklangComponentRe.FindStringSubmatch(text[i:]); match != nil && (c == '~' || strings.TrimSpace(match[3]) != "") {
+			kind, modifiers, name, quantity, note = match[1], match[2], match[3], match[4], match[5]
+			i += len(match[0])
+		} else if match := cooklangWordRe.FindStringSubmatch(text[i:]); match != nil {
+			kind, modifiers, name, note = match[1], match[2], match[3], match[4]
+			i += len(match[0])
+		} else {
+			step.WriteByte(c)
+			i++
+			continue
+		}
+
+		// "@name|alias{}" names the ingredient one way and the step another
+		name, alias, ok := strings.Cut(strings.TrimSpace(name), "|")
+		if !ok {
+			alias = name
+		}
+		switch kind {
+		case "@":
+			step.WriteString(alias)
+			ingredient := cooklangIngredient(name, quantity, note)
+			if strings.Contains(modifiers, "?") {
+				ingredient.Notes = strings.TrimPrefix(ingredient.Notes+", optional", ", ")
+			}
+			ingredients = append(ingredients, ingredient)
+		case "#":
+			step.WriteString(alias)
+			cookware = append(cookware, strings.TrimSpace(name))
+		case "~":
+			amount, unit, _ := strings.Cut(quantity, "%")
+			step.WriteString(strings.TrimSpace(strings.TrimSpace(amount) + " " + strings.TrimSpace(unit)))
+		}
+	}
+	return strings.TrimSpace(spaceRe.ReplaceAllString(step.String(), " ")), ingredients, cookware
+}
+
+// cooklangIngredient reads an ingredient's quantity, like "2%cups" or "1/2"
+func cooklangIngredient(name, quantity, note string) Ingredient {
+	ingredient := Ingredient{Name: singularize(strings.ToLower(strings.TrimSpace(name)))}
+	amount, unit, _ := strings.Cut(quantity, "%")
+	// "=" marks an amount that doesn't scale with the servings
+	amount = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(amount), "="))
+	var notes []string
+	if amount != "" {
+		value, rest := parseAmount(normalizeIngredientLine(amount))
+		ingredient.Amount = value
+		if rest = strings.TrimSpace(rest); rest != "" {
+			notes = append(notes, rest)
+		}
+	}
+	if unit = strings.TrimSpace(unit); unit != "" {
+		ingredient.Unit = NormalizeUnit(unit)
+	}
+	if note = strings.TrimSpace(note); note != "" {
+		notes = append(notes, note)
+	}
+	ingredient.Notes = strings.Join(notes, ", ")
+	return ingredient
+}
+
+// addCooklangIngredient adds an ingredient to a part's list. Cooklang names an
+// ingredient again each time a step uses it, so amounts in the same unit are
+// added up rather than listed twice.
+func addCooklangIngredient(ingredients []Ingredient, ingredient Ingredient) []Ingredient {
+	for i, existing := range ingredients {
+		if existing.Name != ingredient.Name || existing.Unit != ingredient.Unit {
+			continue
+		}
+		switch {
+		case existing.Amount != nil && ingredient.Amount != nil:
+			total := *existing.Amount + *ingredient.Amount
+			ingredients[i].Amount = &total
+			return ingredients
+		case existing.Amount == nil && ingredient.Amount == nil && existing.Notes == ingredient.Notes:
+			return ingredients
+		}
+	}
+	return append(ingredients, ingredient)
+}
+
+// cooklangComponentText takes the names of a step's ingredients out of its
+// text, to see what else the step says
+func cooklangComponentText(step string, ingredients []Ingredient) string {
+	lower := strings.ToLower(step)
+	for _, ingredient := range ingredients {
+		re, err := regexp.Compile(`\b` + regexp.QuoteMeta(ingredient.Name) + `(e?s)?\b`)
+		if err != nil {
+			continue
+		}
+		lower = re.ReplaceAllString(lower, "")
+	}
+	return lower
+}
+
+// cooklangUnescape takes the backslashes out of escaped markup in plain text
+var cooklangUnescape = strings.NewReplacer(`\@`, "@", `\#`, "#", `\~`, "~").Replace
+
+// uniqueFold drops repeated names, ignoring case
+func uniqueFold(names []string) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+// cooklangSpecial are the characters that start an ingredient, cookware or
+// timer in Cooklang, and are escaped in plain text. A double dash would start
+// a comment.
+var cooklangSpecial = strings.NewReplacer("@", `\@`, "#", `\#`, "~", `\~`, "--", "–", "\n", " ")
+
+// WriteCooklang writes the recipe in Cooklang. Cooklang marks ingredients where
+// the steps use them, so each ingredient is marked the first time a step names
+// it, and ingredients no step names are listed in a step of their own first.
+func WriteCooklang(w io.Writer, recipe *Cooklang) error {
+	var all []Recipe
+	if recipe.Collection != nil {
+		all = recipe.Collection.Recipes
+	}
+	if len(all) == 0 {
+		all = []Recipe{{Name: recipe.Title}}
+	}
+	main := all[0]
+	b := bufio.NewWriter(w)
+
+	metadata := func(key, value string) {
+		if value = strings.TrimSpace(strings.ReplaceAll(value, "\n", " ")); value != "" {
+			fmt.Fprintf(b, ">> %s: %s\n", key, strings.ReplaceAll(value, "--", "–"))
+		}
+	}
+	metadata("title", recipe.Title)
+	metadata("description", recipe.Description)
+	metadata("source", recipe.Source)
+	metadata("image", main.Image)
+	if main.Servings > 0 {
+		metadata("servings", fmt.Sprint(main.Servings))
+	}
+	metadata("prep time", cooklangTime(main.PrepTime))
+	metadata("cook time", cooklangTime(main.CookTime))
+	metadata("total time", cooklangTime(main.TotalTime))
+	metadata("cuisine", strings.Join(main.Cuisine, ", "))
+	metadata("tags", strings.Join(main.Tags, ", "))
+
+	for i, part := range all {
+		if i > 0 {
+			fmt.Fprintf(b, "\n== %s ==\n", strings.ReplaceAll(part.Name, "\n", " "))
+		}
+		for _, step := range cooklangSteps(part) {
+			fmt.Fprintf(b, "\n%s\n", step)
+		}
+		for _, note := range part.Notes {
+			fmt.Fprintf(b, "\n> %s\n", cooklangSpecial.Replace(note))
+		}
+	}
+	return b.Flush()
+}
+
+// cooklangTime writes a time the way people write it, like "1 hr 5 min"
+func cooklangTime(value string) string {
+	if d := ParseDurationOrZero(value); d > 0 {
+		return d.String()
+	}
+	return value
+}
+
+// cooklangMark is where a step names one of the ingredients
+type cooklangMark struct {
+	start, end int
+	ingredient Ingredient
+}
+
+// cooklangSteps writes a part's steps with its ingredients marked in them
+func cooklangSteps(part Recipe) []string {
+	// Longer names first, so "olive oil" is found before "oil"
+	order := make([]int, len(part.Ingredients))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return len(part.Ingredients[order[a]].Name) > len(part.Ingredients[order[b]].Name)
+	})
+
+	marks := make([][]cooklangMark, len(part.Instructions))
+	named := make([]bool, len(part.Ingredients))
+	for _, i := range order {
+		named[i] = markIngredient(part.Instructions, marks, part.Ingredients[i])
+	}
+
+	var steps []string
+	var listed []string
+	for i, ingredient := range part.Ingredients {
+		if !named[i] {
+			listed = append(listed, cooklangMarkup(ingredient.Name, ingredient))
+		}
+	}
+	if len(listed) > 0 {
+		steps = append(steps, strings.Join(listed, ", "))
+	}
+	for i, text := range part.Instructions {
+		sort.Slice(marks[i], func(a, b int) bool { return marks[i][a].start < marks[i][b].start })
+		var step strings.Builder
+		at := 0
+		for _, mark := range marks[i] {
+			step.WriteString(cooklangSpecial.Replace(text[at:mark.start]))
+			step.WriteString(cooklangMarkup(text[mark.start:mark.end], mark.ingredient))
+			at = mark.end
+		}
+		step.WriteString(cooklangSpecial.Replace(text[at:]))
+		steps = append(steps, step.String())
+	}
+	return steps
+}
+
+// markIngredient finds the first step that names the ingredient, in the
+// singular or plural, where no other ingredient was already found
+func markIngredient(steps []string, marks [][]cooklangMark, ingredient Ingredient) bool {
+	name := strings.TrimSpace(ingredient.Name)
+	if name == "" {
+		return false
+	}
+	re, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(name) + `(e?s)?\b`)
+	if err != nil {
+		return false
+	}
+	for i, step := range steps {
+	next:
+		for _, match := range re.FindAllStringIndex(step, -1) {
+			for _, mark := range marks[i] {
+				if match[0] < mark.end && mark.start < match[1] {
+					continue next
+				}
+			}
+			marks[i] = append(marks[i], cooklangMark{start: match[0], end: match[1], ingredient: ingredient})
+			return true
+		}
+	}
+	return false
+}
+
+// cooklangMarkup writes an ingredient as it is marked in a step, like
+// "@olive oil{2%tbsp}(extra virgin)"
+func cooklangMarkup(name string, ingredient Ingredient) string {
+	clean := strings.NewReplacer("{", "", "}", "", "@", "", "#", "", "~", "", "(", "", ")", "", "\n", " ")
+	var quantity string
+	if ingredient.Amount != nil {
+		quantity = strconv.FormatFloat(math.Round(*ingredient.Amount*100)/100, 'f', -1, 64)
+		if ingredient.Unit != "" {
+			quantity += "%" + clean.Replace(ingredient.Unit)
+		}
+	}
+	out := "@" + clean.Replace(name) + "{" + quantity + "}"
+	if ingredient.Notes != "" {
+		out += "(" + clean.Replace(ingredient.Notes) + ")"
+	}
+	return out
+}
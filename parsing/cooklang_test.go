@@ -0,0 +1,103 @@
+package parsing_test
+
+import (
+	"strings"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+const shakshukaCook = `>> title: Shakshuka
+>> servings: 4
+>> time: 35 minutes
+>> tags: Breakfast, Vegetarian
+-- Mum's version, less cumin
+
+Heat @olive oil{2%tbsp} in a #large pan{}. Add the @onion{1}(diced) and cook for ~{5%minutes}.
+
+Stir in @tomatoes{800%g}, @cumin{1%tsp} and a pinch of @salt. Simmer for ~{10%minutes}.
+
+Make four wells and crack in the @eggs{4}. Cover and cook until set. [- the whites should be firm -]
+
+Top with @feta{}(crumbled) and serve with @bread{}.
+
+> Use \@home grown tomatoes when you can.
+
+== Yogurt sauce ==
+
+Mix @yogurt{1%cup} with @garlic{1%clove} and a @salt.
+`
+
+// ingredientLines writes a recipe's ingredients back out, one per "|"
+func ingredientLines(recipe parsing.Recipe) string {
+	var lines []string
+	for _, ingredient := range recipe.Ingredients {
+		lines = append(lines, parsing.FormatIngredient(ingredient))
+	}
+	return strings.Join(lines, "|")
+}
+
+func TestParseCooklang(t *testing.T) {
+	recipe, err := parsing.ParseCooklang(shakshukaCook)
+	is.NotError(t, err)
+	is.Equal(t, "Shakshuka", recipe.Title)
+	is.Equal(t, 2, len(recipe.Collection.Recipes))
+
+	main := recipe.Collection.Recipes[0]
+	is.Equal(t, "Shakshuka", main.Name)
+	is.Equal(t, 4, main.Servings)
+	is.Equal(t, "35 minutes", main.TotalTime)
+	is.Equal(t, "Breakfast,Vegetarian", strings.Join(main.Tags, ","))
+	is.Equal(t, "2 tbsp olive oil|1 onion, diced|800 g tomato|1 tsp cumin|salt|4 egg|feta, crumbled|bread", ingredientLines(main))
+	is.Equal(t, "Heat olive oil in a large pan. Add the onion and cook for 5 minutes.", main.Instructions[0])
+	is.Equal(t, "Stir in tomatoes, cumin and a pinch of salt. Simmer for 10 minutes.", main.Instructions[1])
+	is.Equal(t, "Make four wells and crack in the eggs. Cover and cook until set.", main.Instructions[2])
+	is.Equal(t, "Use @home grown tomatoes when you can.|Cookware: large pan", strings.Join(main.Notes, "|"))
+
+	sauce := recipe.Collection.Recipes[1]
+	is.Equal(t, "Yogurt sauce", sauce.Name)
+	is.Equal(t, "1 cup yogurt|1 clove garlic|salt", ingredientLines(sauce))
+	is.Equal(t, "Mix yogurt with garlic and a salt.", strings.Join(sauce.Instructions, "|"))
+}
+
+func TestParseCooklang_addsUpRepeatedIngredients(t *testing.T) {
+	recipe, err := parsing.ParseCooklang("Melt @butter{1%tbsp}.\n\nBrush with @butter{2%tbsp} and @sugar{}.\n\nDust with @sugar{}.")
+	is.NotError(t, err)
+	is.Equal(t, "3 tbsp butter|sugar", ingredientLines(recipe.Collection.Recipes[0]))
+}
+
+func TestParseCooklang_frontMatter(t *testing.T) {
+	recipe, err := parsing.ParseCooklang("---\ntitle: \"Toast\"\nservings: 2\ntags:\n  - quick\n  - breakfast\n---\n\nToast the @bread{2%slices}.\n")
+	is.NotError(t, err)
+	is.Equal(t, "Toast", recipe.Title)
+	is.Equal(t, 2, recipe.Collection.Recipes[0].Servings)
+	is.Equal(t, "quick,breakfast", strings.Join(recipe.Collection.Recipes[0].Tags, ","))
+	is.Equal(t, "2 slice bread", ingredientLines(recipe.Collection.Recipes[0]))
+}
+
+func TestParseCooklang_empty(t *testing.T) {
+	_, err := parsing.ParseCooklang(">> title: Nothing yet\n-- to do\n")
+	is.Error(t, parsing.ErrNoCooklangRecipe, err)
+}
+
+// TestCooklang_roundTrip checks that writing what was read, and reading it
+// again, gives the same file
+func TestCooklang_roundTrip(t *testing.T) {
+	recipe, err := parsing.ParseCooklang(shakshukaCook)
+	is.NotError(t, err)
+	var first strings.Builder
+	is.NotError(t, parsing.WriteCooklang(&first, recipe))
+
+	again, err := parsing.ParseCooklang(first.String())
+	is.NotError(t, err)
+	var second strings.Builder
+	is.NotError(t, parsing.WriteCooklang(&second, again))
+	is.Equal(t, first.String(), second.String())
+
+	is.Equal(t, ingredientLines(recipe.Collection.Recipes[0]), ingredientLines(again.Collection.Recipes[0]))
+	is.Equal(t, strings.Join(recipe.Collection.Recipes[0].Instructions, "|"), strings.Join(again.Collection.Recipes[0].Instructions, "|"))
+	is.Equal(t, strings.Join(recipe.Collection.Recipes[0].Notes, "|"), strings.Join(again.Collection.Recipes[0].Notes, "|"))
+	is.Equal(t, "Yogurt sauce", again.Collection.Recipes[1].Name)
+}
@@ -0,0 +1,191 @@
+package parsing
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is how long part of a recipe takes, such as its prep or cook time
+type Duration time.Duration
+
+// MaxDuration is the longest a recipe is taken to last. Longer times are junk
+// from the page, and would not fit the columns recipes are sorted by.
+const MaxDuration = Duration(7 * 24 * time.Hour)
+
+var (
+	isoDurationRe    = regexp.MustCompile(`(?i)^P(?:(\d+(?:\.\d+)?)Y)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)W)?(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+	clockDurationRe  = regexp.MustCompile(`^(\d+):(\d{2})$`)
+	letterDigitRe    = regexp.MustCompile(`([a-zA-Z])(\d)`)
+	phraseDurationRe = regexp.MustCompile(`(?i)(\d+\s+\d+/\d+|\d+/\d+|\d*\.\d+|\d+)\s*(days?|d|hours?|hrs?|h|minutes?|mins?|m|seconds?|secs?|s)\b`)
+)
+
+// isoUnits are the sizes of the ISO-8601 designators, in the order isoDurationRe captures them
+var isoUnits = []time.Duration{
+	365 * 24 * time.Hour, 30 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour,
+	time.Hour, time.Minute, time.Second,
+}
+
+// ParseDuration reads an ISO-8601 duration like "PT1H15M", a clock time like
+// "1:15", or a phrase like "1 hr 15 mins".
+func ParseDuration(s string) (Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	if match := isoDurationRe.FindStringSubmatch(s); match != nil && len(s) > 1 && !strings.EqualFold(s, "PT") {
+		var total time.Duration
+		for i, unit := range isoUnits {
+			if match[i+1] == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(match[i+1], 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+			}
+			if total, err = addDuration(total, value, unit); err != nil {
+				return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+			}
+		}
+		return Duration(total), nil
+	}
+
+	if match := clockDurationRe.FindStringSubmatch(s); match != nil {
+		hours, _ := strconv.ParseFloat(match[1], 64)
+		minutes, _ := strconv.ParseFloat(match[2], 64)
+		total, err := addDuration(0, hours*60+minutes, time.Minute)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		return Duration(total), nil
+	}
+
+	// Split compact forms like "2h30m" so each unit ends on a word boundary
+	phrase := letterDigitRe.ReplaceAllString(normalizeIngredientLine(s), "$1 $2")
+	matches := phraseDurationRe.FindAllStringSubmatch(phrase, -1)
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var total time.Duration
+	for _, match := range matches {
+		value, ok := parseNumber(match[1])
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		var unit time.Duration
+		switch strings.ToLower(match[2])[0] {
+		case 'd':
+			unit = 24 * time.Hour
+		case 'h':
+			unit = time.Hour
+		case 'm':
+			unit = time.Minute
+		case 's':
+			unit = time.Second
+		}
+		var err error
+		if total, err = addDuration(total, value, unit); err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+	}
+	return Duration(total), nil
+}
+
+// addDuration adds value units to total, refusing to go past MaxDuration
+func addDuration(total time.Duration, value float64, unit time.Duration) (time.Duration, error) {
+	part := value * float64(unit)
+	if part > float64(MaxDuration) || total+time.Duration(part) > time.Duration(MaxDuration) {
+		return 0, fmt.Errorf("longer than %s", MaxDuration)
+	}
+	return total + time.Duration(part), nil
+}
+
+// ParseDurationOrZero is ParseDuration for callers that treat unknown times as missing
+func ParseDurationOrZero(s string) Duration {
+	d, err := ParseDuration(s)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// String gives a readable form like "1 hr 15 min"
+func (d Duration) String() string {
+	if d <= 0 {
+		return ""
+	}
+	dur := time.Duration(d)
+	if dur < time.Minute {
+		return fmt.Sprintf("%d sec", int(dur.Seconds()))
+	}
+	dur = dur.Round(time.Minute)
+	days := int(dur / (24 * time.Hour))
+	hours := int(dur % (24 * time.Hour) / time.Hour)
+	minutes := int(dur % time.Hour / time.Minute)
+
+	var parts []string
+	if days == 1 {
+		parts = append(parts, "1 day")
+	} else if days > 1 {
+		parts = append(parts, fmt.Sprintf("%d days", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%d hr", hours))
+	}
+	if minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%d min", minutes))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ISO8601 gives the schema.org form of the duration, like "PT1H15M"
+func (d Duration) ISO8601() string {
+	if d <= 0 {
+		return ""
+	}
+	dur := time.Duration(d).Round(time.Second)
+	hours := int(dur / time.Hour)
+	minutes := int(dur % time.Hour / time.Minute)
+	seconds := int(dur % time.Minute / time.Second)
+
+	var b strings.Builder
+	b.WriteString("PT")
+	if hours > 0 {
+		fmt.Fprintf(&b, "%dH", hours)
+	}
+	if minutes > 0 {
+		fmt.Fprintf(&b, "%dM", minutes)
+	}
+	if seconds > 0 {
+		fmt.Fprintf(&b, "%dS", seconds)
+	}
+	return b.String()
+}
+
+// Seconds gives the duration in whole seconds, for storing in sortable columns
+func (d Duration) Seconds() int {
+	return int(time.Duration(d) / time.Second)
+}
+
+// Times gives the prep, cook and total time of the main recipe in the
+// collection. A missing total is filled in from the prep and cook times, and
+// is unknown when that comes to more than MaxDuration.
+func (c *RecipeCollection) Times() (prep, cook, total Duration) {
+	if c == nil || len(c.Recipes) == 0 {
+		return 0, 0, 0
+	}
+	recipe := c.Recipes[0]
+	prep = ParseDurationOrZero(recipe.PrepTime)
+	cook = ParseDurationOrZero(recipe.CookTime)
+	total = ParseDurationOrZero(recipe.TotalTime)
+	if total == 0 {
+		total = prep + cook
+	}
+	if total > MaxDuration {
+		total = 0
+	}
+	return prep, cook, total
+}
@@ -0,0 +1,70 @@
+package parsing_test
+
+import (
+	"testing"
+	"time"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{"PT1H15M", time.Hour + 15*time.Minute},
+		{"PT20M", 20 * time.Minute},
+		{"P0DT1H", time.Hour},
+		{"P0Y0M0DT0H35M0.000S", 35 * time.Minute},
+		{"pt0.5h", 30 * time.Minute},
+		{"P1D", 24 * time.Hour},
+		{"1 hr 20 mins", time.Hour + 20*time.Minute},
+		{"45 minutes", 45 * time.Minute},
+		{"1 1/2 hours", 90 * time.Minute},
+		{"½ hour", 30 * time.Minute},
+		{"2h30m", 2*time.Hour + 30*time.Minute},
+		{"1:05", time.Hour + 5*time.Minute},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			d, err := parsing.ParseDuration(test.input)
+			is.NotError(t, err)
+			is.Equal(t, test.expected, time.Duration(d))
+		})
+	}
+
+	t.Run("rejects text without a duration", func(t *testing.T) {
+		_, err := parsing.ParseDuration("overnight")
+		is.True(t, err != nil)
+	})
+
+	for _, input := range []string{"PT999999999H", "PT1000000H", "P8D", "P5DT100H", "1000000:00", "9999999999 days"} {
+		t.Run("rejects "+input+" as longer than the cap", func(t *testing.T) {
+			_, err := parsing.ParseDuration(input)
+			is.True(t, err != nil)
+		})
+	}
+}
+
+func TestRecipeCollection_Times(t *testing.T) {
+	t.Run("treats times past the cap as unknown", func(t *testing.T) {
+		collection := &parsing.RecipeCollection{Recipes: []parsing.Recipe{{PrepTime: "PT20M", TotalTime: "PT1000000H"}}}
+		prep, _, total := collection.Times()
+		is.Equal(t, parsing.Duration(20*time.Minute), prep)
+		is.Equal(t, parsing.Duration(20*time.Minute), total)
+
+		collection = &parsing.RecipeCollection{Recipes: []parsing.Recipe{{PrepTime: "P4D", CookTime: "P4D"}}}
+		_, _, total = collection.Times()
+		is.Equal(t, parsing.Duration(0), total)
+	})
+}
+
+func TestDuration_String(t *testing.T) {
+	is.Equal(t, "1 hr 15 min", parsing.Duration(75*time.Minute).String())
+	is.Equal(t, "45 min", parsing.Duration(45*time.Minute).String())
+	is.Equal(t, "1 day 2 hr", parsing.Duration(26*time.Hour).String())
+	is.Equal(t, "PT1H15M", parsing.Duration(75*time.Minute).ISO8601())
+}
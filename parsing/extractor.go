@@ -0,0 +1,70 @@
+package parsing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoExtractor is returned when a recipe needs the LLM but no backend is configured
+var ErrNoExtractor = errors.New("no LLM backend configured")
+
+// ErrOverBudget is returned by extractors that may not be asked right now, such
+// as when a group has used up its LLM budget. The pipeline then makes do with
+// structured data, as it does without an LLM.
+var ErrOverBudget = errors.New("LLM budget used up")
+
+// Completion is the reply from an LLM backend
+type Completion struct {
+	Text         string
+	InputTokens  int
+	OutputTokens int
+}
+
+// RecipeExtractor sends an extraction prompt to an LLM backend
+type RecipeExtractor interface {
+	// Complete sends the prompt and returns the backend's reply
+	Complete(ctx context.Context, prompt string) (*Completion, error)
+
+	// Model names the backend and model, like "anthropic/claude-3-5-haiku-latest"
+	Model() string
+}
+
+// Supported values for ExtractorConfig.Backend
+const (
+	BackendAnthropic = "anthropic"
+	BackendOpenAI    = "openai"
+	BackendFake      = "fake"
+)
+
+// ExtractorConfig chooses the LLM backend used for recipe extraction
+type ExtractorConfig struct {
+	Backend string // One of the Backend constants
+	Model   string // Backend specific model name, a default is used when empty
+	APIKey  string
+	BaseURL string // Only used by the OpenAI compatible backend, e.g. http://localhost:11434/v1 for Ollama
+}
+
+// NewRecipeExtractor builds the configured backend. It returns ErrNoExtractor
+// when nothing is configured so callers can carry on without the LLM.
+func NewRecipeExtractor(cfg ExtractorConfig) (RecipeExtractor, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "":
+		return nil, ErrNoExtractor
+	case BackendAnthropic:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("%w: anthropic backend needs an API key", ErrNoExtractor)
+		}
+		return newAnthropicExtractor(cfg), nil
+	case BackendOpenAI:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("%w: openai backend needs a base URL", ErrNoExtractor)
+		}
+		return newOpenAIExtractor(cfg), nil
+	case BackendFake:
+		return &FakeExtractor{}, nil
+	default:
+		return nil, fmt.Errorf("unknown LLM backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,53 @@
+package parsing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+func TestNewRecipeExtractor(t *testing.T) {
+	t.Run("returns ErrNoExtractor when nothing is configured", func(t *testing.T) {
+		_, err := parsing.NewRecipeExtractor(parsing.ExtractorConfig{})
+		is.True(t, errors.Is(err, parsing.ErrNoExtractor))
+	})
+
+	t.Run("returns ErrNoExtractor when the anthropic key is missing", func(t *testing.T) {
+		_, err := parsing.NewRecipeExtractor(parsing.ExtractorConfig{Backend: parsing.BackendAnthropic})
+		is.True(t, errors.Is(err, parsing.ErrNoExtractor))
+	})
+
+	t.Run("talks to an OpenAI compatible endpoint", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			is.Equal(t, "/v1/chat/completions", r.URL.Path)
+			var req struct {
+				Model string `json:"model"`
+			}
+			is.NotError(t, json.NewDecoder(r.Body).Decode(&req))
+			is.Equal(t, "llama3", req.Model)
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"recipes\":[]}"}}],"usage":{"prompt_tokens":12,"completion_tokens":3}}`))
+		}))
+		defer ts.Close()
+
+		extractor, err := parsing.NewRecipeExtractor(parsing.ExtractorConfig{
+			Backend: parsing.BackendOpenAI,
+			Model:   "llama3",
+			BaseURL: ts.URL + "/v1/",
+		})
+		is.NotError(t, err)
+		is.Equal(t, "openai/llama3", extractor.Model())
+
+		completion, err := extractor.Complete(context.Background(), "prompt")
+		is.NotError(t, err)
+		is.Equal(t, `{"recipes":[]}`, completion.Text)
+		is.Equal(t, 12, completion.InputTokens)
+		is.Equal(t, 3, completion.OutputTokens)
+	})
+}
@@ -0,0 +1,48 @@
+package parsing
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeExtractor is a deterministic RecipeExtractor for tests and for running
+// the app without an LLM. It replies with Responses in order, repeating the
+// last one, and records every prompt it was sent.
+type FakeExtractor struct {
+	Responses []string
+	Err       error
+
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (f *FakeExtractor) Complete(ctx context.Context, prompt string) (*Completion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.prompts = append(f.prompts, prompt)
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	text := `{"recipes":[]}`
+	if len(f.Responses) > 0 {
+		text = f.Responses[min(len(f.prompts), len(f.Responses))-1]
+	}
+	return &Completion{
+		Text:         text,
+		InputTokens:  len(prompt) / 4,
+		OutputTokens: len(text) / 4,
+	}, nil
+}
+
+func (f *FakeExtractor) Model() string {
+	return BackendFake + "/fake"
+}
+
+// Prompts gives every prompt sent so far
+func (f *FakeExtractor) Prompts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.prompts...)
+}
@@ -0,0 +1,157 @@
+package parsing
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// MinHeuristicConfidence is the score heuristic results need before they are
+// used instead of asking the LLM
+const MinHeuristicConfidence = 0.6
+
+// Confidence scores from 0 to 1 how likely each extracted field is right.
+// Data from markup meant to describe the recipe scores 1.
+type Confidence struct {
+	Title        float64 `json:"title"`
+	Ingredients  float64 `json:"ingredients"`
+	Instructions float64 `json:"instructions"`
+}
+
+// structuredConfidence fully trusts every field the recipe's own markup filled
+func structuredConfidence(recipe *ExtractedRecipe) Confidence {
+	var c Confidence
+	if recipe.Title != "" {
+		c.Title = 1
+	}
+	if len(recipe.Ingredients) > 0 {
+		c.Ingredients = 1
+	}
+	if len(recipe.Instructions) > 0 {
+		c.Instructions = 1
+	}
+	return c
+}
+
+// Overall is the score of the weakest of the fields a recipe needs
+func (c Confidence) Overall() float64 {
+	return min(c.Ingredients, c.Instructions)
+}
+
+// Trusted tells if every field that was found scores well enough to be used
+// without asking the LLM
+func (e *ExtractedRecipe) Trusted() bool {
+	if e.Confidence.Ingredients < MinHeuristicConfidence {
+		return false
+	}
+	return len(e.Instructions) == 0 || e.Confidence.Instructions >= MinHeuristicConfidence
+}
+
+// How much each heuristic is trusted before looking at what it found. Class
+// names like "ingredients" are a better sign than text that mentions cups.
+const (
+	patternWeight      = 0.9
+	unstructuredWeight = 0.6
+)
+
+// extractHeuristics fills whatever the structured data left empty by guessing
+// from class names and text, scoring each guess
+func extractHeuristics(doc *goquery.Document, recipe *ExtractedRecipe) {
+	if len(recipe.Ingredients) == 0 {
+		weight := patternWeight
+		extractIngredientsByPatterns(doc, recipe)
+		if len(recipe.Ingredients) == 0 {
+			weight = unstructuredWeight
+			extractUnstructuredIngredients(doc, recipe)
+		}
+		recipe.Ingredients = cleanLines(recipe.Ingredients)
+		recipe.Confidence.Ingredients = ingredientConfidence(recipe.Ingredients, weight)
+	}
+
+	if len(recipe.Instructions) == 0 {
+		weight := patternWeight
+		extractInstructionsByPatterns(doc, recipe)
+		if len(recipe.Instructions) == 0 {
+			weight = unstructuredWeight
+			extractUnstructuredInstructions(doc, recipe)
+		}
+		recipe.Instructions = cleanLines(recipe.Instructions)
+		recipe.Confidence.Instructions = instructionConfidence(recipe.Instructions, weight)
+	}
+
+	if recipe.Title == "" {
+		if h1 := cleanText(doc.Find("h1").First().Text()); h1 != "" {
+			recipe.Title, recipe.Confidence.Title = h1, 0.8
+		} else if title := cleanText(doc.Find("title").First().Text()); title != "" {
+			recipe.Title, recipe.Confidence.Title = title, 0.5
+		}
+	}
+}
+
+// cleanLines collapses whitespace and drops empty and repeated lines
+func cleanLines(lines []string) []string {
+	seen := map[string]bool{}
+	cleaned := []string{}
+	for _, line := range lines {
+		line = cleanText(line)
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		cleaned = append(cleaned, line)
+	}
+	return cleaned
+}
+
+// ingredientConfidence scores lines by how many read like ingredients, with
+// an amount or unit and a short name
+func ingredientConfidence(lines []string, weight float64) float64 {
+	if len(lines) == 0 {
+		return 0
+	}
+	var good int
+	for _, line := range lines {
+		ingredient := ParseIngredient(line)
+		if (ingredient.Amount != nil || ingredient.Unit != "") && len(line) <= 120 {
+			good++
+		}
+	}
+	return weight * float64(good) / float64(len(lines)) * listSizePenalty(len(lines), 50)
+}
+
+// cookingVerbs are words nearly every recipe step has one of
+var cookingVerbs = []string{
+	"add", "bake", "beat", "blend", "boil", "bring", "chop", "combine", "cook", "cover",
+	"cut", "drain", "fold", "fry", "grill", "heat", "knead", "let", "mix", "place",
+	"pour", "preheat", "reduce", "remove", "roast", "season", "serve", "simmer", "slice",
+	"spread", "sprinkle", "stir", "toss", "transfer", "whisk",
+}
+
+// instructionConfidence scores lines by how many read like recipe steps
+func instructionConfidence(lines []string, weight float64) float64 {
+	if len(lines) == 0 {
+		return 0
+	}
+	var good int
+	for _, line := range lines {
+		if len(line) < 15 || len(line) > 800 {
+			continue
+		}
+		lower := strings.ToLower(line)
+		for _, verb := range cookingVerbs {
+			if containsWord(lower, verb) {
+				good++
+				break
+			}
+		}
+	}
+	return weight * float64(good) / float64(len(lines)) * listSizePenalty(len(lines), 40)
+}
+
+// listSizePenalty halves the score of lists too short or long to be a recipe's
+func listSizePenalty(n, most int) float64 {
+	if n < 2 || n > most {
+		return 0.5
+	}
+	return 1
+}
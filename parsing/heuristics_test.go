@@ -0,0 +1,88 @@
+package parsing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+const classNamePage = `<html><head><title>Grandma's Chili | My Blog</title></head><body>
+<nav><ul><li>Home</li><li>Recipes</li></ul></nav>
+<article>
+<h1>Grandma's Chili</h1>
+<div class="recipe-ingredients"><ul>
+<li>1 lb ground beef</li>
+<li>1 onion, chopped</li>
+<li>2 cans kidney beans</li>
+<li>1 can (28 oz) crushed tomatoes</li>
+<li>2 tbsp chili powder</li>
+</ul></div>
+<div class="recipe-directions"><ol>
+<li>Brown the beef with the onion in a large pot.</li>
+<li>Add the beans, tomatoes and chili powder.</li>
+<li>Simmer for an hour, stirring now and then.</li>
+</ol></div>
+</article></body></html>`
+
+const lowConfidencePage = `<html><body>
+<h1>Our Story</h1>
+<div class="ingredients-promo"><p>Shop our favorite ingredients!</p><p>Sign up for our newsletter</p></div>
+<div class="instructions-banner"><p>Read the instructions for returns</p></div>
+</body></html>`
+
+func TestParseRecipe_heuristics(t *testing.T) {
+	t.Run("finds ingredients and steps by class name", func(t *testing.T) {
+		recipe, err := parsing.ParseRecipe("", []byte(classNamePage))
+		is.NotError(t, err)
+		is.Equal(t, parsing.SourceHeuristic, recipe.Source)
+		is.Equal(t, "Grandma's Chili", recipe.Title)
+		is.Equal(t, 5, len(recipe.Ingredients))
+		is.Equal(t, "1 lb ground beef", recipe.Ingredients[0])
+		is.Equal(t, 3, len(recipe.Instructions))
+		is.True(t, recipe.Confidence.Ingredients >= parsing.MinHeuristicConfidence)
+		is.True(t, recipe.Confidence.Instructions >= parsing.MinHeuristicConfidence)
+		is.True(t, recipe.Trusted())
+	})
+
+	t.Run("scores text that does not read like a recipe low", func(t *testing.T) {
+		recipe, err := parsing.ParseRecipe("", []byte(lowConfidencePage))
+		is.NotError(t, err)
+		is.Equal(t, parsing.SourceHeuristic, recipe.Source)
+		is.True(t, recipe.Confidence.Ingredients < parsing.MinHeuristicConfidence)
+		is.True(t, !recipe.Trusted())
+	})
+
+	t.Run("structured data is fully trusted", func(t *testing.T) {
+		recipe, err := parsing.ParseRecipe("", []byte(jsonLDPage))
+		is.NotError(t, err)
+		is.Equal(t, 1.0, recipe.Confidence.Overall())
+	})
+}
+
+func TestPipeline_Extract_heuristics(t *testing.T) {
+	t.Run("uses confident heuristics without asking the LLM", func(t *testing.T) {
+		fake := &parsing.FakeExtractor{}
+		collection, source, err := parsing.NewPipeline(fake).Extract(context.Background(), "", []byte(classNamePage))
+		is.NotError(t, err)
+		is.Equal(t, parsing.SourceHeuristic, source)
+		is.Equal(t, 0, len(fake.Prompts()))
+		is.Equal(t, "ground beef", collection.Recipes[0].Ingredients[0].Name)
+	})
+
+	t.Run("asks the LLM when heuristics are unsure", func(t *testing.T) {
+		fake := &parsing.FakeExtractor{Responses: []string{`{"recipes":[{"name":"Nothing","ingredients":[{"name":"water","amount":null}]}]}`}}
+		_, source, err := parsing.NewPipeline(fake).Extract(context.Background(), "", []byte(lowConfidencePage))
+		is.NotError(t, err)
+		is.Equal(t, parsing.SourceLLM, source)
+		is.Equal(t, 1, len(fake.Prompts()))
+	})
+
+	t.Run("does not save unsure heuristics when there is no LLM", func(t *testing.T) {
+		_, _, err := parsing.NewPipeline(nil).Extract(context.Background(), "", []byte(lowConfidencePage))
+		is.True(t, errors.Is(err, parsing.ErrNoExtractor))
+	})
+}
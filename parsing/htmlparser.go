@@ -2,7 +2,6 @@ package parsing
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"html"
 	"log/slog"
@@ -14,53 +13,56 @@ import (
 
 // ExtractedRecipe represents the extracted recipe data
 type ExtractedRecipe struct {
-	Title        string   `json:"title"`
-	Description  string   `json:"description"`
-	ImageURL     string   `json:"image_url"`
-	Ingredients  []string `json:"ingredients"`
-	Instructions []string `json:"instructions"`
-	PrepTime     string   `json:"prep_time,omitempty"`
-	CookTime     string   `json:"cook_time,omitempty"`
-	TotalTime    string   `json:"total_time,omitempty"`
-	Yield        string   `json:"yield,omitempty"`
-	Author       string   `json:"author,omitempty"`
+	Title        string               `json:"title"`
+	Description  string               `json:"description"`
+	ImageURL     string               `json:"image_url"`
+	Ingredients  []string             `json:"ingredients"`
+	Instructions []string             `json:"instructions"`
+	PrepTime     string               `json:"prep_time,omitempty"`
+	CookTime     string               `json:"cook_time,omitempty"`
+	TotalTime    string               `json:"total_time,omitempty"`
+	Yield        string               `json:"yield,omitempty"`
+	Author       string               `json:"author,omitempty"`
+	VideoURL     string               `json:"video_url,omitempty"`
+	Nutrition    *Nutrition           `json:"nutrition,omitempty"` // Per serving
+	Sections     []InstructionSection `json:"sections,omitempty"`  // Instructions with their group names and media, when known
+	Source       Source               `json:"source,omitempty"`    // Which structured data the recipe came from
+	Confidence   Confidence           `json:"confidence"`
 }
 
-// JSONLDRecipe represents the JSON-LD schema.org/Recipe structure
-type JSONLDRecipe struct {
-	Context      string      `json:"@context"`
-	Type         string      `json:"@type"`
-	Name         string      `json:"name"`
-	Description  string      `json:"description"`
-	Image        interface{} `json:"image"` // Can be string or array of strings
-	Author       interface{} `json:"author"`
-	PrepTime     string      `json:"prepTime"`
-	CookTime     string      `json:"cookTime"`
-	TotalTime    string      `json:"totalTime"`
-	RecipeYield  interface{} `json:"recipeYield"`
-	Ingredients  interface{} `json:"recipeIngredient"`   // Can be array of strings
-	Instructions interface{} `json:"recipeInstructions"` // Can be array of strings or objects
-}
-
-// ParseRecipe extracts recipe information from HTML content
-func ParseRecipe(htmlContent []byte) (*ExtractedRecipe, error) {
+// ParseRecipe extracts recipe information from HTML content. pageURL picks a
+// site specific extractor and may be empty.
+func ParseRecipe(pageURL string, htmlContent []byte) (*ExtractedRecipe, error) {
 	recipe := &ExtractedRecipe{
 		Ingredients:  []string{},
 		Instructions: []string{},
 	}
 
-	// Try to parse structured JSON-LD data first
-	if jsonLDRecipe := extractJSONLD(htmlContent); jsonLDRecipe != nil {
-		mapJSONLDToRecipe(jsonLDRecipe, recipe)
-		slog.Info("found jsonLD in recipe")
-	}
-
-	// Parse HTML for additional information and as fallback
+	// Parse the HTML once for the site extractors and the fallbacks
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(htmlContent))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse HTML: %v", err)
 	}
 
+	// Sites we know beat the generic parsers
+	if site, ok := findSiteExtractor(pageURL, doc); ok {
+		siteRecipe, err := site.Extract(doc)
+		if err == nil && len(siteRecipe.Ingredients) > 0 {
+			siteRecipe.Source = SourceSite
+			siteRecipe.Confidence = structuredConfidence(siteRecipe)
+			slog.Info("used site extractor", "site", site.Name)
+			return siteRecipe, nil
+		}
+		slog.Info("site extractor found no ingredients, using generic parsers", "site", site.Name, "error", err)
+	}
+
+	// Try to parse structured JSON-LD data first
+	if jsonLDRecipe := extractJSONLD(doc); jsonLDRecipe != nil {
+		mapJSONLDToRecipe(jsonLDRecipe, recipe)
+		recipe.Source = SourceJSONLD
+		slog.Info("found jsonLD in recipe")
+	}
+
 	doc.Find("script").Each(func(i int, el *goquery.Selection) {
 		el.Remove()
 	})
@@ -68,6 +70,19 @@ func ParseRecipe(htmlContent []byte) (*ExtractedRecipe, error) {
 	// Try to extract schema.org microdata if JSON-LD wasn't found
 	if len(recipe.Ingredients) == 0 || len(recipe.Instructions) == 0 {
 		extractMicrodata(doc, recipe)
+		if recipe.Source == "" && len(recipe.Ingredients) > 0 {
+			recipe.Source = SourceMicrodata
+		}
+	}
+	recipe.Confidence = structuredConfidence(recipe)
+
+	// Last, guess from class names and text, scoring each guess
+	if len(recipe.Ingredients) == 0 || len(recipe.Instructions) == 0 {
+		hadIngredients := len(recipe.Ingredients) > 0
+		extractHeuristics(doc, recipe)
+		if !hadIngredients && len(recipe.Ingredients) > 0 {
+			recipe.Source = SourceHeuristic
+		}
 	}
 	if len(recipe.Ingredients) == 0 {
 		return nil, fmt.Errorf("failed to find ingredients")
@@ -149,142 +164,6 @@ func HtmlToText(htmlContent []byte) []byte {
 	return []byte(text)
 }
 
-// extractJSONLD extracts JSON-LD data from HTML
-func extractJSONLD(htmlContent []byte) *JSONLDRecipe {
-	re := regexp.MustCompile(`<script type="application/ld\+json">(.*?)</script>`)
-	matches := re.FindAllSubmatch(htmlContent, -1)
-
-	for _, match := range matches {
-		if len(match) >= 2 {
-			jsonContent := match[1]
-			var data map[string]interface{}
-
-			err := json.Unmarshal(jsonContent, &data)
-			if err != nil {
-				continue
-			}
-
-			// Check if this is an array of JSON-LD objects
-			if _, ok := data["@graph"]; ok {
-				graph, ok := data["@graph"].([]interface{})
-				if !ok {
-					continue
-				}
-
-				for _, item := range graph {
-					itemMap, ok := item.(map[string]interface{})
-					if !ok {
-						continue
-					}
-
-					if typeStr, ok := itemMap["@type"].(string); ok && typeStr == "Recipe" {
-						jsonBytes, err := json.Marshal(itemMap)
-						if err != nil {
-							continue
-						}
-
-						var recipe JSONLDRecipe
-						if err := json.Unmarshal(jsonBytes, &recipe); err == nil {
-							return &recipe
-						}
-					}
-				}
-				continue
-			}
-
-			// Check if this is a recipe directly
-			if typeStr, ok := data["@type"].(string); ok {
-				if typeStr == "Recipe" {
-					var recipe JSONLDRecipe
-					if err := json.Unmarshal(jsonContent, &recipe); err == nil {
-						return &recipe
-					}
-				}
-			}
-		}
-	}
-	return nil
-}
-
-// mapJSONLDToRecipe maps JSON-LD data to our Recipe struct
-func mapJSONLDToRecipe(jsonLD *JSONLDRecipe, recipe *ExtractedRecipe) {
-	recipe.Title = jsonLD.Name
-	recipe.Description = jsonLD.Description
-	recipe.PrepTime = jsonLD.PrepTime
-	recipe.CookTime = jsonLD.CookTime
-	recipe.TotalTime = jsonLD.TotalTime
-
-	// Handle image URL (can be string or array)
-	switch img := jsonLD.Image.(type) {
-	case string:
-		recipe.ImageURL = img
-	case []interface{}:
-		if len(img) > 0 {
-			if imgStr, ok := img[0].(string); ok {
-				recipe.ImageURL = imgStr
-			}
-		}
-	case map[string]interface{}:
-		if url, ok := img["url"].(string); ok {
-			recipe.ImageURL = url
-		}
-	}
-
-	// Handle author (can be string or object)
-	switch author := jsonLD.Author.(type) {
-	case string:
-		recipe.Author = author
-	case map[string]interface{}:
-		if name, ok := author["name"].(string); ok {
-			recipe.Author = name
-		}
-	}
-
-	// Handle yield (can be string or array)
-	switch yield := jsonLD.RecipeYield.(type) {
-	case string:
-		recipe.Yield = yield
-	case []interface{}:
-		if len(yield) > 0 {
-			if yieldStr, ok := yield[0].(string); ok {
-				recipe.Yield = yieldStr
-			}
-		}
-	}
-
-	// Handle ingredients (array of strings)
-	switch ingredients := jsonLD.Ingredients.(type) {
-	case []interface{}:
-		for _, ing := range ingredients {
-			if ingStr, ok := ing.(string); ok {
-				recipe.Ingredients = append(recipe.Ingredients, strings.TrimSpace(ingStr))
-			}
-		}
-	}
-
-	// Handle instructions (array of strings or objects)
-	switch instructions := jsonLD.Instructions.(type) {
-	case []interface{}:
-		for _, inst := range instructions {
-			if instStr, ok := inst.(string); ok {
-				recipe.Instructions = append(recipe.Instructions, strings.TrimSpace(instStr))
-			} else if instMap, ok := inst.(map[string]interface{}); ok {
-				if text, ok := instMap["text"].(string); ok {
-					recipe.Instructions = append(recipe.Instructions, strings.TrimSpace(text))
-				}
-			}
-		}
-	case string:
-		// Some sites just provide a single string with line breaks
-		lines := strings.Split(instructions, "\n")
-		for _, line := range lines {
-			if trimmed := strings.TrimSpace(line); trimmed != "" {
-				recipe.Instructions = append(recipe.Instructions, trimmed)
-			}
-		}
-	}
-}
-
 // extractMicrodata extracts schema.org Recipe microdata
 func extractMicrodata(doc *goquery.Document, recipe *ExtractedRecipe) {
 	// Look for elements with itemtype="http://schema.org/Recipe"
@@ -321,6 +200,18 @@ func extractMicrodata(doc *goquery.Document, recipe *ExtractedRecipe) {
 				}
 			}
 		})
+
+		// Extract nutrition, which is its own NutritionInformation item
+		if recipe.Nutrition == nil {
+			nutrition := s.Find("[itemprop='nutrition']").First()
+			recipe.Nutrition = NewNutrition(func(property string) any {
+				prop := nutrition.Find("[itemprop='" + property + "']").First()
+				if content, ok := prop.Attr("content"); ok {
+					return content
+				}
+				return prop.Text()
+			})
+		}
 	})
 }
 
@@ -335,8 +226,8 @@ func extractIngredientsByPatterns(doc *goquery.Document, recipe *ExtractedRecipe
 	}
 
 	for _, selector := range selectors {
-		// Try to find container
-		doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
+		// Try to find container, stopping at the first one with items
+		doc.Find(selector).EachWithBreak(func(_ int, s *goquery.Selection) bool {
 			var items []string
 
 			// Try to find list items within the container
@@ -350,7 +241,7 @@ func extractIngredientsByPatterns(doc *goquery.Document, recipe *ExtractedRecipe
 			// If list items found, use them
 			if len(items) > 0 {
 				recipe.Ingredients = items
-				return
+				return false
 			}
 
 			// Otherwise, look for separate elements like divs or spans
@@ -364,7 +255,7 @@ func extractIngredientsByPatterns(doc *goquery.Document, recipe *ExtractedRecipe
 			// If items found in divs or spans, use them
 			if len(items) > 0 {
 				recipe.Ingredients = items
-				return
+				return false
 			}
 
 			// Last resort: use the text content of the container itself
@@ -377,6 +268,7 @@ func extractIngredientsByPatterns(doc *goquery.Document, recipe *ExtractedRecipe
 					}
 				}
 			}
+			return len(recipe.Ingredients) == 0
 		})
 
 		// If ingredients were found, break the loop
@@ -398,8 +290,8 @@ func extractInstructionsByPatterns(doc *goquery.Document, recipe *ExtractedRecip
 	}
 
 	for _, selector := range selectors {
-		// Try to find container
-		doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
+		// Try to find container, stopping at the first one with items
+		doc.Find(selector).EachWithBreak(func(_ int, s *goquery.Selection) bool {
 			var items []string
 
 			// Try to find list items within the container
@@ -413,7 +305,7 @@ func extractInstructionsByPatterns(doc *goquery.Document, recipe *ExtractedRecip
 			// If list items found, use them
 			if len(items) > 0 {
 				recipe.Instructions = items
-				return
+				return false
 			}
 
 			// Look for step headings or numbered paragraphs
@@ -428,7 +320,7 @@ func extractInstructionsByPatterns(doc *goquery.Document, recipe *ExtractedRecip
 			// If step headings found, use them
 			if len(items) > 0 {
 				recipe.Instructions = items
-				return
+				return false
 			}
 
 			// Look for div or p elements that might contain steps
@@ -442,7 +334,7 @@ func extractInstructionsByPatterns(doc *goquery.Document, recipe *ExtractedRecip
 			// If items found in divs or paragraphs, use them
 			if len(items) > 0 {
 				recipe.Instructions = items
-				return
+				return false
 			}
 
 			// Last resort: use the text content of the container itself
@@ -456,6 +348,7 @@ func extractInstructionsByPatterns(doc *goquery.Document, recipe *ExtractedRecip
 					}
 				}
 			}
+			return len(recipe.Instructions) == 0
 		})
 
 		// If instructions were found, break the loop
@@ -0,0 +1,73 @@
+package parsing
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ImageCandidates lists the photos a page offers for its recipe, best first,
+// so the next can be tried when one fails to download. The recipe's own
+// images come before the ones the page shares on social media.
+func ImageCandidates(pageURL string, htmlContent []byte) []string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(htmlContent))
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	if site, ok := findSiteExtractor(pageURL, doc); ok {
+		if recipe, err := site.Extract(doc); err == nil {
+			candidates = append(candidates, recipe.ImageURL)
+		}
+	}
+	if jsonLD := extractJSONLD(doc); jsonLD != nil {
+		candidates = append(candidates, jsonLDImages(jsonLD.Image)...)
+	}
+	for _, selector := range []string{
+		`meta[property="og:image"]`,
+		`meta[property="og:image:url"]`,
+		`meta[name="twitter:image"]`,
+		`meta[name="image"]`,
+		`meta[itemprop="image"]`,
+	} {
+		if content, ok := doc.Find(selector).First().Attr("content"); ok {
+			candidates = append(candidates, content)
+		}
+	}
+	if href, ok := doc.Find(`link[rel="image_src"]`).First().Attr("href"); ok {
+		candidates = append(candidates, href)
+	}
+	return resolveImageURLs(pageURL, candidates)
+}
+
+// resolveImageURLs makes relative URLs absolute and drops empty, repeated
+// and inline images
+func resolveImageURLs(pageURL string, candidates []string) []string {
+	base, _ := url.Parse(pageURL)
+	seen := map[string]bool{}
+	var resolved []string
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" {
+			continue
+		}
+		u, err := url.Parse(candidate)
+		if err != nil {
+			continue
+		}
+		if base != nil {
+			u = base.ResolveReference(u)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			continue
+		}
+		if s := u.String(); !seen[s] {
+			seen[s] = true
+			resolved = append(resolved, s)
+		}
+	}
+	return resolved
+}
@@ -0,0 +1,45 @@
+package parsing_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+func TestImageCandidates(t *testing.T) {
+	t.Run("lists the recipe's images before the page's", func(t *testing.T) {
+		page := `<html><head>
+			<meta property="og:image" content="/social.jpg">
+			<meta name="twitter:image" content="https://cdn.example.com/card.jpg">
+			<link rel="image_src" href="data:image/png;base64,AAAA">
+			<script type="application/ld+json">{"@type":"Recipe","name":"Toast",
+				"image":["https://cdn.example.com/toast-16x9.jpg",{"@type":"ImageObject","url":"https://cdn.example.com/toast-1x1.jpg"}]}</script>
+		</head><body></body></html>`
+
+		candidates := parsing.ImageCandidates("https://example.com/recipes/toast", []byte(page))
+		is.Equal(t, strings.Join([]string{
+			"https://cdn.example.com/toast-16x9.jpg",
+			"https://cdn.example.com/toast-1x1.jpg",
+			"https://example.com/social.jpg",
+			"https://cdn.example.com/card.jpg",
+		}, "\n"), strings.Join(candidates, "\n"))
+	})
+
+	t.Run("uses the site extractor's image first", func(t *testing.T) {
+		page, err := os.ReadFile(filepath.Join("testdata", "sites", "food.com.html"))
+		is.NotError(t, err)
+
+		candidates := parsing.ImageCandidates("https://www.food.com/recipe/bow-ties-86903", page)
+		is.True(t, len(candidates) > 0)
+		is.Equal(t, "https://img.sndimg.com/food/image/upload/q_92,fl_progressive,w_1200,c_scale/v1/img/recipes/86/90/3/picmJ7Gxr.jpg", candidates[0])
+	})
+
+	t.Run("no images", func(t *testing.T) {
+		is.Equal(t, 0, len(parsing.ImageCandidates("https://example.com", []byte(plainPage))))
+	})
+}
@@ -0,0 +1,289 @@
+package parsing
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// unitAliases maps the spellings found on recipe sites to the units we store.
+// The canonical names match what the LLM prompt asks for, so data from both
+// paths can be combined on a shopping list.
+var unitAliases = map[string]string{
+	"c": "cup", "cup": "cup", "cups": "cup",
+	"tbsp": "tbsp", "tbsps": "tbsp", "tbs": "tbsp", "tbl": "tbsp", "tbls": "tbsp", "T": "tbsp",
+	"tablespoon": "tbsp", "tablespoons": "tbsp",
+	"tsp": "tsp", "tsps": "tsp", "t": "tsp", "teaspoon": "tsp", "teaspoons": "tsp",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"fl oz": "fl oz", "fl. oz": "fl oz", "fluid ounce": "fl oz", "fluid ounces": "fl oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+	"g": "g", "gr": "g", "gram": "g", "grams": "g", "gramme": "g", "grammes": "g",
+	"kg": "kg", "kgs": "kg", "kilogram": "kg", "kilograms": "kg",
+	"mg": "mg", "milligram": "mg", "milligrams": "mg",
+	"ml": "ml", "mls": "ml", "milliliter": "ml", "milliliters": "ml", "millilitre": "ml", "millilitres": "ml",
+	"cl": "cl", "centiliter": "cl", "centiliters": "cl",
+	"dl": "dl", "deciliter": "dl", "deciliters": "dl",
+	"l": "l", "liter": "l", "liters": "l", "litre": "l", "litres": "l",
+	"pt": "pint", "pint": "pint", "pints": "pint",
+	"qt": "quart", "quart": "quart", "quarts": "quart",
+	"gal": "gallon", "gallon": "gallon", "gallons": "gallon",
+	"clove": "clove", "cloves": "clove",
+	"can": "can", "cans": "can", "tin": "can", "tins": "can",
+	"jar": "jar", "jars": "jar",
+	"package": "package", "packages": "package", "pkg": "package", "packet": "package", "packets": "package",
+	"stick": "stick", "sticks": "stick",
+	"pinch": "pinch", "pinches": "pinch",
+	"dash": "dash", "dashes": "dash",
+	"slice": "slice", "slices": "slice",
+	"bunch": "bunch", "bunches": "bunch",
+	"sprig": "sprig", "sprigs": "sprig",
+	"head": "head", "heads": "head",
+	"stalk": "stalk", "stalks": "stalk",
+	"handful": "handful", "handfuls": "handful",
+}
+
+// sizeWords describe the size of a unit rather than the ingredient, as in
+// "2 large cloves garlic", and are dropped when they come before a unit.
+var sizeWords = map[string]bool{
+	"small": true, "medium": true, "large": true, "heaping": true, "heaped": true,
+	"level": true, "scant": true, "generous": true, "big": true,
+}
+
+var numberWords = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10, "twelve": 12,
+	"half": 0.5, "dozen": 12,
+}
+
+var unicodeFractions = map[rune]string{
+	'½': "1/2", '⅓': "1/3", '⅔': "2/3", '¼': "1/4", '¾': "3/4",
+	'⅕': "1/5", '⅖': "2/5", '⅗': "3/5", '⅘': "4/5", '⅙': "1/6",
+	'⅚': "5/6", '⅛': "1/8", '⅜': "3/8", '⅝': "5/8", '⅞': "7/8",
+}
+
+// irregularPlurals covers the plurals the suffix rules in singularize get wrong
+var irregularPlurals = map[string]string{
+	"leaves": "leaf", "halves": "half", "loaves": "loaf", "cookies": "cookie",
+	"chives": "chive", "olives": "olive", "cloves": "clove",
+}
+
+// singularInvariant are names that end in "s" but are not plurals
+var singularInvariant = map[string]bool{
+	"asparagus": true, "couscous": true, "hummus": true, "molasses": true,
+	"grits": true, "greens": true, "oats": true, "swiss": true, "lemongrass": true,
+	"bitters": true, "citrus": true, "hibiscus": true, "octopus": true, "series": true,
+	"brussels": true, "schnapps": true, "watercress": true, "bass": true, "anise": true,
+}
+
+var (
+	parentheticalRe = regexp.MustCompile(`\(([^)]*)\)`)
+	spaceRe         = regexp.MustCompile(`\s+`)
+	numberPattern   = `(?:\d+\s+\d+/\d+|\d+/\d+|\d*\.\d+|\d+)`
+	amountRe        = regexp.MustCompile(`^(` + numberPattern + `)(?:\s*(?:-|to|or)\s*(` + numberPattern + `))?`)
+)
+
+// ParseIngredient turns a raw ingredient line such as
+// "1 ½ cups (190g) all-purpose flour, sifted" into a structured Ingredient.
+// Ranges use the upper amount so a shopping list never comes up short, and
+// parenthetical amounts or remarks are kept in the notes.
+func ParseIngredient(line string) Ingredient {
+	line = normalizeIngredientLine(line)
+
+	var notes []string
+	for _, match := range parentheticalRe.FindAllStringSubmatch(line, -1) {
+		if note := strings.TrimSpace(match[1]); note != "" {
+			notes = append(notes, note)
+		}
+	}
+	line = spaceRe.ReplaceAllString(parentheticalRe.ReplaceAllString(line, " "), " ")
+	line = strings.TrimSpace(line)
+
+	var ingredient Ingredient
+	amount, rest := parseAmount(line)
+	ingredient.Amount = amount
+
+	unit, rest := parseUnit(rest)
+	ingredient.Unit = unit
+	if unit != "" && amount == nil {
+		// "pinch of salt" still means one pinch
+		one := 1.0
+		ingredient.Amount = &one
+	}
+
+	rest = strings.TrimPrefix(rest, "of ")
+	name, note, _ := strings.Cut(rest, ",")
+	if note = strings.TrimSpace(note); note != "" {
+		notes = append(notes, note)
+	}
+	name, note = splitTrailingNote(strings.TrimSpace(name))
+	if note != "" {
+		notes = append(notes, note)
+	}
+
+	ingredient.Name = singularize(strings.ToLower(name))
+	ingredient.Notes = strings.Join(notes, ", ")
+	ingredient.Category = string(Categorize(ingredient.Name))
+	return ingredient
+}
+
+// FormatIngredient writes an ingredient back out as a line like "1 ½ cup flour, sifted"
+func FormatIngredient(ingredient Ingredient) string {
+	var parts []string
+	if ingredient.Amount != nil {
+		parts = append(parts, FormatAmount(*ingredient.Amount, ingredient.Unit))
+	}
+	if ingredient.Unit != "" {
+		parts = append(parts, ingredient.Unit)
+	}
+	parts = append(parts, ingredient.Name)
+	line := strings.Join(parts, " ")
+	if ingredient.Notes != "" {
+		line += ", " + ingredient.Notes
+	}
+	return line
+}
+
+// normalizeIngredientLine rewrites unicode fractions and dashes so the amount
+// pattern only has to deal with ASCII.
+func normalizeIngredientLine(line string) string {
+	var b strings.Builder
+	for _, r := range line {
+		if frac, ok := unicodeFractions[r]; ok {
+			b.WriteString(" " + frac)
+			continue
+		}
+		switch r {
+		case '–', '—', '‑':
+			b.WriteRune('-')
+		case '⁄':
+			b.WriteRune('/')
+		case '\u00a0':
+			b.WriteRune(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	line = spaceRe.ReplaceAllString(b.String(), " ")
+	line = strings.TrimSpace(line)
+	// Strip list markers left over from plain text
+	line = strings.TrimLeft(line, "-•*▢□☐ ")
+	return line
+}
+
+// parseAmount reads a leading quantity, returning nil when there is none
+func parseAmount(line string) (*float64, string) {
+	if match := amountRe.FindStringSubmatchIndex(line); match != nil {
+		value, ok := parseNumber(line[match[2]:match[3]])
+		if ok && match[4] >= 0 {
+			if upper, ok := parseNumber(line[match[4]:match[5]]); ok {
+				value = upper
+			}
+		}
+		if ok {
+			return &value, strings.TrimSpace(line[match[1]:])
+		}
+	}
+
+	first, rest, _ := strings.Cut(line, " ")
+	if value, ok := numberWords[strings.ToLower(first)]; ok && rest != "" {
+		return &value, strings.TrimSpace(rest)
+	}
+	return nil, line
+}
+
+// parseNumber parses whole numbers, decimals, fractions and mixed fractions
+func parseNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	var total float64
+	for _, part := range strings.Fields(s) {
+		if num, den, ok := strings.Cut(part, "/"); ok {
+			n, err1 := strconv.ParseFloat(num, 64)
+			d, err2 := strconv.ParseFloat(den, 64)
+			if err1 != nil || err2 != nil || d == 0 {
+				return 0, false
+			}
+			total += n / d
+			continue
+		}
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, false
+		}
+		total += v
+	}
+	return total, true
+}
+
+// parseUnit reads a leading unit, skipping a size word in front of it
+func parseUnit(line string) (string, string) {
+	words := strings.Fields(line)
+	start := 0
+	if len(words) > 1 && sizeWords[strings.ToLower(words[0])] {
+		start = 1
+	}
+	for _, n := range []int{2, 1} {
+		if start+n > len(words) {
+			continue
+		}
+		candidate := strings.TrimSuffix(strings.Join(words[start:start+n], " "), ".")
+		unit, ok := unitAliases[candidate]
+		if !ok {
+			unit, ok = unitAliases[strings.ToLower(candidate)]
+		}
+		if !ok {
+			continue
+		}
+		// A single letter is only a unit when something follows it
+		if len(candidate) == 1 && start+n == len(words) {
+			continue
+		}
+		return unit, strings.Join(words[start+n:], " ")
+	}
+	return "", line
+}
+
+// splitTrailingNote moves phrases like "to taste" out of the name
+func splitTrailingNote(name string) (string, string) {
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{"to taste", "for garnish", "for serving", "optional", "divided"} {
+		if strings.HasSuffix(lower, suffix) {
+			trimmed := strings.TrimSpace(strings.TrimSuffix(name[:len(name)-len(suffix)], " "))
+			trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, "-"))
+			return trimmed, suffix
+		}
+	}
+	return name, ""
+}
+
+// singularize makes the last word of a name singular, since the shopping list
+// should not care whether a recipe said "tomato" or "tomatoes"
+func singularize(name string) string {
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return name
+	}
+	last := words[len(words)-1]
+	if singular, ok := irregularPlurals[last]; ok {
+		words[len(words)-1] = singular
+		return strings.Join(words, " ")
+	}
+	if singularInvariant[last] || len(last) < 4 {
+		return name
+	}
+	switch {
+	case strings.HasSuffix(last, "ies"):
+		last = strings.TrimSuffix(last, "ies") + "y"
+	case strings.HasSuffix(last, "oes"),
+		strings.HasSuffix(last, "ches"),
+		strings.HasSuffix(last, "shes"),
+		strings.HasSuffix(last, "sses"),
+		strings.HasSuffix(last, "xes"):
+		last = strings.TrimSuffix(last, "es")
+	case strings.HasSuffix(last, "ss"), strings.HasSuffix(last, "us"), strings.HasSuffix(last, "is"):
+		// Not a plural
+	case strings.HasSuffix(last, "s"):
+		last = strings.TrimSuffix(last, "s")
+	}
+	words[len(words)-1] = last
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,52 @@
+package parsing_test
+
+import (
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+func TestParseIngredient(t *testing.T) {
+	tests := []struct {
+		line   string
+		amount float64 // 0 means no amount
+		unit   string
+		name   string
+		notes  string
+	}{
+		{"1 ½ cups (190g) all-purpose flour, sifted", 1.5, "cup", "all-purpose flour", "190g, sifted"},
+		{"2-3 large cloves garlic, minced", 3, "clove", "garlic", "minced"},
+		{"1½ tsp baking soda", 1.5, "tsp", "baking soda", ""},
+		{"1 1/2 Tablespoons olive oil", 1.5, "tbsp", "olive oil", ""},
+		{"¾ cup sugar", 0.75, "cup", "sugar", ""},
+		{"0.5 kg potatoes", 0.5, "kg", "potato", ""},
+		{"2 large eggs", 2, "", "large egg", ""},
+		{"1 (14.5 oz) can diced tomatoes", 1, "can", "diced tomato", "14.5 oz"},
+		{"4 to 6 sprigs fresh thyme", 6, "sprig", "fresh thyme", ""},
+		{"2 – 3 bay leaves", 3, "", "bay leaf", ""},
+		{"a pinch of salt", 1, "pinch", "salt", ""},
+		{"Salt and pepper to taste", 0, "", "salt and pepper", "to taste"},
+		{"3 fl oz heavy cream", 3, "fl oz", "heavy cream", ""},
+		{"1 T. butter", 1, "tbsp", "butter", ""},
+		{"▢ 200 g dark chocolate (70%), chopped", 200, "g", "dark chocolate", "70%, chopped"},
+		{"1 cup frozen peas", 1, "cup", "frozen pea", ""},
+		{"2 cups asparagus", 2, "cup", "asparagus", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			ingredient := parsing.ParseIngredient(test.line)
+			if test.amount == 0 {
+				is.Nil(t, ingredient.Amount)
+			} else {
+				is.NotNil(t, ingredient.Amount)
+				is.Equal(t, test.amount, *ingredient.Amount)
+			}
+			is.Equal(t, test.unit, ingredient.Unit)
+			is.Equal(t, test.name, ingredient.Name)
+			is.Equal(t, test.notes, ingredient.Notes)
+		})
+	}
+}
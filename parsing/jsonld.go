@@ -0,0 +1,346 @@
+package parsing
+
+import (
+	"encoding/json"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// JSONLDRecipe represents the JSON-LD schema.org/Recipe structure. Most
+// fields can take several shapes, so they are decoded as any.
+type JSONLDRecipe struct {
+	Context           any    `json:"@context"`
+	Type              any    `json:"@type"` // "Recipe" or a list including it
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	Image             any    `json:"image"` // URL, ImageObject, or a list of either
+	Video             any    `json:"video"`
+	Author            any    `json:"author"`
+	PrepTime          string `json:"prepTime"`
+	CookTime          string `json:"cookTime"`
+	TotalTime         string `json:"totalTime"`
+	RecipeYield       any    `json:"recipeYield"`
+	Ingredients       any    `json:"recipeIngredient"`
+	LegacyIngredients any    `json:"ingredients"`        // Used before recipeIngredient existed
+	Instructions      any    `json:"recipeInstructions"` // Text, HowToSteps or HowToSections
+	Nutrition         any    `json:"nutrition"`          // NutritionInformation
+}
+
+// InstructionSection is a named group of steps, such as "For the frosting"
+type InstructionSection struct {
+	Name  string `json:"name"`
+	Steps []Step `json:"steps"`
+}
+
+// Step is one instruction with the media the page attached to it
+type Step struct {
+	Text  string `json:"text"`
+	Image string `json:"image,omitempty"`
+	Video string `json:"video,omitempty"`
+}
+
+// extractJSONLD finds the first Recipe in the page's JSON-LD scripts. Recipes
+// can be at the top level, in a list, in an @graph, or the mainEntity of a page.
+func extractJSONLD(doc *goquery.Document) *JSONLDRecipe {
+	var recipe *JSONLDRecipe
+	doc.Find("script").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		scriptType, _ := s.Attr("type")
+		if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(scriptType)), "application/ld+json") {
+			return true
+		}
+		var data any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(s.Text())), &data); err != nil {
+			return true
+		}
+		node := findRecipeNode(data)
+		if node == nil {
+			return true
+		}
+		// Round trip through JSON to fill the struct from the generic map
+		jsonBytes, err := json.Marshal(node)
+		if err != nil {
+			return true
+		}
+		var r JSONLDRecipe
+		if err := json.Unmarshal(jsonBytes, &r); err != nil {
+			return true
+		}
+		recipe = &r
+		return false
+	})
+	return recipe
+}
+
+// findRecipeNode searches decoded JSON-LD for an object typed as a Recipe
+func findRecipeNode(data any) map[string]any {
+	switch v := data.(type) {
+	case []any:
+		for _, item := range v {
+			if node := findRecipeNode(item); node != nil {
+				return node
+			}
+		}
+	case map[string]any:
+		if hasType(v, "Recipe") {
+			return v
+		}
+		for _, key := range []string{"@graph", "mainEntity", "mainEntityOfPage", "itemListElement", "item"} {
+			if node := findRecipeNode(v[key]); node != nil {
+				return node
+			}
+		}
+	}
+	return nil
+}
+
+// hasType tells if a JSON-LD node's @type is or includes the given type,
+// with or without a schema.org prefix
+func hasType(node map[string]any, want string) bool {
+	matches := func(t any) bool {
+		s, ok := t.(string)
+		if !ok {
+			return false
+		}
+		s = strings.TrimPrefix(strings.TrimPrefix(s, "schema:"), "http://schema.org/")
+		return strings.TrimPrefix(s, "https://schema.org/") == want
+	}
+	switch t := node["@type"].(type) {
+	case []any:
+		for _, item := range t {
+			if matches(item) {
+				return true
+			}
+		}
+		return false
+	default:
+		return matches(t)
+	}
+}
+
+// mapJSONLDToRecipe maps JSON-LD data to our Recipe struct
+func mapJSONLDToRecipe(jsonLD *JSONLDRecipe, recipe *ExtractedRecipe) {
+	recipe.Title = jsonLDText(jsonLD.Name)
+	recipe.Description = jsonLDText(jsonLD.Description)
+	recipe.PrepTime = jsonLD.PrepTime
+	recipe.CookTime = jsonLD.CookTime
+	recipe.TotalTime = jsonLD.TotalTime
+	recipe.ImageURL = jsonLDImage(jsonLD.Image)
+	recipe.VideoURL = jsonLDVideo(jsonLD.Video)
+	recipe.Author = jsonLDName(jsonLD.Author)
+	recipe.Yield = jsonLDYield(jsonLD.RecipeYield)
+	if nutrition, ok := jsonLD.Nutrition.(map[string]any); ok {
+		recipe.Nutrition = NewNutrition(func(property string) any { return nutrition[property] })
+	}
+
+	ingredients := jsonLD.Ingredients
+	if ingredients == nil {
+		ingredients = jsonLD.LegacyIngredients
+	}
+	recipe.Ingredients = append(recipe.Ingredients, jsonLDLines(ingredients)...)
+
+	recipe.Sections = jsonLDSections(jsonLD.Instructions)
+	for _, section := range recipe.Sections {
+		for _, step := range section.Steps {
+			recipe.Instructions = append(recipe.Instructions, step.Text)
+		}
+	}
+}
+
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+
+// jsonLDText undoes the HTML escaping and markup many sites leave in their JSON-LD strings
+func jsonLDText(s string) string {
+	return cleanText(html.UnescapeString(tagRe.ReplaceAllString(s, " ")))
+}
+
+// jsonLDLines reads a list of strings, or one string with a line per item
+func jsonLDLines(value any) []string {
+	var lines []string
+	switch v := value.(type) {
+	case string:
+		for _, line := range strings.Split(v, "\n") {
+			if text := jsonLDText(line); text != "" {
+				lines = append(lines, text)
+			}
+		}
+	case []any:
+		for _, item := range v {
+			lines = append(lines, jsonLDLines(item)...)
+		}
+	}
+	return lines
+}
+
+// jsonLDSections reads recipeInstructions in any of its shapes. Steps outside
+// a HowToSection are collected in a section with no name.
+func jsonLDSections(value any) []InstructionSection {
+	var sections []InstructionSection
+	var loose []Step
+	flushLoose := func() {
+		if len(loose) > 0 {
+			sections = append(sections, InstructionSection{Steps: loose})
+			loose = nil
+		}
+	}
+
+	var items []any
+	switch v := value.(type) {
+	case []any:
+		items = v
+	case nil:
+	default:
+		items = []any{v}
+	}
+	for _, item := range items {
+		if node, ok := item.(map[string]any); ok && hasType(node, "HowToSection") {
+			flushLoose()
+			name, _ := node["name"].(string)
+			sections = append(sections, InstructionSection{
+				Name:  jsonLDText(name),
+				Steps: jsonLDSteps(node["itemListElement"]),
+			})
+			continue
+		}
+		loose = append(loose, jsonLDSteps(item)...)
+	}
+	flushLoose()
+	return sections
+}
+
+// jsonLDSteps reads a HowToStep, text, or a list of them
+func jsonLDSteps(value any) []Step {
+	var steps []Step
+	switch v := value.(type) {
+	case string:
+		for _, line := range jsonLDLines(v) {
+			steps = append(steps, Step{Text: line})
+		}
+	case []any:
+		for _, item := range v {
+			steps = append(steps, jsonLDSteps(item)...)
+		}
+	case map[string]any:
+		// A step can itself list directions and tips
+		if children, ok := v["itemListElement"]; ok && v["text"] == nil {
+			return jsonLDSteps(children)
+		}
+		text, _ := v["text"].(string)
+		if text == "" {
+			text, _ = v["name"].(string)
+		}
+		if text = jsonLDText(text); text == "" {
+			return nil
+		}
+		steps = append(steps, Step{
+			Text:  text,
+			Image: jsonLDImage(v["image"]),
+			Video: jsonLDVideo(v["video"]),
+		})
+	}
+	return steps
+}
+
+// jsonLDImage finds the first image URL in any of the shapes schema.org allows:
+// a string, an ImageObject, or a list of either
+func jsonLDImage(image any) string {
+	if images := jsonLDImages(image); len(images) > 0 {
+		return images[0]
+	}
+	return ""
+}
+
+// jsonLDImages lists every image URL, in the order the page gave them
+func jsonLDImages(image any) []string {
+	switch img := image.(type) {
+	case string:
+		return []string{img}
+	case map[string]any:
+		if url, ok := img["url"].(string); ok {
+			return []string{url}
+		}
+		if url, ok := img["contentUrl"].(string); ok {
+			return []string{url}
+		}
+	case []any:
+		var images []string
+		for _, item := range img {
+			images = append(images, jsonLDImages(item)...)
+		}
+		return images
+	}
+	return nil
+}
+
+// jsonLDVideo finds a playable URL for a VideoObject, preferring the file over an embed
+func jsonLDVideo(video any) string {
+	switch v := video.(type) {
+	case string:
+		return WebURL(v)
+	case map[string]any:
+		for _, key := range []string{"contentUrl", "embedUrl", "url"} {
+			if link, ok := v[key].(string); ok && WebURL(link) != "" {
+				return WebURL(link)
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if link := jsonLDVideo(item); link != "" {
+				return link
+			}
+		}
+	}
+	return ""
+}
+
+// WebURL gives raw back when it is an absolute http or https URL and "" when
+// it is anything else, such as a javascript: URL a page put in its recipe data
+func WebURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
+
+// jsonLDName reads a name from a string, a Person or Organization, or a list of them
+func jsonLDName(value any) string {
+	switch v := value.(type) {
+	case string:
+		return jsonLDText(v)
+	case map[string]any:
+		name, _ := v["name"].(string)
+		return jsonLDText(name)
+	case []any:
+		for _, item := range v {
+			if name := jsonLDName(item); name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// jsonLDYield reads a yield given as text, a number, or a list of both
+// such as ["4", "4 servings"], preferring the most descriptive
+func jsonLDYield(value any) string {
+	switch v := value.(type) {
+	case string:
+		return jsonLDText(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []any:
+		var best string
+		for _, item := range v {
+			if yield := jsonLDYield(item); len(yield) > len(best) {
+				best = yield
+			}
+		}
+		return best
+	}
+	return ""
+}
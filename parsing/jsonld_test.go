@@ -0,0 +1,91 @@
+package parsing_test
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected outputs in testdata")
+
+func TestParseRecipe_jsonLDCorpus(t *testing.T) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "jsonld", "*.html"))
+	is.NotError(t, err)
+	is.True(t, len(pages) > 0)
+
+	for _, page := range pages {
+		name := strings.TrimSuffix(filepath.Base(page), ".html")
+		t.Run(name, func(t *testing.T) {
+			html, err := os.ReadFile(page)
+			is.NotError(t, err)
+
+			recipe, err := parsing.ParseRecipe("", html)
+			is.NotError(t, err)
+			is.Equal(t, parsing.SourceJSONLD, recipe.Source)
+
+			got, err := json.MarshalIndent(recipe, "", "  ")
+			is.NotError(t, err)
+			got = append(got, '\n')
+
+			golden := strings.TrimSuffix(page, ".html") + ".json"
+			if *update {
+				is.NotError(t, os.WriteFile(golden, got, 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			is.NotError(t, err)
+			is.Equal(t, string(want), string(got))
+		})
+	}
+}
+
+func TestExtractedRecipe_ToCollection_sections(t *testing.T) {
+	html, err := os.ReadFile(filepath.Join("testdata", "jsonld", "how-to-sections.html"))
+	is.NotError(t, err)
+	recipe, err := parsing.ParseRecipe("", html)
+	is.NotError(t, err)
+
+	collection := recipe.ToCollection()
+	is.Equal(t, 3, len(collection.Recipes))
+
+	main := collection.Recipes[0]
+	is.Equal(t, "Carrot Cake with Cream Cheese Frosting", main.Name)
+	is.Equal(t, 6, len(main.Ingredients))
+	is.Equal(t, "Preheat the oven to 350°F and grease two 9-inch cake pans.", strings.Join(main.Instructions, "\n"))
+
+	is.Equal(t, "Cake", collection.Recipes[1].Name)
+	is.Equal(t, 2, len(collection.Recipes[1].Instructions))
+	is.Equal(t, "Cream Cheese Frosting", collection.Recipes[2].Name)
+	is.Equal(t, 0, len(collection.Recipes[2].Ingredients))
+
+	t.Run("keeps step media with the step", func(t *testing.T) {
+		html, err := os.ReadFile(filepath.Join("testdata", "jsonld", "step-media.html"))
+		is.NotError(t, err)
+		recipe, err := parsing.ParseRecipe("", html)
+		is.NotError(t, err)
+
+		collection := recipe.ToCollection()
+		is.Equal(t, 1, len(collection.Recipes))
+		is.Equal(t, "https://videos.seriouseats.example/pizza-dough.mp4", collection.Recipes[0].Video)
+		media := collection.Recipes[0].StepMedia
+		is.Equal(t, 3, len(media))
+		is.Equal(t, 0, media[0].Step)
+		is.Equal(t, "https://www.seriouseats.example/thmb/step-1.jpg", media[0].Image)
+		is.Equal(t, 1, media[1].Step)
+		is.Equal(t, "https://www.youtube.example/embed/abc123?start=42", media[1].Video)
+		is.Equal(t, 2, media[2].Step)
+	})
+
+	t.Run("ignores video URLs that aren't web pages", func(t *testing.T) {
+		page := `<script type="application/ld+json">{"@type":"Recipe","name":"Toast","recipeIngredient":["1 slice bread"],"recipeInstructions":["Toast it."],"video":{"@type":"VideoObject","contentUrl":"javascript:alert(1)","embedUrl":"https://video.example/embed/1"}}</script>`
+		recipe, err := parsing.ParseRecipe("", []byte(page))
+		is.NotError(t, err)
+		is.Equal(t, "https://video.example/embed/1", recipe.ToCollection().Recipes[0].Video)
+	})
+}
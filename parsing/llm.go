@@ -2,12 +2,8 @@ package parsing
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"os"
-	"strconv"
-
-	"github.com/liushuangls/go-anthropic/v2"
+	"log/slog"
 )
 
 type RecipeCollection struct {
@@ -26,6 +22,17 @@ type Recipe struct {
 	Instructions []string     `json:"instructions,omitempty"`
 	Notes        []string     `json:"notes,omitempty"`
 	Tags         []string     `json:"tags,omitempty"`
+	Image        string       `json:"image,omitempty"` // Photo found on the page, when there was one
+	Video        string       `json:"video,omitempty"`
+	StepMedia    []StepMedia  `json:"step_media,omitempty"`
+	Nutrition    *Nutrition   `json:"nutrition,omitempty"` // Per serving
+}
+
+// StepMedia is a photo or video the page showed with one of the instructions
+type StepMedia struct {
+	Step  int    `json:"step"` // Index into Instructions
+	Image string `json:"image,omitempty"`
+	Video string `json:"video,omitempty"`
 }
 
 // Ingredient represents a single ingredient with its details
@@ -37,8 +44,40 @@ type Ingredient struct {
 	Category string   `json:"category,omitempty"`
 }
 
-// RecipeTextToJSON converts recipe text to a JSON structure using Claude API
-func RecipeTextToJsonString(text []byte) string {
+// RecipeTextToCollection converts recipe text to a RecipeCollection using the
+// configured LLM backend. The reply is validated against recipeschema.json and
+// the LLM gets a bounded number of chances to fix output that does not pass.
+func RecipeTextToCollection(ctx context.Context, extractor RecipeExtractor, text []byte) (*RecipeCollection, error) {
+	if extractor == nil {
+		return nil, ErrNoExtractor
+	}
+
+	original := recipePrompt(text)
+	prompt := original
+	for attempt := 0; ; attempt++ {
+		completion, err := extractor.Complete(ctx, prompt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", extractor.Model(), err)
+		}
+
+		collection, err := ValidateRecipeJSON([]byte(CleanLLMOutput(completion.Text)))
+		if err == nil {
+			return collection, nil
+		}
+		if attempt == maxRepairAttempts {
+			return nil, fmt.Errorf("%s gave up after %d attempts: %w", extractor.Model(), attempt+1, err)
+		}
+		slog.Info("LLM output failed validation, asking again", "model", extractor.Model(), "attempt", attempt+1, "error", err)
+		prompt = repairPrompt(original, completion.Text, err)
+	}
+}
+
+// PromptVersion goes up with every change to recipePrompt or repairPrompt, so
+// recipes the LLM read with an older prompt are re-extracted
+const PromptVersion = 1
+
+// recipePrompt asks for the recipe in text to be returned as JSON following our schema
+func recipePrompt(text []byte) string {
 	// Create a schema string based on our struct definitions
 	schemaPrompt := `
 {
@@ -86,7 +125,7 @@ func RecipeTextToJsonString(text []byte) string {
 }`
 
 	// Create the prompt with the schema and recipe text
-	prompt := fmt.Sprintf(`
+	return fmt.Sprintf(`
 Extract the recipe information from the following text and format it according to this schema:
 %s
 
@@ -106,44 +145,10 @@ Some recipes have multiple parts, like a dressing or sauce to go with it, that i
 Leave salt and pepper out, they are not needed to shop for most of the time.
 Specify paprika as either smoked or sweet. Clove is not a unit (garlic)
 `, schemaPrompt, string(text))
-
-	// Initialize the Claude client
-	client := anthropic.NewClient(os.Getenv("ANTHROPIC_KEY"))
-
-	// Make the API call to Claude
-	resp, err := client.CreateMessages(context.Background(), anthropic.MessagesRequest{
-		Model: anthropic.ModelClaude3Dot5HaikuLatest,
-		Messages: []anthropic.Message{
-			anthropic.NewUserTextMessage(prompt),
-		},
-		MaxTokens: 2000,
-	})
-
-	if err != nil {
-		var e *anthropic.APIError
-		if errors.As(err, &e) {
-			fmt.Printf("Messages error, type: %s, message: %s", e.Type, e.Message)
-		} else {
-			fmt.Printf("Messages error: %v\n", err)
-		}
-		return ""
-	}
-
-	// Get the generated text
-	generatedJSON := resp.Content[0].GetText()
-	return generatedJSON
-	// // Parse the generated JSON
-	// var collection RecipeCollection
-	// err = json.Unmarshal([]byte(generatedJSON), &collection)
-	// if err != nil {
-	// 	fmt.Printf("Error parsing generated JSON: %v\n", err)
-	// 	return nil
-	// }
-
-	// return &collection
 }
 
-func RecipeIngredients(collection *RecipeCollection) string {
+// RecipeIngredients lists the ingredients one per line in the given units
+func RecipeIngredients(collection *RecipeCollection, units UnitSystem) string {
 	if collection == nil {
 		return ""
 	}
@@ -151,9 +156,10 @@ func RecipeIngredients(collection *RecipeCollection) string {
 	var list string
 	for _, recipe := range collection.Recipes {
 		for _, ingredient := range recipe.Ingredients {
+			ingredient = ConvertIngredient(ingredient, units)
 			var amount string
 			if ingredient.Amount != nil {
-				amount = strconv.FormatFloat(*ingredient.Amount, 'f', -1, 64) + " "
+				amount = FormatAmount(*ingredient.Amount, ingredient.Unit) + " "
 			}
 			list = fmt.Sprintf("%s%s %v%s\n", list, ingredient.Name, amount, ingredient.Unit) //FIXME use buffer
 		}
@@ -0,0 +1,92 @@
+package parsing
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Nutrition is what one serving of a recipe contains, as the page gave it.
+// A nil field is one the page didn't list.
+type Nutrition struct {
+	ServingSize   string   `json:"serving_size,omitempty"`
+	Calories      *float64 `json:"calories,omitempty"`      // kcal
+	Protein       *float64 `json:"protein,omitempty"`       // g
+	Fat           *float64 `json:"fat,omitempty"`           // g
+	Carbohydrates *float64 `json:"carbohydrates,omitempty"` // g
+	Sodium        *float64 `json:"sodium,omitempty"`        // mg
+}
+
+// IsEmpty tells if none of the nutrition values are known
+func (n *Nutrition) IsEmpty() bool {
+	return n == nil || (n.ServingSize == "" && n.Calories == nil && n.Protein == nil &&
+		n.Fat == nil && n.Carbohydrates == nil && n.Sodium == nil)
+}
+
+// Times multiplies every value, as for the nutrition of several servings
+func (n Nutrition) Times(factor float64) Nutrition {
+	multiply := func(v *float64) *float64 {
+		if v == nil {
+			return nil
+		}
+		result := *v * factor
+		return &result
+	}
+	return Nutrition{
+		ServingSize:   n.ServingSize,
+		Calories:      multiply(n.Calories),
+		Protein:       multiply(n.Protein),
+		Fat:           multiply(n.Fat),
+		Carbohydrates: multiply(n.Carbohydrates),
+		Sodium:        multiply(n.Sodium),
+	}
+}
+
+// nutrientRe finds the amount and unit in values such as "240 kcal", "12.5g" or "1,200 mg"
+var nutrientRe = regexp.MustCompile(`(\d[\d,]*(?:\.\d+)?|\.\d+)\s*([a-zA-Z]*)`)
+
+// parseNutrient reads a nutrient amount in the given unit, "g" or "mg", converting
+// between the two. Calories are read with no unit.
+func parseNutrient(value any, unit string) *float64 {
+	var amount float64
+	switch v := value.(type) {
+	case float64:
+		amount = v
+	case string:
+		match := nutrientRe.FindStringSubmatch(v)
+		if match == nil {
+			return nil
+		}
+		var err error
+		if amount, err = strconv.ParseFloat(strings.ReplaceAll(match[1], ",", ""), 64); err != nil {
+			return nil
+		}
+		switch given := strings.ToLower(match[2]); {
+		case unit == "mg" && (given == "g" || given == "grams"):
+			amount *= 1000
+		case unit == "g" && (given == "mg" || given == "milligrams"):
+			amount /= 1000
+		}
+	default:
+		return nil
+	}
+	return &amount
+}
+
+// NewNutrition reads the schema.org NutritionInformation properties with get,
+// which returns a string or number for a property name
+func NewNutrition(get func(property string) any) *Nutrition {
+	servingSize, _ := get("servingSize").(string)
+	n := &Nutrition{
+		ServingSize:   cleanText(servingSize),
+		Calories:      parseNutrient(get("calories"), ""),
+		Protein:       parseNutrient(get("proteinContent"), "g"),
+		Fat:           parseNutrient(get("fatContent"), "g"),
+		Carbohydrates: parseNutrient(get("carbohydrateContent"), "g"),
+		Sodium:        parseNutrient(get("sodiumContent"), "mg"),
+	}
+	if n.IsEmpty() {
+		return nil
+	}
+	return n
+}
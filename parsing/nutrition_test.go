@@ -0,0 +1,56 @@
+package parsing_test
+
+import (
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+const microdataNutritionPage = `<html><body>
+<div itemscope itemtype="https://schema.org/Recipe">
+	<h1 itemprop="name">Lentil Soup</h1>
+	<ul>
+		<li itemprop="recipeIngredient">1 cup red lentils</li>
+		<li itemprop="recipeIngredient">4 cups vegetable broth</li>
+	</ul>
+	<ol itemprop="recipeInstructions"><li>Simmer the lentils in the broth for 20 minutes.</li></ol>
+	<div itemprop="nutrition" itemscope itemtype="https://schema.org/NutritionInformation">
+		<span itemprop="calories">310 calories</span>
+		<span itemprop="proteinContent">18 grams</span>
+		<meta itemprop="fatContent" content="2.5 g">
+		<span itemprop="sodiumContent">1,040 mg</span>
+	</div>
+</div>
+</body></html>`
+
+func TestParseRecipe_microdataNutrition(t *testing.T) {
+	recipe, err := parsing.ParseRecipe("", []byte(microdataNutritionPage))
+	is.NotError(t, err)
+	is.NotNil(t, recipe.Nutrition)
+	is.Equal(t, 310.0, *recipe.Nutrition.Calories)
+	is.Equal(t, 18.0, *recipe.Nutrition.Protein)
+	is.Equal(t, 2.5, *recipe.Nutrition.Fat)
+	is.Equal(t, 1040.0, *recipe.Nutrition.Sodium)
+	is.True(t, recipe.Nutrition.Carbohydrates == nil)
+}
+
+func TestNutrition_Times(t *testing.T) {
+	calories, fat := 200.0, 7.5
+	n := parsing.Nutrition{ServingSize: "1 cup", Calories: &calories, Fat: &fat}
+
+	total := n.Times(4)
+	is.Equal(t, 800.0, *total.Calories)
+	is.Equal(t, 30.0, *total.Fat)
+	is.True(t, total.Protein == nil)
+	is.Equal(t, "1 cup", total.ServingSize)
+	is.Equal(t, 200.0, *n.Calories)
+}
+
+func TestNutrition_IsEmpty(t *testing.T) {
+	var missing *parsing.Nutrition
+	is.True(t, missing.IsEmpty())
+	is.True(t, (&parsing.Nutrition{}).IsEmpty())
+	is.True(t, !(&parsing.Nutrition{ServingSize: "1 slice"}).IsEmpty())
+}
@@ -0,0 +1,117 @@
+package parsing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultOpenAIModel = "gpt-4o-mini"
+
+// openAIExtractor talks to any server implementing the OpenAI chat
+// completions API, which includes a local Ollama server.
+type openAIExtractor struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+func newOpenAIExtractor(cfg ExtractorConfig) *openAIExtractor {
+	model := cfg.Model
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	return &openAIExtractor{
+		// Local models can be slow, so be generous
+		client:  &http.Client{Timeout: 3 * time.Minute},
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		apiKey:  cfg.APIKey,
+		model:   model,
+	}
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	MaxTokens   int             `json:"max_tokens"`
+	Temperature float64         `json:"temperature"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (o *openAIExtractor) Complete(ctx context.Context, prompt string) (*Completion, error) {
+	body, err := json.Marshal(openAIRequest{
+		Model:     o.model,
+		Messages:  []openAIMessage{{Role: "user", Content: prompt}},
+		MaxTokens: 2000,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed openAIResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("status %d: could not decode response: %w", resp.StatusCode, err)
+	}
+	if parsed.Error != nil {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, parsed.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if len(parsed.Choices) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+
+	return &Completion{
+		Text:         parsed.Choices[0].Message.Content,
+		InputTokens:  parsed.Usage.PromptTokens,
+		OutputTokens: parsed.Usage.CompletionTokens,
+	}, nil
+}
+
+func (o *openAIExtractor) Model() string {
+	return BackendOpenAI + "/" + o.model
+}
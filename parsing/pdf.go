@@ -0,0 +1,191 @@
+package parsing
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// MaxPDFPages keeps a cookbook upload from tying up the server
+const MaxPDFPages = 200
+
+const (
+	// maxPDFStreamBytes is the most a page's text, or a font's character map,
+	// may unpack to. Real pages hold a small part of this.
+	maxPDFStreamBytes = 2 << 20
+	// maxPDFTextBytes is the most all the pages of a PDF may unpack to
+	maxPDFTextBytes = 32 << 20
+)
+
+// PDFPages reads the text of each page of a PDF, one line of text per line on
+// the page. Scanned pages without a text layer come back empty.
+func PDFPages(r io.ReaderAt, size int64) (pages []string, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if p := recover(); p != nil {
+			pages, err = nil, fmt.Errorf("could not read PDF: %v", p)
+		}
+	}()
+
+	reader, err := pdf.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("could not read PDF: %w", err)
+	}
+	if reader.NumPage() > MaxPDFPages {
+		return nil, fmt.Errorf("PDF has %d pages, at most %d are read", reader.NumPage(), MaxPDFPages)
+	}
+	budget := int64(maxPDFTextBytes)
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			pages = append(pages, "")
+			continue
+		}
+		if budget, err = checkPageSize(page, budget); err != nil {
+			return nil, fmt.Errorf("page %d: %w", i, err)
+		}
+		pages = append(pages, pageText(page.Content().Text))
+	}
+
+	var hasText bool
+	for _, page := range pages {
+		hasText = hasText || strings.TrimSpace(page) != ""
+	}
+	if !hasText {
+		return nil, errors.New("PDF has no text, it may be scanned images")
+	}
+	return pages, nil
+}
+
+// checkPageSize unpacks the streams a page's text is read from without keeping
+// them, so a small file that unpacks to gigabytes is refused before the PDF
+// reader holds it in memory. It gives what is left of budget after the page.
+func checkPageSize(page pdf.Page, budget int64) (int64, error) {
+	streams := []pdf.Value{page.V.Key("Contents")}
+	for _, name := range page.Fonts() {
+		streams = append(streams, page.Font(name).V.Key("ToUnicode"))
+	}
+	for _, stream := range streams {
+		if stream.Kind() != pdf.Stream {
+			continue
+		}
+		limit := min(budget, maxPDFStreamBytes)
+		n, err := io.Copy(io.Discard, io.LimitReader(stream.Reader(), limit+1))
+		if err != nil {
+			return 0, fmt.Errorf("could not read PDF: %w", err)
+		}
+		if n > limit && limit == budget {
+			return 0, fmt.Errorf("PDF unpacks to more than %d MB of text", maxPDFTextBytes>>20)
+		}
+		if n > limit {
+			return 0, fmt.Errorf("page unpacks to more than %d MB of text", maxPDFStreamBytes>>20)
+		}
+		budget -= n
+	}
+	return budget, nil
+}
+
+// pageText puts the characters of a page back into lines, top to bottom. PDFs
+// place each character on its own, so characters at about the same height are
+// a line and a gap wider than a space between two of them is a word break.
+func pageText(chars []pdf.Text) string {
+	sort.SliceStable(chars, func(i, j int) bool { return chars[i].Y > chars[j].Y })
+
+	var lines []string
+	for start := 0; start < len(chars); {
+		// Allow for superscripts and mixed font sizes on a line
+		end := start + 1
+		for end < len(chars) && chars[start].Y-chars[end].Y < max(chars[start].FontSize, 1)/2 {
+			end++
+		}
+		lines = append(lines, lineText(chars[start:end]))
+		start = end
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lineText joins the characters of a line left to right
+func lineText(chars []pdf.Text) string {
+	sort.SliceStable(chars, func(i, j int) bool { return chars[i].X < chars[j].X })
+
+	var b strings.Builder
+	for i, char := range chars {
+		if i > 0 {
+			prev := chars[i-1]
+			width := prev.W
+			if width == 0 {
+				// Fonts without widths, guess at an average letter
+				width = prev.FontSize / 2
+			}
+			if char.X-(prev.X+width) > char.FontSize/4 && prev.S != " " && char.S != " " {
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(char.S)
+	}
+	return b.String()
+}
+
+// SplitRecipeText splits the text of a document that may hold many recipes,
+// like a cookbook, into the text of each recipe. A recipe starts at the title
+// above its "Ingredients" heading. Pages without headings are each taken as
+// one recipe.
+func SplitRecipeText(pages []string) []string {
+	var lines []string
+	var pageStarts []int
+	for _, page := range pages {
+		pageStarts = append(pageStarts, len(lines))
+		for _, line := range strings.Split(strings.ReplaceAll(page, "\r\n", "\n"), "\n") {
+			if line = cleanText(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	var starts []int
+	previous := 0
+	for i, line := range lines {
+		if !ingredientsHeadingRe.MatchString(line) {
+			continue
+		}
+		// Take the title and a line about the recipe with it, without
+		// going back into the previous recipe's ingredients
+		start := i
+		for start > previous && i-start < 2 && looksLikeTitle(lines[start-1]) {
+			start--
+		}
+		starts = append(starts, start)
+		previous = i + 1
+	}
+	if len(starts) == 0 {
+		starts = pageStarts
+	}
+
+	// Whatever comes before the first recipe is a preface or contents
+	var recipes []string
+	for i, start := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		if text := strings.Join(lines[start:end], "\n"); text != "" {
+			recipes = append(recipes, text)
+		}
+	}
+	return recipes
+}
+
+// looksLikeTitle tells if a line could be a recipe's title or the short
+// description under it, rather than the last step of the recipe before
+func looksLikeTitle(line string) bool {
+	return len(line) <= 80 &&
+		!strings.HasSuffix(line, ".") &&
+		!bulletRe.MatchString(line) &&
+		!looksLikeIngredient(line) &&
+		!instructionsHeadingRe.MatchString(line) &&
+		!notesHeadingRe.MatchString(line)
+}
@@ -0,0 +1,168 @@
+package parsing_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+// buildPDF writes a PDF with a page for each list of lines, set in Helvetica
+// from the top of the page down
+func buildPDF(pages [][]string) []byte {
+	contents := make([]string, len(pages))
+	for i, lines := range pages {
+		var content strings.Builder
+		content.WriteString("BT /F1 12 Tf 72 720 Td 14 TL\n")
+		for _, line := range lines {
+			line = strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(line)
+			fmt.Fprintf(&content, "(%s) Tj T*\n", line)
+		}
+		content.WriteString("ET")
+		contents[i] = content.String()
+	}
+	return writePDF(contents, false)
+}
+
+// writePDF writes a PDF with a page for each content stream, compressing the
+// streams with flate
+func writePDF(contents []string, flate bool) []byte {
+	var objects []string
+	kids := make([]string, len(contents))
+	for i, content := range contents {
+		stream, filter := []byte(content), ""
+		if flate {
+			var compressed bytes.Buffer
+			w := zlib.NewWriter(&compressed)
+			_, _ = w.Write(stream)
+			_ = w.Close()
+			stream, filter = compressed.Bytes(), " /Filter /FlateDecode"
+		}
+
+		pageID, contentID := 4+2*i, 5+2*i
+		kids[i] = fmt.Sprintf("%d 0 R", pageID)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", contentID),
+			fmt.Sprintf("<< /Length %d%s >>\nstream\n%s\nendstream", len(stream), filter, stream),
+		)
+	}
+	objects = append([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(contents)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}, objects...)
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+func TestPDFPages(t *testing.T) {
+	t.Run("reads each page's lines", func(t *testing.T) {
+		data := buildPDF([][]string{
+			{"Pancakes", "Ingredients", "1 cup flour", "2 eggs"},
+			{"Directions", "Whisk everything (no lumps)."},
+		})
+		pages, err := parsing.PDFPages(bytes.NewReader(data), int64(len(data)))
+		is.NotError(t, err)
+		is.Equal(t, 2, len(pages))
+		is.Equal(t, "Pancakes\nIngredients\n1 cup flour\n2 eggs", pages[0])
+		is.Equal(t, "Directions\nWhisk everything (no lumps).", pages[1])
+	})
+
+	t.Run("refuses files that aren't PDFs", func(t *testing.T) {
+		data := []byte("<html>not a pdf</html>")
+		_, err := parsing.PDFPages(bytes.NewReader(data), int64(len(data)))
+		is.True(t, err != nil)
+	})
+
+	t.Run("refuses PDFs without text", func(t *testing.T) {
+		data := buildPDF([][]string{{}})
+		_, err := parsing.PDFPages(bytes.NewReader(data), int64(len(data)))
+		is.True(t, err != nil)
+	})
+
+	t.Run("reads compressed pages", func(t *testing.T) {
+		data := writePDF([]string{"BT /F1 12 Tf 72 720 Td (Pancakes) Tj ET"}, true)
+		pages, err := parsing.PDFPages(bytes.NewReader(data), int64(len(data)))
+		is.NotError(t, err)
+		is.Equal(t, "Pancakes", pages[0])
+	})
+
+	t.Run("refuses pages that unpack to too much", func(t *testing.T) {
+		data := writePDF([]string{"BT /F1 12 Tf 72 720 Td (Pancakes) Tj ET\n" + strings.Repeat(" ", 3<<20)}, true)
+		_, err := parsing.PDFPages(bytes.NewReader(data), int64(len(data)))
+		is.True(t, err != nil)
+		is.True(t, strings.Contains(err.Error(), "unpacks to more than"))
+	})
+
+	t.Run("refuses PDFs whose pages together unpack to too much", func(t *testing.T) {
+		page := "BT /F1 12 Tf 72 720 Td (Pancakes) Tj ET\n" + strings.Repeat(" ", 1<<20)
+		contents := make([]string, 40)
+		for i := range contents {
+			contents[i] = page
+		}
+		data := writePDF(contents, true)
+		_, err := parsing.PDFPages(bytes.NewReader(data), int64(len(data)))
+		is.True(t, err != nil)
+		is.True(t, strings.Contains(err.Error(), "PDF unpacks to more than"))
+	})
+}
+
+func TestSplitRecipeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages []string
+		want  []string
+	}{
+		{
+			name: "splits at each ingredients heading with its title",
+			pages: []string{
+				"My Family Cookbook\nContents\n\nPancakes\nFluffy and quick\nIngredients\n1 cup flour\n2 eggs\nDirections\nWhisk and fry.",
+				"Tomato Soup\nIngredients\n6 tomatoes\nMethod\nSimmer for an hour.\n12",
+			},
+			want: []string{
+				"Pancakes\nFluffy and quick\nIngredients\n1 cup flour\n2 eggs\nDirections\nWhisk and fry.",
+				"Tomato Soup\nIngredients\n6 tomatoes\nMethod\nSimmer for an hour.\n12",
+			},
+		},
+		{
+			name: "does not take the previous recipe's steps as a title",
+			pages: []string{
+				"Pancakes\nIngredients\n1 cup flour\nDirections\nFry until golden.\nWaffles\nIngredients\n2 cups flour",
+			},
+			want: []string{
+				"Pancakes\nIngredients\n1 cup flour\nDirections\nFry until golden.",
+				"Waffles\nIngredients\n2 cups flour",
+			},
+		},
+		{
+			name:  "takes each page as a recipe without headings",
+			pages: []string{"Pancakes\n1 cup flour\nFry.", "", "Waffles\n2 cups flour\nBake."},
+			want:  []string{"Pancakes\n1 cup flour\nFry.", "Waffles\n2 cups flour\nBake."},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := parsing.SplitRecipeText(test.pages)
+			is.Equal(t, strings.Join(test.want, "\n---\n"), strings.Join(got, "\n---\n"))
+		})
+	}
+}
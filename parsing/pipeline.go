@@ -0,0 +1,227 @@
+package parsing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Source records which extraction path produced a recipe's data
+type Source string
+
+const (
+	SourceJSONLD    Source = "json-ld"
+	SourceMicrodata Source = "microdata"
+	SourceSite      Source = "site"      // A SiteExtractor for the page's site or recipe plugin
+	SourceHeuristic Source = "heuristic" // Guessed from class names and text, see Confidence
+	SourceLLM       Source = "llm"
+	SourceManual    Source = "manual" // Typed in by hand in the review editor
+	SourceImport    Source = "import" // Read from another recipe manager's export
+)
+
+// ParserVersion goes up with every change to the deterministic extractors that
+// would give different data for the same page. Recipes extracted by an older
+// version are re-extracted by cmd/reprocess.
+const ParserVersion = 1
+
+// Versions gives the parser and prompt versions that data from this source was
+// extracted with. Data that was typed in or imported was not extracted, and
+// has neither.
+func (s Source) Versions() (parser, prompt int) {
+	switch s {
+	case SourceLLM:
+		return ParserVersion, PromptVersion
+	case SourceManual, SourceImport, "":
+		return 0, 0
+	default:
+		return ParserVersion, 0
+	}
+}
+
+// Pipeline turns fetched pages into recipe data
+type Pipeline struct {
+	extractor RecipeExtractor // nil when no LLM backend is configured
+	cache     ExtractionCache // nil to always ask the LLM
+}
+
+// NewPipeline makes a pipeline that falls back to extractor when structured
+// data is not enough. A nil extractor limits it to the deterministic paths.
+func NewPipeline(extractor RecipeExtractor) *Pipeline {
+	return &Pipeline{extractor: extractor}
+}
+
+// WithCache reuses what the LLM read from the same text before, kept in cache
+func (p *Pipeline) WithCache(cache ExtractionCache) *Pipeline {
+	p.cache = cache
+	return p
+}
+
+// Versions gives the parser and prompt versions an extraction is tried with,
+// the prompt's only when there is an LLM to ask
+func (p *Pipeline) Versions() (parser, prompt int) {
+	if p.extractor == nil {
+		return ParserVersion, 0
+	}
+	return ParserVersion, PromptVersion
+}
+
+// Extract turns a fetched page into a RecipeCollection. The deterministic
+// extractors run first and the LLM is only asked when the page has no
+// structured recipe data or the data it has is incomplete.
+func (p *Pipeline) Extract(ctx context.Context, pageURL string, htmlContent []byte) (*RecipeCollection, Source, error) {
+	extracted, err := ParseRecipe(pageURL, htmlContent)
+	return p.extract(ctx, extracted, err, func() []byte { return HtmlToText(htmlContent) })
+}
+
+// ExtractText turns recipe text a user pasted, such as a family recipe from an
+// email, into a RecipeCollection. The text is read for headings and ingredient
+// lines before the LLM is asked.
+func (p *Pipeline) ExtractText(ctx context.Context, text string) (*RecipeCollection, Source, error) {
+	extracted, err := ParseRecipeText(text)
+	return p.extract(ctx, extracted, err, func() []byte { return []byte(text) })
+}
+
+// extract decides between the deterministic result and asking the LLM about
+// the recipe's text
+func (p *Pipeline) extract(ctx context.Context, extracted *ExtractedRecipe, err error, text func() []byte) (*RecipeCollection, Source, error) {
+	if err == nil && extracted.IsComplete() && extracted.Trusted() {
+		slog.Info("using structured recipe data", "source", extracted.Source, "confidence", extracted.Confidence.Overall())
+		return extracted.ToCollection(), extracted.Source, nil
+	}
+
+	if p.extractor == nil {
+		return structuredOnly(extracted, err, ErrNoExtractor)
+	}
+	switch {
+	case err != nil:
+		slog.Info("no structured recipe data, asking LLM", "reason", err)
+	case !extracted.Trusted():
+		slog.Info("heuristic recipe data not confident enough, asking LLM", "confidence", extracted.Confidence)
+	default:
+		slog.Info("structured recipe data incomplete, asking LLM", "source", extracted.Source)
+	}
+
+	collection, llmErr := p.askLLM(ctx, text())
+	if errors.Is(llmErr, ErrOverBudget) {
+		return structuredOnly(extracted, err, llmErr)
+	}
+	if llmErr != nil {
+		return nil, SourceLLM, fmt.Errorf("no recipe data from LLM: %w", llmErr)
+	}
+	return collection, SourceLLM, nil
+}
+
+// askLLM reads the recipe in text with the LLM, unless it read the same text
+// with the same model and prompt before. The cache is only a saving, failing
+// to use it is logged.
+func (p *Pipeline) askLLM(ctx context.Context, text []byte) (*RecipeCollection, error) {
+	var key string
+	if p.cache != nil {
+		key = CacheKey(text, p.extractor.Model())
+		cached, err := p.cache.Get(ctx, key)
+		if err != nil {
+			slog.Warn("could not read extraction cache", "key", key, "error", err)
+		}
+		if cached != nil {
+			slog.Info("using cached LLM extraction", "key", key)
+			return cached, nil
+		}
+	}
+
+	collection, err := RecipeTextToCollection(ctx, p.extractor, text)
+	if err != nil {
+		return nil, err
+	}
+	// The LLM's categories are free text, ours match the aisles the UI groups by
+	collection.Categorize()
+
+	if p.cache != nil {
+		if err := p.cache.Put(context.WithoutCancel(ctx), key, p.extractor.Model(), collection); err != nil {
+			slog.Warn("could not cache LLM extraction", "key", key, "error", err)
+		}
+	}
+	return collection, nil
+}
+
+// structuredOnly makes do with what the deterministic extractors found when the
+// LLM can't be asked, for the reason given. Partial structured data is still
+// better than nothing, unlikely guesses are not.
+func structuredOnly(extracted *ExtractedRecipe, err error, reason error) (*RecipeCollection, Source, error) {
+	if err == nil && extracted.Trusted() {
+		slog.Info("structured recipe data incomplete and the LLM can't be asked", "source", extracted.Source, "reason", reason)
+		return extracted.ToCollection(), extracted.Source, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("heuristic confidence %.2f is below %.2f", extracted.Confidence.Overall(), MinHeuristicConfidence)
+	}
+	return nil, "", fmt.Errorf("%w: %v", reason, err)
+}
+
+// IsComplete tells if the recipe has enough data to skip the LLM
+func (e *ExtractedRecipe) IsComplete() bool {
+	return len(e.Ingredients) > 0 && len(e.Instructions) > 0
+}
+
+// ToCollection converts the extracted recipe into the format stored for each recipe
+func (e *ExtractedRecipe) ToCollection() *RecipeCollection {
+	recipe := Recipe{
+		Name:         e.Title,
+		PrepTime:     e.PrepTime,
+		CookTime:     e.CookTime,
+		TotalTime:    e.TotalTime,
+		Servings:     ParseServings(e.Yield),
+		Image:        e.ImageURL,
+		Video:        e.VideoURL,
+		Nutrition:    e.Nutrition,
+		Ingredients:  make([]Ingredient, 0, len(e.Ingredients)),
+		Instructions: e.Instructions,
+	}
+	for _, line := range e.Ingredients {
+		ingredient := ParseIngredient(line)
+		if ingredient.Name == "" {
+			continue
+		}
+		recipe.Ingredients = append(recipe.Ingredients, ingredient)
+	}
+	if len(e.Sections) == 0 {
+		return &RecipeCollection{Recipes: []Recipe{recipe}}
+	}
+
+	// Named instruction groups become sub-recipes, like "For the frosting", and
+	// the main recipe keeps the ingredients and any steps outside a group
+	recipe.Instructions = nil
+	collection := &RecipeCollection{Recipes: []Recipe{recipe}}
+	for _, section := range e.Sections {
+		target := &collection.Recipes[0]
+		if section.Name != "" {
+			collection.Recipes = append(collection.Recipes, Recipe{Name: section.Name, Ingredients: []Ingredient{}})
+			target = &collection.Recipes[len(collection.Recipes)-1]
+		}
+		for _, step := range section.Steps {
+			if step.Image != "" || step.Video != "" {
+				target.StepMedia = append(target.StepMedia, StepMedia{Step: len(target.Instructions), Image: step.Image, Video: step.Video})
+			}
+			target.Instructions = append(target.Instructions, step.Text)
+		}
+	}
+	return collection
+}
+
+var servingsRe = regexp.MustCompile(`\d+`)
+
+// ParseServings pulls the first number out of a yield such as "4 servings" or "Serves 6-8"
+func ParseServings(yield string) int {
+	match := servingsRe.FindString(strings.TrimSpace(yield))
+	if match == "" {
+		return 0
+	}
+	servings, err := strconv.Atoi(match)
+	if err != nil {
+		return 0
+	}
+	return servings
+}
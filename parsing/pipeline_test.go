@@ -0,0 +1,136 @@
+package parsing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+const jsonLDPage = `<html><head>
+<script type="application/ld+json">{"@context":"https://schema.org","@type":"Recipe","name":"Pancakes","recipeYield":"4 servings","prepTime":"PT10M","recipeIngredient":["1 cup flour","1 egg"],"recipeInstructions":[{"@type":"HowToStep","text":"Mix."},{"@type":"HowToStep","text":"Fry."}]}</script>
+</head><body><h1>Pancakes</h1></body></html>`
+
+// ingredientsOnlyPage has JSON-LD without instructions, which the LLM would fill in
+const ingredientsOnlyPage = `<html><head>
+<script type="application/ld+json">{"@context":"https://schema.org","@type":"Recipe","name":"Pancakes","recipeIngredient":["1 cup flour","1 egg"]}</script>
+</head><body><h1>Pancakes</h1></body></html>`
+
+const plainPage = `<html><body><article><h1>Toast</h1><p>Put bread in the toaster.</p></article></body></html>`
+
+func TestPipeline_Extract(t *testing.T) {
+	t.Run("uses JSON-LD without asking the LLM", func(t *testing.T) {
+		fake := &parsing.FakeExtractor{}
+		collection, source, err := parsing.NewPipeline(fake).Extract(context.Background(), "", []byte(jsonLDPage))
+		is.NotError(t, err)
+		is.Equal(t, parsing.SourceJSONLD, source)
+		is.Equal(t, 0, len(fake.Prompts()))
+		is.Equal(t, 1, len(collection.Recipes))
+
+		recipe := collection.Recipes[0]
+		is.Equal(t, "Pancakes", recipe.Name)
+		is.Equal(t, 4, recipe.Servings)
+		is.Equal(t, "PT10M", recipe.PrepTime)
+		is.Equal(t, 2, len(recipe.Ingredients))
+		is.Equal(t, "Fry.", recipe.Instructions[1])
+	})
+
+	t.Run("asks the LLM when there is no structured data", func(t *testing.T) {
+		fake := &parsing.FakeExtractor{Responses: []string{`{"recipes":[{"name":"Toast","ingredients":[{"name":"bread","amount":1,"unit":"slice"}]}]}`}}
+		collection, source, err := parsing.NewPipeline(fake).Extract(context.Background(), "", []byte(plainPage))
+		is.NotError(t, err)
+		is.Equal(t, parsing.SourceLLM, source)
+		is.Equal(t, 1, len(fake.Prompts()))
+		is.Equal(t, "bread", collection.Recipes[0].Ingredients[0].Name)
+	})
+
+	t.Run("reports when there is no structured data and no LLM", func(t *testing.T) {
+		_, _, err := parsing.NewPipeline(nil).Extract(context.Background(), "", []byte(plainPage))
+		is.True(t, errors.Is(err, parsing.ErrNoExtractor))
+	})
+
+	t.Run("uses incomplete structured data when the LLM is over budget", func(t *testing.T) {
+		fake := &parsing.FakeExtractor{Err: parsing.ErrOverBudget}
+		collection, source, err := parsing.NewPipeline(fake).Extract(context.Background(), "", []byte(ingredientsOnlyPage))
+		is.NotError(t, err)
+		is.Equal(t, parsing.SourceJSONLD, source)
+		is.Equal(t, 1, len(fake.Prompts()))
+		is.Equal(t, 2, len(collection.Recipes[0].Ingredients))
+	})
+
+	t.Run("reports the budget when there is no structured data to fall back on", func(t *testing.T) {
+		fake := &parsing.FakeExtractor{Err: parsing.ErrOverBudget}
+		_, _, err := parsing.NewPipeline(fake).Extract(context.Background(), "", []byte(plainPage))
+		is.True(t, errors.Is(err, parsing.ErrOverBudget))
+	})
+}
+
+// mapCache is an ExtractionCache in memory
+type mapCache map[string]*parsing.RecipeCollection
+
+func (c mapCache) Get(ctx context.Context, key string) (*parsing.RecipeCollection, error) {
+	return c[key], nil
+}
+
+func (c mapCache) Put(ctx context.Context, key, model string, collection *parsing.RecipeCollection) error {
+	c[key] = collection
+	return nil
+}
+
+func TestPipeline_WithCache(t *testing.T) {
+	t.Run("reuses what the LLM read from the same page", func(t *testing.T) {
+		cache := mapCache{}
+		first := &parsing.FakeExtractor{Responses: []string{`{"recipes":[{"name":"Toast","ingredients":[{"name":"bread","amount":1,"unit":"slice"}]}]}`}}
+		_, _, err := parsing.NewPipeline(first).WithCache(cache).Extract(context.Background(), "", []byte(plainPage))
+		is.NotError(t, err)
+		is.Equal(t, 1, len(cache))
+
+		second := &parsing.FakeExtractor{Err: errors.New("should not be asked")}
+		collection, source, err := parsing.NewPipeline(second).WithCache(cache).Extract(context.Background(), "", []byte(plainPage))
+		is.NotError(t, err)
+		is.Equal(t, parsing.SourceLLM, source)
+		is.Equal(t, 0, len(second.Prompts()))
+		is.Equal(t, "bread", collection.Recipes[0].Ingredients[0].Name)
+	})
+
+	t.Run("does not cache failures", func(t *testing.T) {
+		cache := mapCache{}
+		down := errors.New("down")
+		fake := &parsing.FakeExtractor{Err: down}
+		_, _, err := parsing.NewPipeline(fake).WithCache(cache).Extract(context.Background(), "", []byte(plainPage))
+		is.Error(t, down, err)
+		is.Equal(t, 0, len(cache))
+	})
+}
+
+func TestCacheKey(t *testing.T) {
+	text := []byte("Toast\nPut bread in the toaster.")
+	is.Equal(t, parsing.CacheKey(text, "fake/fake"), parsing.CacheKey(text, "fake/fake"))
+	is.True(t, parsing.CacheKey(text, "fake/fake") != parsing.CacheKey(text, "openai/gpt-4o-mini"))
+	is.True(t, parsing.CacheKey(text, "fake/fake") != parsing.CacheKey([]byte("Toast"), "fake/fake"))
+	is.Equal(t, 64, len(parsing.CacheKey(text, "fake/fake")))
+}
+
+func TestSource_Versions(t *testing.T) {
+	tests := []struct {
+		source         parsing.Source
+		parser, prompt int
+	}{
+		{parsing.SourceJSONLD, parsing.ParserVersion, 0},
+		{parsing.SourceHeuristic, parsing.ParserVersion, 0},
+		{parsing.SourceLLM, parsing.ParserVersion, parsing.PromptVersion},
+		{parsing.SourceManual, 0, 0},
+		{parsing.SourceImport, 0, 0},
+		{"", 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(string(test.source), func(t *testing.T) {
+			parser, prompt := test.source.Versions()
+			is.Equal(t, test.parser, parser)
+			is.Equal(t, test.prompt, prompt)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package parsing
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Scale gives a copy of the collection with ingredient amounts multiplied to
+// serve the given number of people. The servings of the main recipe set one
+// factor for every part, so the frosting grows with the cake. Collections
+// without a known number of servings are copied unchanged.
+func (c *RecipeCollection) Scale(servings int) *RecipeCollection {
+	if c == nil {
+		return nil
+	}
+	factor := 1.0
+	if len(c.Recipes) > 0 && c.Recipes[0].Servings > 0 && servings > 0 {
+		factor = float64(servings) / float64(c.Recipes[0].Servings)
+	}
+	scaled := &RecipeCollection{Recipes: make([]Recipe, len(c.Recipes))}
+	for i, recipe := range c.Recipes {
+		ingredients := make([]Ingredient, len(recipe.Ingredients))
+		copy(ingredients, recipe.Ingredients)
+		if factor != 1 {
+			for j := range ingredients {
+				ingredients[j] = ScaleIngredient(ingredients[j], factor)
+			}
+			if recipe.Servings > 0 {
+				recipe.Servings = max(1, int(math.Round(float64(recipe.Servings)*factor)))
+			}
+		}
+		recipe.Ingredients = ingredients
+		scaled.Recipes[i] = recipe
+	}
+	return scaled
+}
+
+// ScaleIngredient multiplies the amount of an ingredient, then moves it to a
+// bigger or smaller unit when the amount gets awkward, so 16 tbsp becomes 1 cup
+func ScaleIngredient(ingredient Ingredient, factor float64) Ingredient {
+	if ingredient.Amount == nil {
+		return ingredient
+	}
+	amount := *ingredient.Amount * factor
+	unit := NormalizeUnit(ingredient.Unit)
+	if _, known := unitAliases[unit]; !known {
+		// Keep how the recipe wrote an unknown unit
+		unit = ingredient.Unit
+	}
+
+	// Each step moves one unit up or down, a few are enough to settle
+	for range 3 {
+		next, ok := unitSteps[unit]
+		if !ok {
+			break
+		}
+		if next.up != "" && amount >= next.upAt {
+			amount, unit = amount/next.upAt, next.up
+		} else if next.down != "" && amount < next.downBelow {
+			amount, unit = amount*next.downFactor, next.down
+		} else {
+			break
+		}
+	}
+
+	amount = roundForUnit(amount, unit)
+	ingredient.Amount = &amount
+	ingredient.Unit = unit
+	return ingredient
+}
+
+// unitStep says when an amount is too big or too small for its unit
+type unitStep struct {
+	up         string  // Bigger unit
+	upAt       float64 // How many of this unit make one of the bigger unit
+	down       string  // Smaller unit
+	downBelow  float64 // Amounts below this use the smaller unit
+	downFactor float64 // How many of the smaller unit make one of this unit
+}
+
+var unitSteps = map[string]unitStep{
+	"tsp":  {up: "tbsp", upAt: 3},
+	"tbsp": {up: "cup", upAt: 16, down: "tsp", downBelow: 1, downFactor: 3},
+	"cup":  {down: "tbsp", downBelow: 0.25, downFactor: 16},
+	"oz":   {up: "lb", upAt: 16},
+	"lb":   {down: "oz", downBelow: 1, downFactor: 16},
+	"g":    {up: "kg", upAt: 1000},
+	"kg":   {down: "g", downBelow: 1, downFactor: 1000},
+	"ml":   {up: "l", upAt: 1000},
+	"l":    {down: "ml", downBelow: 1, downFactor: 1000},
+}
+
+// roundForUnit rounds to what can actually be measured with the unit
+func roundForUnit(amount float64, unit string) float64 {
+	switch {
+	case isMetricUnit(unit):
+		if unit == "kg" || unit == "l" {
+			return roundTo(amount, 0.05)
+		}
+		return roundMetric(amount)
+	case volumeUnits[unit] > 0 || massUnits[unit] > 0:
+		return roundFraction(amount)
+	default:
+		// Eggs, cloves and cans come in halves at best
+		if amount >= 10 {
+			return math.Round(amount)
+		}
+		return roundTo(amount, 0.5)
+	}
+}
+
+// friendlyFractions are the fractions found on measuring cups and spoons
+var friendlyFractions = []struct {
+	value float64
+	glyph string
+}{
+	{1.0 / 8, "⅛"}, {1.0 / 4, "¼"}, {1.0 / 3, "⅓"}, {3.0 / 8, "⅜"}, {1.0 / 2, "½"},
+	{5.0 / 8, "⅝"}, {2.0 / 3, "⅔"}, {3.0 / 4, "¾"}, {7.0 / 8, "⅞"},
+}
+
+// roundFraction rounds to the nearest whole number or friendly fraction
+func roundFraction(amount float64) float64 {
+	whole, frac := math.Modf(amount)
+	best, bestDiff := 0.0, frac
+	if 1-frac < bestDiff {
+		best, bestDiff = 1, 1-frac
+	}
+	for _, f := range friendlyFractions {
+		if diff := math.Abs(frac - f.value); diff < bestDiff {
+			best, bestDiff = f.value, diff
+		}
+	}
+	if whole+best == 0 && amount > 0 {
+		return friendlyFractions[0].value
+	}
+	return whole + best
+}
+
+// FormatAmount writes an amount the way a recipe would, with fractions like
+// "1 ½" for US units and decimals like "1.5" for metric ones
+func FormatAmount(amount float64, unit string) string {
+	if !isMetricUnit(NormalizeUnit(unit)) {
+		whole, frac := math.Modf(amount)
+		for _, f := range friendlyFractions {
+			if math.Abs(frac-f.value) < 0.01 {
+				if whole == 0 {
+					return f.glyph
+				}
+				return strconv.FormatFloat(whole, 'f', -1, 64) + " " + f.glyph
+			}
+		}
+	}
+	formatted := strconv.FormatFloat(amount, 'f', 2, 64)
+	formatted = strings.TrimRight(formatted, "0")
+	return strings.TrimSuffix(formatted, ".")
+}
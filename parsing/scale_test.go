@@ -0,0 +1,107 @@
+package parsing_test
+
+import (
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+func TestRecipeCollection_Scale(t *testing.T) {
+	collection := &parsing.RecipeCollection{Recipes: []parsing.Recipe{{
+		Name:     "Pancakes",
+		Servings: 4,
+		Ingredients: []parsing.Ingredient{
+			parsing.ParseIngredient("1 cup flour"),
+			parsing.ParseIngredient("2 eggs"),
+			parsing.ParseIngredient("8 tbsp butter"),
+			parsing.ParseIngredient("salt to taste"),
+		},
+	}}}
+
+	t.Run("multiplies amounts and switches units", func(t *testing.T) {
+		scaled := collection.Scale(8)
+		recipe := scaled.Recipes[0]
+		is.Equal(t, 8, recipe.Servings)
+		is.Equal(t, 2.0, *recipe.Ingredients[0].Amount)
+		is.Equal(t, 4.0, *recipe.Ingredients[1].Amount)
+		is.Equal(t, 1.0, *recipe.Ingredients[2].Amount)
+		is.Equal(t, "cup", recipe.Ingredients[2].Unit)
+		is.Nil(t, recipe.Ingredients[3].Amount)
+	})
+
+	t.Run("does not change the original", func(t *testing.T) {
+		collection.Scale(2)
+		is.Equal(t, 4, collection.Recipes[0].Servings)
+		is.Equal(t, 1.0, *collection.Recipes[0].Ingredients[0].Amount)
+	})
+
+	t.Run("leaves recipes without servings alone", func(t *testing.T) {
+		unknown := &parsing.RecipeCollection{Recipes: []parsing.Recipe{{
+			Ingredients: []parsing.Ingredient{parsing.ParseIngredient("1 cup flour")},
+		}}}
+		is.Equal(t, 1.0, *unknown.Scale(8).Recipes[0].Ingredients[0].Amount)
+	})
+
+	t.Run("scales every part by the main recipe's servings", func(t *testing.T) {
+		cake := &parsing.RecipeCollection{Recipes: []parsing.Recipe{
+			{Name: "Carrot Cake", Servings: 12},
+			{Name: "Cake", Servings: 6, Ingredients: []parsing.Ingredient{parsing.ParseIngredient("2 cups flour")}},
+			{Name: "Frosting", Ingredients: []parsing.Ingredient{parsing.ParseIngredient("1 cup cream cheese")}},
+		}}
+		scaled := cake.Scale(24)
+		is.Equal(t, 24, scaled.Recipes[0].Servings)
+		is.Equal(t, 12, scaled.Recipes[1].Servings)
+		is.Equal(t, 4.0, *scaled.Recipes[1].Ingredients[0].Amount)
+		is.Equal(t, 0, scaled.Recipes[2].Servings)
+		is.Equal(t, 2.0, *scaled.Recipes[2].Ingredients[0].Amount)
+	})
+}
+
+func TestScaleIngredient(t *testing.T) {
+	tests := []struct {
+		line   string
+		factor float64
+		amount float64
+		unit   string
+	}{
+		{"1 tbsp olive oil", 1.5, 1.5, "tbsp"},
+		{"2 tsp salt", 3, 2, "tbsp"},
+		{"1/4 cup sugar", 0.5, 2, "tbsp"},
+		{"1 tbsp vinegar", 0.25, 0.75, "tsp"},
+		{"12 oz pasta", 2, 1.5, "lb"},
+		{"600 g flour", 2, 1.2, "kg"},
+		{"1 cup milk", 1.0 / 3, 1.0 / 3, "cup"},
+		{"3 eggs", 0.5, 1.5, ""},
+		{"3 cloves garlic", 1.0 / 3, 1, "clove"},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			scaled := parsing.ScaleIngredient(parsing.ParseIngredient(test.line), test.factor)
+			is.Equal(t, test.amount, *scaled.Amount)
+			is.Equal(t, test.unit, scaled.Unit)
+		})
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		unit     string
+		expected string
+	}{
+		{1.5, "tbsp", "1 ½"},
+		{0.25, "cup", "¼"},
+		{2.0 / 3, "cup", "⅔"},
+		{2, "cup", "2"},
+		{1.5, "kg", "1.5"},
+		{250, "g", "250"},
+		{0.3, "cup", "0.3"},
+	}
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			is.Equal(t, test.expected, parsing.FormatAmount(test.amount, test.unit))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package parsing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// wprmExtractor reads pages made with the WP Recipe Maker WordPress plugin
+var wprmExtractor = SiteExtractor{
+	Name:        "wp-recipe-maker",
+	Fingerprint: ".wprm-recipe-container",
+	Extract: func(doc *goquery.Document) (*ExtractedRecipe, error) {
+		root := doc.Find(".wprm-recipe-container").First()
+		// Screen reader text repeats the units
+		root.Find(".sr-only, .wprm-screen-reader-text").Remove()
+
+		recipe := &ExtractedRecipe{
+			Title:        cleanText(root.Find(".wprm-recipe-name").First().Text()),
+			Description:  cleanText(root.Find(".wprm-recipe-summary").First().Text()),
+			ImageURL:     imageSource(root.Find(".wprm-recipe-image img").First()),
+			Instructions: selectionTexts(root.Find(".wprm-recipe-instruction-text")),
+			PrepTime:     wprmTime(root, "prep"),
+			CookTime:     wprmTime(root, "cook"),
+			TotalTime:    wprmTime(root, "total"),
+			Yield:        cleanText(root.Find(".wprm-recipe-servings").First().Text() + " " + root.Find(".wprm-recipe-servings-unit").First().Text()),
+			Author:       cleanText(root.Find(".wprm-recipe-author").First().Text()),
+		}
+		root.Find(".wprm-recipe-ingredient").Each(func(_ int, ing *goquery.Selection) {
+			var parts []string
+			for _, class := range []string{"amount", "unit", "name", "notes"} {
+				if text := cleanText(ing.Find(".wprm-recipe-ingredient-" + class).Text()); text != "" {
+					parts = append(parts, text)
+				}
+			}
+			if len(parts) > 0 {
+				recipe.Ingredients = append(recipe.Ingredients, strings.Join(parts, " "))
+			}
+		})
+		return recipe, nil
+	},
+}
+
+// wprmTime joins the number and unit spans of a WP Recipe Maker time, as in "1 hr 15 mins"
+func wprmTime(root *goquery.Selection, kind string) string {
+	container := root.Find(fmt.Sprintf(".wprm-recipe-%s-time-container, .wprm-recipe-%s_time-container", kind, kind)).First()
+	return strings.Join(selectionTexts(container.Find(".wprm-recipe-details, .wprm-recipe-details-unit")), " ")
+}
+
+// tastyRecipesExtractor reads pages made with the Tasty Recipes WordPress plugin
+var tastyRecipesExtractor = SiteExtractor{
+	Name:        "tasty-recipes",
+	Fingerprint: ".tasty-recipes",
+	Extract: func(doc *goquery.Document) (*ExtractedRecipe, error) {
+		root := doc.Find(".tasty-recipes").First()
+		// The yield has buttons for scaling the recipe next to it
+		yield := root.Find(".tasty-recipes-yield").First().Clone()
+		yield.Find("button, .tasty-recipes-yield-scale").Remove()
+
+		return &ExtractedRecipe{
+			Title:        cleanText(root.Find(".tasty-recipes-title").First().Text()),
+			Description:  cleanText(root.Find(".tasty-recipes-description").First().Text()),
+			ImageURL:     imageSource(root.Find(".tasty-recipes-image img").First()),
+			Ingredients:  selectionTexts(root.Find(".tasty-recipes-ingredients li")),
+			Instructions: selectionTexts(root.Find(".tasty-recipes-instructions li")),
+			PrepTime:     cleanText(root.Find(".tasty-recipes-prep-time").First().Text()),
+			CookTime:     cleanText(root.Find(".tasty-recipes-cook-time").First().Text()),
+			TotalTime:    cleanText(root.Find(".tasty-recipes-total-time").First().Text()),
+			Yield:        cleanText(yield.Text()),
+			Author:       cleanText(root.Find(".tasty-recipes-author-name").First().Text()),
+		}, nil
+	},
+}
+
+// foodComExtractor reads food.com, whose hero image is loaded lazily and is
+// not always in its JSON-LD, so the generic parsers can miss the photo
+var foodComExtractor = SiteExtractor{
+	Name:  "food.com",
+	Hosts: []string{"food.com"},
+	Extract: func(doc *goquery.Document) (*ExtractedRecipe, error) {
+		recipe := &ExtractedRecipe{}
+		jsonLD := extractJSONLD(doc)
+		if jsonLD == nil {
+			return nil, fmt.Errorf("no recipe JSON-LD")
+		}
+		mapJSONLDToRecipe(jsonLD, recipe)
+
+		if recipe.ImageURL == "" {
+			recipe.ImageURL = imageSource(doc.Find(".primary-image img, .recipe-hero img, .recipe-image img").First())
+		}
+		if recipe.ImageURL == "" {
+			recipe.ImageURL, _ = doc.Find(`meta[property="og:image"]`).Attr("content")
+		}
+		return recipe, nil
+	},
+}
+
+// nytExtractor reads NYT Cooking, which has no recipe plugin and names its
+// classes with generated suffixes such as "ingredient_ingredient__rFi3c"
+var nytExtractor = SiteExtractor{
+	Name:        "nyt-cooking",
+	Hosts:       []string{"cooking.nytimes.com"},
+	Fingerprint: `li[class*="ingredient_ingredient__"]`,
+	Extract: func(doc *goquery.Document) (*ExtractedRecipe, error) {
+		recipe := &ExtractedRecipe{
+			Title:        firstText(doc, `h1[class*="header_recipe-name"], h1`),
+			Description:  firstText(doc, `[class*="topnote_topnoteParagraphs"]`),
+			ImageURL:     imageSource(doc.Find(`[class*="recipeheaderimage"] img, [class*="RecipePageImage"] img`).First()),
+			Instructions: selectionTexts(doc.Find(`li[class*="preparation_step__"] p, [class*="preparation_stepContent"]`)),
+			Author:       firstText(doc, `[class*="byline"] a, [class*="byline_author"]`),
+		}
+
+		doc.Find(`li[class*="ingredient_ingredient__"]`).Each(func(_ int, li *goquery.Selection) {
+			// The quantity is its own span, so keep a space between it and the name
+			var parts []string
+			li.Children().Each(func(_ int, part *goquery.Selection) {
+				if text := cleanText(part.Text()); text != "" {
+					parts = append(parts, text)
+				}
+			})
+			if len(parts) == 0 {
+				parts = append(parts, cleanText(li.Text()))
+			}
+			recipe.Ingredients = append(recipe.Ingredients, strings.Join(parts, " "))
+		})
+
+		yield := firstText(doc, `[class*="ingredients_recipeYield"]`)
+		recipe.Yield = strings.TrimSpace(strings.TrimPrefix(yield, "Yield:"))
+
+		// Times are a definition list of labels and values
+		doc.Find(`dl[class*="stats_cookingTimeTable"] dt`).Each(func(_ int, dt *goquery.Selection) {
+			value := cleanText(dt.Next().Text())
+			switch label := strings.ToLower(cleanText(dt.Text())); {
+			case strings.Contains(label, "total"):
+				recipe.TotalTime = value
+			case strings.Contains(label, "prep"):
+				recipe.PrepTime = value
+			case strings.Contains(label, "cook"):
+				recipe.CookTime = value
+			}
+		})
+		return recipe, nil
+	},
+}
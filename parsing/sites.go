@@ -0,0 +1,98 @@
+package parsing
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// SiteExtractor reads recipes from one site, or from the markup of one recipe
+// plugin, where knowing the layout beats the generic parsers
+type SiteExtractor struct {
+	Name string
+	// Hosts the extractor is used for, including their subdomains
+	Hosts []string
+	// Fingerprint is a CSS selector only found on pages made by the plugin
+	Fingerprint string
+	Extract     func(doc *goquery.Document) (*ExtractedRecipe, error)
+}
+
+// siteExtractors run before the generic JSON-LD and microdata parsers
+var siteExtractors = []SiteExtractor{
+	foodComExtractor,
+	nytExtractor,
+	wprmExtractor,
+	tastyRecipesExtractor,
+}
+
+// RegisterSiteExtractor adds an extractor. It is not safe to call while pages are being parsed.
+func RegisterSiteExtractor(extractor SiteExtractor) {
+	siteExtractors = append(siteExtractors, extractor)
+}
+
+// findSiteExtractor picks the extractor for a page. A host match wins over a
+// fingerprint, since a site can use a plugin and still need special handling.
+func findSiteExtractor(pageURL string, doc *goquery.Document) (SiteExtractor, bool) {
+	if host := pageHost(pageURL); host != "" {
+		for _, extractor := range siteExtractors {
+			for _, h := range extractor.Hosts {
+				if host == h || strings.HasSuffix(host, "."+h) {
+					return extractor, true
+				}
+			}
+		}
+	}
+	for _, extractor := range siteExtractors {
+		if extractor.Fingerprint != "" && doc.Find(extractor.Fingerprint).Length() > 0 {
+			return extractor, true
+		}
+	}
+	return SiteExtractor{}, false
+}
+
+func pageHost(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// cleanText collapses the whitespace left behind by markup
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// selectionTexts gives the cleaned text of every element matched, skipping empty ones
+func selectionTexts(sel *goquery.Selection) []string {
+	texts := []string{}
+	sel.Each(func(_ int, s *goquery.Selection) {
+		if text := cleanText(s.Text()); text != "" {
+			texts = append(texts, text)
+		}
+	})
+	return texts
+}
+
+// firstText gives the cleaned text of the first element matching selector
+func firstText(doc *goquery.Document, selector string) string {
+	return cleanText(doc.Find(selector).First().Text())
+}
+
+// imageSource reads an image's URL, looking past the placeholders used for lazy loading
+func imageSource(img *goquery.Selection) string {
+	for _, attr := range []string{"data-lazy-src", "data-src", "src"} {
+		if src, ok := img.Attr(attr); ok && src != "" && !strings.HasPrefix(src, "data:") {
+			return src
+		}
+	}
+	for _, attr := range []string{"data-lazy-srcset", "data-srcset", "srcset"} {
+		if srcset, ok := img.Attr(attr); ok {
+			if first, _, _ := strings.Cut(strings.TrimSpace(srcset), " "); first != "" {
+				return strings.TrimSuffix(first, ",")
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+package parsing_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+func TestParseRecipe_siteExtractors(t *testing.T) {
+	tests := []struct {
+		fixture      string
+		url          string
+		title        string
+		image        string
+		ingredients  []string
+		instructions int
+		lastStep     string
+		prep, total  string
+		yield        string
+		author       string
+	}{
+		{
+			fixture: "wprm.html",
+			url:     "https://example-blog.com/easy-banana-bread/",
+			title:   "Easy Banana Bread",
+			image:   "https://example-blog.com/wp-content/uploads/2024/02/banana-bread-500x500.jpg",
+			ingredients: []string{
+				"3 ripe bananas mashed",
+				"½ cup butter (melted)",
+				"¾ cup brown sugar",
+				"1 egg",
+				"1 ½ cups all-purpose flour",
+				"1 tsp baking soda",
+			},
+			instructions: 3,
+			lastStep:     "Fold in the flour and baking soda, then bake for 65 minutes.",
+			prep:         "15 mins",
+			total:        "1 hr 20 mins",
+			yield:        "8 slices",
+			author:       "Jamie Baker",
+		},
+		{
+			fixture: "tasty-recipes.html",
+			url:     "https://anotherfoodblog.com/weeknight-chicken-curry/",
+			title:   "Weeknight Chicken Curry",
+			image:   "https://anotherfoodblog.com/wp-content/uploads/2023/10/chicken-curry-225x225.jpg",
+			ingredients: []string{
+				"1 ½ lb boneless chicken thighs, cubed",
+				"1 onion, diced",
+				"3 cloves garlic",
+				"2 tbsp curry powder",
+				"1 can (14 oz) coconut milk",
+			},
+			instructions: 3,
+			lastStep:     "Pour in the coconut milk and simmer for 15 minutes.",
+			prep:         "10 minutes",
+			total:        "35 minutes",
+			yield:        "4 servings",
+			author:       "Priya Shah",
+		},
+		{
+			fixture: "food.com.html",
+			url:     "https://www.food.com/recipe/bow-ties-with-roasted-eggplant-and-three-cheeses-86903",
+			title:   "Bow Ties With Roasted Eggplant and Three Cheeses",
+			image:   "https://img.sndimg.com/food/image/upload/q_92,fl_progressive,w_1200,c_scale/v1/img/recipes/86/90/3/picmJ7Gxr.jpg",
+			ingredients: []string{
+				"1 medium eggplant, cut into 1-inch cubes",
+				"3 tablespoons olive oil",
+				"1/2 lb bow tie pasta",
+				"1 cup ricotta cheese",
+				"1/2 cup shredded mozzarella cheese",
+				"1/4 cup grated parmesan cheese",
+			},
+			instructions: 3,
+			lastStep:     "Cook the pasta and toss with the eggplant and cheeses.",
+			prep:         "PT20M",
+			total:        "PT1H",
+			yield:        "6",
+			author:       "Rita1652",
+		},
+		{
+			fixture: "nyt-cooking.html",
+			url:     "https://cooking.nytimes.com/recipes/1020000-lemony-white-bean-soup",
+			title:   "Lemony White Bean Soup",
+			image:   "https://static01.nyt.com/images/2020/01/21/dining/white-bean-soup/white-bean-soup-articleLarge.jpg",
+			ingredients: []string{
+				"¼ cup extra-virgin olive oil",
+				"1 large onion, chopped",
+				"2 (15-ounce) cans cannellini beans, rinsed",
+				"4 cups vegetable broth",
+				"Juice of 1 lemon",
+			},
+			instructions: 3,
+			lastStep:     "Stir in the lemon juice and season to taste.",
+			prep:         "10 minutes",
+			total:        "45 minutes",
+			yield:        "4 servings",
+			author:       "Melissa Clark",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			page, err := os.ReadFile(filepath.Join("testdata", "sites", test.fixture))
+			is.NotError(t, err)
+
+			recipe, err := parsing.ParseRecipe(test.url, page)
+			is.NotError(t, err)
+			is.Equal(t, parsing.SourceSite, recipe.Source)
+			is.Equal(t, test.title, recipe.Title)
+			is.Equal(t, test.image, recipe.ImageURL)
+			is.Equal(t, strings.Join(test.ingredients, "\n"), strings.Join(recipe.Ingredients, "\n"))
+			is.Equal(t, test.instructions, len(recipe.Instructions))
+			is.Equal(t, test.lastStep, recipe.Instructions[len(recipe.Instructions)-1])
+			is.Equal(t, test.prep, recipe.PrepTime)
+			is.Equal(t, test.total, recipe.TotalTime)
+			is.Equal(t, test.yield, recipe.Yield)
+			is.Equal(t, test.author, recipe.Author)
+		})
+	}
+
+	t.Run("plugins are found by fingerprint on any host", func(t *testing.T) {
+		page, err := os.ReadFile(filepath.Join("testdata", "sites", "wprm.html"))
+		is.NotError(t, err)
+		recipe, err := parsing.ParseRecipe("", page)
+		is.NotError(t, err)
+		is.Equal(t, parsing.SourceSite, recipe.Source)
+	})
+
+	t.Run("other pages use the generic parsers", func(t *testing.T) {
+		recipe, err := parsing.ParseRecipe("https://example.com/pancakes", []byte(jsonLDPage))
+		is.NotError(t, err)
+		is.Equal(t, parsing.SourceJSONLD, recipe.Source)
+	})
+}
+
+func TestRegisterSiteExtractor(t *testing.T) {
+	parsing.RegisterSiteExtractor(parsing.SiteExtractor{
+		Name:  "test-site",
+		Hosts: []string{"registered.example"},
+		Extract: func(doc *goquery.Document) (*parsing.ExtractedRecipe, error) {
+			return &parsing.ExtractedRecipe{Title: doc.Find("h1").Text(), Ingredients: []string{"1 egg"}}, nil
+		},
+	})
+
+	recipe, err := parsing.ParseRecipe("https://recipes.registered.example/toast", []byte(plainPage))
+	is.NotError(t, err)
+	is.Equal(t, "Toast", recipe.Title)
+	is.Equal(t, parsing.SourceSite, recipe.Source)
+}
@@ -0,0 +1,79 @@
+package parsing
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// unsafeSnapshotElements run code, load other pages or send the reader
+// somewhere else, none of which a saved copy should do
+var unsafeSnapshotElements = []string{
+	"script", "iframe", "frame", "frameset", "object", "embed", "applet",
+	"base", "portal", "meta[http-equiv]", "meta[charset]", "link[rel=import]", "link[rel=preload]",
+	"link[rel=prefetch]", "link[rel=modulepreload]", "link[rel=manifest]",
+}
+
+// snapshotURLAttributes hold links, which may hide script behind javascript:
+var snapshotURLAttributes = map[string]bool{
+	"href": true, "src": true, "action": true, "formaction": true, "poster": true,
+	"xlink:href": true, "data": true, "background": true, "lowsrc": true,
+}
+
+// SanitizeSnapshot makes a saved page safe to show: scripts, frames, plugins
+// and event handlers are removed, and links point back at the page's site.
+// Links open in a new tab, as the copy is shown in a frame.
+func SanitizeSnapshot(htmlContent []byte, pageURL string) ([]byte, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(htmlContent))
+	if err != nil {
+		return nil, err
+	}
+	for _, selector := range unsafeSnapshotElements {
+		doc.Find(selector).Remove()
+	}
+	doc.Find("*").Each(func(_ int, el *goquery.Selection) {
+		node := el.Get(0)
+		attrs := node.Attr[:0]
+		for _, attr := range node.Attr {
+			key := strings.ToLower(attr.Key)
+			if attr.Namespace != "" {
+				key = attr.Namespace + ":" + key
+			}
+			if strings.HasPrefix(key, "on") || key == "srcdoc" {
+				continue
+			}
+			if snapshotURLAttributes[key] && isScriptURL(attr.Val) {
+				continue
+			}
+			attrs = append(attrs, attr)
+		}
+		node.Attr = attrs
+	})
+
+	base := doc.Find("head").PrependHtml(`<base target="_blank">`).Find("base")
+	if pageURL != "" {
+		base.SetAttr("href", pageURL)
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc.Get(0)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isScriptURL finds links that run code instead of going somewhere. Browsers
+// ignore whitespace and control characters in the scheme, so this does too.
+func isScriptURL(value string) bool {
+	scheme := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(value))
+	return strings.HasPrefix(scheme, "javascript:") ||
+		strings.HasPrefix(scheme, "vbscript:") ||
+		strings.HasPrefix(scheme, "data:text/html")
+}
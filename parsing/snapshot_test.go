@@ -0,0 +1,56 @@
+package parsing_test
+
+import (
+	"strings"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+const snapshotPage = `<!DOCTYPE html>
+<html><head>
+<meta charset="iso-8859-1">
+<meta http-equiv="refresh" content="0; url=https://elsewhere.example/">
+<base href="https://elsewhere.example/">
+<title>Soup</title>
+<link rel="stylesheet" href="/style.css">
+<script>alert(1)</script>
+</head>
+<body onload="alert(2)">
+<h1 onclick="alert(3)">Tomato soup</h1>
+<img src="/soup.jpg" onerror="alert(4)" alt="Soup">
+<a href=" java&#x09;script:alert(5)">Print</a>
+<a href="/more">More soups</a>
+<iframe src="https://ads.example/"></iframe>
+<iframe srcdoc="<script>alert(6)</script>"></iframe>
+<object data="javascript:alert(7)"></object>
+<svg><a xlink:href="javascript:alert(8)"><text>Go</text></a></svg>
+<form action="javascript:alert(9)"><button formaction="javascript:alert(10)">Rate</button></form>
+</body></html>`
+
+func TestSanitizeSnapshot(t *testing.T) {
+	page, err := parsing.SanitizeSnapshot([]byte(snapshotPage), "https://soups.example/tomato")
+	is.NotError(t, err)
+	html := string(page)
+
+	for _, unsafe := range []string{"alert", "<script", "<iframe", "<object", "http-equiv", "charset=", "elsewhere.example", "javascript:"} {
+		t.Run("removes "+unsafe, func(t *testing.T) {
+			is.True(t, !strings.Contains(strings.ToLower(html), unsafe))
+		})
+	}
+
+	for _, kept := range []string{
+		`<base target="_blank" href="https://soups.example/tomato"/>`,
+		`<h1>Tomato soup</h1>`,
+		`<img src="/soup.jpg" alt="Soup"/>`,
+		`<a href="/more">More soups</a>`,
+		`<link rel="stylesheet" href="/style.css"/>`,
+		`<title>Soup</title>`,
+	} {
+		t.Run("keeps "+kept, func(t *testing.T) {
+			is.True(t, strings.Contains(html, kept))
+		})
+	}
+}
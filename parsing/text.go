@@ -0,0 +1,131 @@
+package parsing
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	ingredientsHeadingRe  = regexp.MustCompile(`(?i)^(ingredients?|you('ll)? need|what you('ll)? need|shopping list)\s*:?$`)
+	instructionsHeadingRe = regexp.MustCompile(`(?i)^(instructions?|directions?|method|preparation|steps|how to make it)\s*:?$`)
+	notesHeadingRe        = regexp.MustCompile(`(?i)^(notes?|tips?)\s*:?$`)
+	yieldLineRe           = regexp.MustCompile(`(?i)^(serves|servings|makes|yield)\b:?\s*(.+)$`)
+	bulletRe              = regexp.MustCompile(`^\s*([-*•·–]|\d+[.)]|step \d+:?)\s+`)
+	sectionHeadingRe      = regexp.MustCompile(`^==\s*(.*?)\s*==$`)
+)
+
+// ParseRecipeText reads a recipe written as plain text, such as one sent in an
+// email. Headings like "Ingredients" and "Directions" split the text when it
+// has them, otherwise each line is judged on its own.
+func ParseRecipeText(text string) (*ExtractedRecipe, error) {
+	recipe := &ExtractedRecipe{
+		Ingredients:  []string{},
+		Instructions: []string{},
+		Source:       SourceHeuristic,
+	}
+
+	const (
+		sectionNone = iota
+		sectionIngredients
+		sectionInstructions
+		sectionNotes
+	)
+	section := sectionNone
+	var headings bool
+	var unsorted []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = cleanText(line)
+		switch {
+		case line == "":
+			continue
+		case ingredientsHeadingRe.MatchString(line):
+			section, headings = sectionIngredients, true
+			continue
+		case instructionsHeadingRe.MatchString(line):
+			section, headings = sectionInstructions, true
+			continue
+		case notesHeadingRe.MatchString(line):
+			section = sectionNotes
+			continue
+		}
+		if match := yieldLineRe.FindStringSubmatch(line); match != nil && recipe.Yield == "" {
+			recipe.Yield = match[2]
+			continue
+		}
+		if recipe.Title == "" && section == sectionNone && len(unsorted) == 0 && !looksLikeIngredient(line) {
+			recipe.Title, recipe.Confidence.Title = line, 0.8
+			continue
+		}
+
+		item := strings.TrimSpace(bulletRe.ReplaceAllString(line, ""))
+		switch section {
+		case sectionIngredients:
+			recipe.Ingredients = append(recipe.Ingredients, item)
+		case sectionInstructions:
+			recipe.Instructions = append(recipe.Instructions, item)
+		case sectionNone:
+			unsorted = append(unsorted, item)
+		}
+	}
+
+	// Without headings, lines with amounts are ingredients, as are short lines
+	// like "salt" before the first step, and the rest are steps
+	weight := patternWeight
+	if !headings {
+		weight = unstructuredWeight
+		for _, line := range unsorted {
+			short := len(line) < 40 && !strings.HasSuffix(line, ".")
+			if looksLikeIngredient(line) || (short && len(recipe.Instructions) == 0) {
+				recipe.Ingredients = append(recipe.Ingredients, line)
+			} else {
+				recipe.Instructions = append(recipe.Instructions, line)
+			}
+		}
+	}
+
+	recipe.Ingredients = cleanLines(recipe.Ingredients)
+	recipe.Instructions = cleanLines(recipe.Instructions)
+	recipe.Confidence.Ingredients = ingredientConfidence(recipe.Ingredients, weight)
+	recipe.Confidence.Instructions = instructionConfidence(recipe.Instructions, weight)
+	if len(recipe.Ingredients) == 0 {
+		return recipe, errors.New("failed to find ingredients")
+	}
+	return recipe, nil
+}
+
+// looksLikeIngredient tells if a line is short and starts with an amount or unit
+func looksLikeIngredient(line string) bool {
+	if len(line) > 80 {
+		return false
+	}
+	ingredient := ParseIngredient(strings.TrimSpace(bulletRe.ReplaceAllString(line, "")))
+	return ingredient.Amount != nil || ingredient.Unit != ""
+}
+
+// Section is a run of lines under a heading like "== Frosting ==", which
+// names a part of the recipe the way Cooklang does
+type Section struct {
+	Name  string // Empty for the lines before the first heading
+	Lines []string
+}
+
+// SectionHeading is the line that starts the part of a recipe called name
+func SectionHeading(name string) string {
+	return "== " + strings.Join(strings.Fields(name), " ") + " =="
+}
+
+// SplitSections splits lines at their section headings. Lines before the first
+// heading come in a section with no name, and sections are in the order given.
+func SplitSections(lines []string) []Section {
+	sections := []Section{{}}
+	for _, line := range lines {
+		if match := sectionHeadingRe.FindStringSubmatch(strings.TrimSpace(line)); match != nil && match[1] != "" {
+			sections = append(sections, Section{Name: match[1]})
+			continue
+		}
+		last := &sections[len(sections)-1]
+		last.Lines = append(last.Lines, line)
+	}
+	return sections
+}
@@ -0,0 +1,104 @@
+package parsing_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+const headedRecipeText = `Grandma's Apple Crisp
+Serves 6
+
+Ingredients:
+- 6 apples, sliced
+- 1 cup oats
+- 1/2 cup brown sugar
+- 1/2 cup butter
+
+Directions:
+1. Preheat the oven to 350°F.
+2. Place the apples in a baking dish.
+3. Mix the oats, sugar and butter and sprinkle over the apples.
+4. Bake for 45 minutes.
+
+Notes:
+Great with vanilla ice cream.
+`
+
+func TestParseRecipeText(t *testing.T) {
+	t.Run("splits the text at its headings", func(t *testing.T) {
+		recipe, err := parsing.ParseRecipeText(headedRecipeText)
+		is.NotError(t, err)
+		is.Equal(t, "Grandma's Apple Crisp", recipe.Title)
+		is.Equal(t, "6", recipe.Yield)
+		is.Equal(t, "6 apples, sliced\n1 cup oats\n1/2 cup brown sugar\n1/2 cup butter", strings.Join(recipe.Ingredients, "\n"))
+		is.Equal(t, 4, len(recipe.Instructions))
+		is.Equal(t, "Preheat the oven to 350°F.", recipe.Instructions[0])
+		is.True(t, recipe.Trusted())
+	})
+
+	t.Run("sorts lines without headings by how they read", func(t *testing.T) {
+		recipe, err := parsing.ParseRecipeText("Scrambled eggs\r\n2 eggs\r\n1 tbsp butter\r\nsalt\r\nMelt the butter in a pan over medium heat.\r\nAdd the eggs and stir until just set.\r\n")
+		is.NotError(t, err)
+		is.Equal(t, "Scrambled eggs", recipe.Title)
+		is.Equal(t, "2 eggs\n1 tbsp butter\nsalt", strings.Join(recipe.Ingredients, "\n"))
+		is.Equal(t, 2, len(recipe.Instructions))
+	})
+
+	t.Run("fails when nothing reads like an ingredient", func(t *testing.T) {
+		_, err := parsing.ParseRecipeText("Call me about dinner on Sunday.")
+		is.True(t, err != nil)
+	})
+}
+
+func TestSplitSections(t *testing.T) {
+	t.Run("splits lines at their headings", func(t *testing.T) {
+		sections := parsing.SplitSections([]string{"2 cups flour", parsing.SectionHeading(" Cream  cheese frosting"), "8 oz cream cheese", "== Glaze =="})
+		is.Equal(t, 3, len(sections))
+		is.Equal(t, "", sections[0].Name)
+		is.Equal(t, "2 cups flour", strings.Join(sections[0].Lines, "\n"))
+		is.Equal(t, "Cream cheese frosting", sections[1].Name)
+		is.Equal(t, "8 oz cream cheese", strings.Join(sections[1].Lines, "\n"))
+		is.Equal(t, "Glaze", sections[2].Name)
+		is.Equal(t, 0, len(sections[2].Lines))
+	})
+
+	t.Run("keeps lines that only look a bit like headings", func(t *testing.T) {
+		sections := parsing.SplitSections([]string{"====", "a == b"})
+		is.Equal(t, 1, len(sections))
+		is.Equal(t, 2, len(sections[0].Lines))
+	})
+}
+
+func TestPipeline_ExtractText(t *testing.T) {
+	t.Run("uses clear text without asking the LLM", func(t *testing.T) {
+		fake := &parsing.FakeExtractor{}
+		collection, source, err := parsing.NewPipeline(fake).ExtractText(context.Background(), headedRecipeText)
+		is.NotError(t, err)
+		is.Equal(t, parsing.SourceHeuristic, source)
+		is.Equal(t, 0, len(fake.Prompts()))
+		is.Equal(t, 6, collection.Recipes[0].Servings)
+		is.Equal(t, "oats", collection.Recipes[0].Ingredients[1].Name)
+	})
+
+	t.Run("asks the LLM about unclear text", func(t *testing.T) {
+		fake := &parsing.FakeExtractor{Responses: []string{`{"recipes":[{"name":"Aunt May's stew","ingredients":[{"name":"beef","amount":2,"unit":"lb"}]}]}`}}
+		text := "Aunt May's stew. Brown two pounds of beef, then add whatever vegetables are around and let it go all afternoon."
+		collection, source, err := parsing.NewPipeline(fake).ExtractText(context.Background(), text)
+		is.NotError(t, err)
+		is.Equal(t, parsing.SourceLLM, source)
+		is.Equal(t, 1, len(fake.Prompts()))
+		is.True(t, strings.Contains(fake.Prompts()[0], "whatever vegetables"))
+		is.Equal(t, "beef", collection.Recipes[0].Ingredients[0].Name)
+	})
+
+	t.Run("reports unclear text when there is no LLM", func(t *testing.T) {
+		_, _, err := parsing.NewPipeline(nil).ExtractText(context.Background(), "Call me about dinner on Sunday.")
+		is.True(t, errors.Is(err, parsing.ErrNoExtractor))
+	})
+}
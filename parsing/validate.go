@@ -0,0 +1,99 @@
+package parsing
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+//go:embed recipeschema.json
+var recipeSchemaJSON string
+
+var recipeSchema = jsonschema.MustCompileString("recipeschema.json", recipeSchemaJSON)
+
+// ErrInvalidRecipeData is returned when LLM output cannot be turned into a valid RecipeCollection
+var ErrInvalidRecipeData = errors.New("invalid recipe data")
+
+// maxRepairAttempts bounds how often the LLM is asked to fix its own output
+const maxRepairAttempts = 2
+
+// CleanLLMOutput removes the wrapping models like to add around JSON, such as
+// markdown code fences or a sentence before the object.
+func CleanLLMOutput(output string) string {
+	output = strings.TrimSpace(output)
+	if fenced, ok := strings.CutPrefix(output, "```"); ok {
+		// Drop the language tag on the opening fence
+		if _, rest, found := strings.Cut(fenced, "\n"); found {
+			fenced = rest
+		}
+		output = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(fenced), "```"))
+	}
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return output
+	}
+	return output[start : end+1]
+}
+
+// ValidateRecipeJSON checks data against recipeschema.json and decodes it.
+// The error lists every schema violation so it can be sent back to the LLM.
+func ValidateRecipeJSON(data []byte) (*RecipeCollection, error) {
+	var doc any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: not valid JSON: %v", ErrInvalidRecipeData, err)
+	}
+
+	if err := recipeSchema.Validate(doc); err != nil {
+		var ve *jsonschema.ValidationError
+		if errors.As(err, &ve) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRecipeData, strings.Join(schemaViolations(ve), "; "))
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecipeData, err)
+	}
+
+	var collection RecipeCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecipeData, err)
+	}
+	if len(collection.Recipes) == 0 {
+		return nil, fmt.Errorf("%w: no recipes found", ErrInvalidRecipeData)
+	}
+	return &collection, nil
+}
+
+// schemaViolations flattens a validation error into readable messages
+func schemaViolations(ve *jsonschema.ValidationError) []string {
+	if len(ve.Causes) == 0 {
+		location := ve.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		return []string{location + ": " + ve.Message}
+	}
+	var violations []string
+	for _, cause := range ve.Causes {
+		violations = append(violations, schemaViolations(cause)...)
+	}
+	return violations
+}
+
+// repairPrompt asks the LLM to fix output that failed validation
+func repairPrompt(prompt, output string, validationErr error) string {
+	return fmt.Sprintf(`%s
+
+Your previous answer could not be used:
+%s
+
+It was rejected because: %v
+
+Return ONLY the corrected JSON that follows the schema, with no code fences or explanation.
+`, prompt, output, validationErr)
+}
@@ -0,0 +1,63 @@
+package parsing_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+)
+
+const validRecipeJSON = `{"recipes":[{"name":"Toast","ingredients":[{"name":"bread","amount":2,"unit":"slice"}]}]}`
+
+func TestCleanLLMOutput(t *testing.T) {
+	t.Run("removes markdown code fences", func(t *testing.T) {
+		is.Equal(t, validRecipeJSON, parsing.CleanLLMOutput("```json\n"+validRecipeJSON+"\n```"))
+	})
+
+	t.Run("removes text around the object", func(t *testing.T) {
+		is.Equal(t, validRecipeJSON, parsing.CleanLLMOutput("Here is the recipe:\n"+validRecipeJSON+"\nEnjoy!"))
+	})
+}
+
+func TestValidateRecipeJSON(t *testing.T) {
+	t.Run("accepts data following the schema", func(t *testing.T) {
+		collection, err := parsing.ValidateRecipeJSON([]byte(validRecipeJSON))
+		is.NotError(t, err)
+		is.Equal(t, "bread", collection.Recipes[0].Ingredients[0].Name)
+	})
+
+	t.Run("lists schema violations", func(t *testing.T) {
+		_, err := parsing.ValidateRecipeJSON([]byte(`{"recipes":[{"name":"Toast","ingredients":[{"amount":"two"}]}]}`))
+		is.True(t, errors.Is(err, parsing.ErrInvalidRecipeData))
+		is.True(t, strings.Contains(err.Error(), "/recipes/0/ingredients/0"))
+	})
+
+	t.Run("rejects broken JSON", func(t *testing.T) {
+		_, err := parsing.ValidateRecipeJSON([]byte(`{"recipes":[`))
+		is.True(t, errors.Is(err, parsing.ErrInvalidRecipeData))
+	})
+}
+
+func TestRecipeTextToCollection(t *testing.T) {
+	t.Run("re-prompts with the validation errors", func(t *testing.T) {
+		fake := &parsing.FakeExtractor{Responses: []string{`{"recipes":[{"name":"Toast"}]}`, "```json\n" + validRecipeJSON + "\n```"}}
+		collection, err := parsing.RecipeTextToCollection(context.Background(), fake, []byte("Toast"))
+		is.NotError(t, err)
+		is.Equal(t, "Toast", collection.Recipes[0].Name)
+
+		prompts := fake.Prompts()
+		is.Equal(t, 2, len(prompts))
+		is.True(t, strings.Contains(prompts[1], "missing properties: 'ingredients'"))
+	})
+
+	t.Run("gives up after a bounded number of attempts", func(t *testing.T) {
+		fake := &parsing.FakeExtractor{Responses: []string{"I could not find a recipe."}}
+		_, err := parsing.RecipeTextToCollection(context.Background(), fake, []byte("nothing"))
+		is.True(t, errors.Is(err, parsing.ErrInvalidRecipeData))
+		is.Equal(t, 3, len(fake.Prompts()))
+	})
+}
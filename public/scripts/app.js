@@ -0,0 +1,117 @@
+// Keeps an open recipe page up to date with changes made elsewhere, by other
+// members of the group or by recipes being read in the background. The server
+// streams the group's recipe events, and the parts of the page they affect are
+// swapped with htmx.
+(function () {
+  "use strict";
+
+  // One change often sends a few events, so wait a moment and refresh once
+  const settleDelay = 300;
+  const timers = new Map();
+
+  function later(key, fn) {
+    clearTimeout(timers.get(key));
+    timers.set(key, setTimeout(function () {
+      timers.delete(key);
+      fn();
+    }, settleDelay));
+  }
+
+  // selectedID is the recipe highlighted in the list
+  function selectedID() {
+    const active = document.querySelector("#recipe-list .active-recipe");
+    return active ? active.id.replace("recipe-list-item-", "") : "";
+  }
+
+  // shownID is the recipe in the detail view, unless it is being edited
+  function shownID() {
+    const shown = document.querySelector("#recipe-detail [data-recipe-id]");
+    return shown ? shown.dataset.recipeId : "";
+  }
+
+  function listItem(id) {
+    const button = document.getElementById("recipe-list-item-" + id);
+    return button ? button.closest("li") : null;
+  }
+
+  function refreshList(groupID) {
+    const params = new URLSearchParams({ selected: selectedID() });
+    const filter = document.querySelector('select[name="max_time"]');
+    if (filter && filter.value !== "") {
+      params.set("max_time", filter.value);
+    }
+    htmx.ajax("GET", "/g/" + groupID + "/recipes?" + params, { target: "#recipe-list" });
+  }
+
+  function refreshItem(groupID, id) {
+    const item = listItem(id);
+    if (!item) {
+      return;
+    }
+    const selected = id === selectedID();
+    htmx.ajax("GET", "/g/" + groupID + "/recipe/" + id + "/item?selected=" + selected, { target: item, swap: "outerHTML" });
+  }
+
+  // refreshDetail also brings the recipe's list entry along
+  function refreshDetail(groupID, id) {
+    htmx.ajax("GET", "/g/" + groupID + "/recipe/" + id, { target: "#recipe-detail" });
+  }
+
+  function recipeID(event) {
+    return String(JSON.parse(event.data).recipe_id);
+  }
+
+  function connect(root) {
+    const groupID = root.dataset.group;
+    const source = new EventSource(root.dataset.events);
+    let connected = false;
+
+    source.addEventListener("open", function () {
+      // Events sent while the stream was reconnecting were missed, catch up
+      if (connected) {
+        later("list", function () { refreshList(groupID); });
+        const shown = shownID();
+        if (shown) {
+          later("recipe-" + shown, function () { refreshDetail(groupID, shown); });
+        }
+      }
+      connected = true;
+    });
+
+    source.addEventListener("recipe-created", function () {
+      later("list", function () { refreshList(groupID); });
+    });
+
+    source.addEventListener("recipe-updated", function (event) {
+      const id = recipeID(event);
+      later("recipe-" + id, function () {
+        if (shownID() === id) {
+          refreshDetail(groupID, id);
+        } else {
+          refreshItem(groupID, id);
+        }
+      });
+    });
+
+    source.addEventListener("recipe-deleted", function (event) {
+      const id = recipeID(event);
+      const item = listItem(id);
+      if (item) {
+        item.remove();
+      }
+      if (shownID() === id) {
+        const note = document.createElement("p");
+        note.className = "text-sm text-gray-500 italic";
+        note.textContent = "This recipe was deleted.";
+        document.getElementById("recipe-detail").replaceChildren(note);
+      }
+    });
+  }
+
+  document.addEventListener("DOMContentLoaded", function () {
+    const root = document.querySelector("[data-events]");
+    if (root && window.EventSource) {
+      connect(root);
+    }
+  });
+})();
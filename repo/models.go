@@ -8,6 +8,16 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ExtractionCache struct {
+	Key           string
+	Model         string
+	PromptVersion int32
+	DataJson      []byte
+	Hits          int32
+	CreatedAt     pgtype.Timestamptz
+	ExpiresAt     pgtype.Timestamptz
+}
+
 type Group struct {
 	ID        int32
 	Name      pgtype.Text
@@ -18,6 +28,36 @@ type GroupUser struct {
 	ID      int32
 	GroupID int32
 	UserID  int32
+	IsAdmin bool
+}
+
+type Job struct {
+	ID          int64
+	Kind        string
+	DedupeKey   pgtype.Text
+	Payload     []byte
+	State       string
+	Attempts    int32
+	MaxAttempts int32
+	RunAt       pgtype.Timestamptz
+	LeasedUntil pgtype.Timestamptz
+	LastError   pgtype.Text
+	CreatedAt   pgtype.Timestamptz
+	FinishedAt  pgtype.Timestamptz
+}
+
+type LlmUsage struct {
+	ID           int64
+	GroupID      pgtype.Int4
+	UserID       pgtype.Int4
+	RecipeID     pgtype.Int4
+	Model        string
+	InputTokens  int32
+	OutputTokens int32
+	LatencyMs    int32
+	CostMicros   int64
+	Succeeded    bool
+	CreatedAt    pgtype.Timestamptz
 }
 
 type LoginToken struct {
@@ -31,15 +71,47 @@ type LoginToken struct {
 }
 
 type Recipe struct {
+	ID               int32
+	CreatedBy        int32
+	GroupID          int32
+	Url              pgtype.Text
+	Name             pgtype.Text
+	Description      pgtype.Text
+	DataJson         []byte
+	ImageUrl         pgtype.Text
+	Likes            pgtype.Int4
+	CreatedAt        pgtype.Timestamptz
+	DataSource       pgtype.Text
+	PrepTimeSeconds  pgtype.Int4
+	CookTimeSeconds  pgtype.Int4
+	TotalTimeSeconds pgtype.Int4
+	ExtractionStatus string
+	ExtractionError  pgtype.Text
+	ImageKey         pgtype.Text
+	ThumbnailKey     pgtype.Text
+	ParserVersion    int32
+	PromptVersion    int32
+}
+
+type RecipeDataHistory struct {
+	ID               int32
+	RecipeID         int32
+	DataJson         []byte
+	DataSource       pgtype.Text
+	PrepTimeSeconds  pgtype.Int4
+	CookTimeSeconds  pgtype.Int4
+	TotalTimeSeconds pgtype.Int4
+	ParserVersion    int32
+	PromptVersion    int32
+	ReplacedAt       pgtype.Timestamptz
+}
+
+type RecipeSnapshot struct {
 	ID          int32
-	CreatedBy   int32
-	GroupID     int32
-	Url         pgtype.Text
-	Name        pgtype.Text
-	Description pgtype.Text
-	DataJson    []byte
-	ImageUrl    pgtype.Text
-	Likes       pgtype.Int4
+	RecipeID    int32
+	PageUrl     string
+	ContentHash string
+	HtmlSize    int32
 	CreatedAt   pgtype.Timestamptz
 }
 
@@ -60,4 +132,5 @@ type User struct {
 	ImageUrl     pgtype.Text
 	SetupAccount pgtype.Bool
 	CreatedAt    pgtype.Timestamptz
+	UnitSystem   string
 }
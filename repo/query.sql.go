@@ -54,7 +54,7 @@ INSERT INTO users (
 ) VALUES (
     $1
 )
-RETURNING id, email, name, image_url, setup_account, created_at
+RETURNING id, email, name, image_url, setup_account, created_at, unit_system
 `
 
 func (q *Queries) AddUser(ctx context.Context, email string) (User, error) {
@@ -67,6 +67,7 @@ func (q *Queries) AddUser(ctx context.Context, email string) (User, error) {
 		&i.ImageUrl,
 		&i.SetupAccount,
 		&i.CreatedAt,
+		&i.UnitSystem,
 	)
 	return i, err
 }
@@ -74,22 +75,169 @@ func (q *Queries) AddUser(ctx context.Context, email string) (User, error) {
 const addUserToGroup = `-- name: AddUserToGroup :exec
 INSERT INTO group_users (
     group_id,
-    user_id
+    user_id,
+    is_admin
 ) VALUES (
-    $1, $2
+    $1, $2, $3
 )
 `
 
 type AddUserToGroupParams struct {
 	GroupID int32
 	UserID  int32
+	IsAdmin bool
 }
 
 func (q *Queries) AddUserToGroup(ctx context.Context, arg AddUserToGroupParams) error {
-	_, err := q.db.Exec(ctx, addUserToGroup, arg.GroupID, arg.UserID)
+	_, err := q.db.Exec(ctx, addUserToGroup, arg.GroupID, arg.UserID, arg.IsAdmin)
 	return err
 }
 
+const buryAbandonedJobs = `-- name: BuryAbandonedJobs :execrows
+UPDATE jobs
+SET
+    state = 'dead',
+    leased_until = NULL,
+    last_error = 'the worker stopped during the last attempt',
+    finished_at = now()
+WHERE state = 'running' AND leased_until < now() AND attempts >= max_attempts
+`
+
+func (q *Queries) BuryAbandonedJobs(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, buryAbandonedJobs)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const buryJob = `-- name: BuryJob :execrows
+UPDATE jobs
+SET
+    state = 'dead',
+    leased_until = NULL,
+    last_error = $1,
+    finished_at = now()
+WHERE id = $2 AND state = 'running' AND leased_until = $3
+`
+
+type BuryJobParams struct {
+	LastError   pgtype.Text
+	ID          int64
+	LeasedUntil pgtype.Timestamptz
+}
+
+func (q *Queries) BuryJob(ctx context.Context, arg BuryJobParams) (int64, error) {
+	result, err := q.db.Exec(ctx, buryJob, arg.LastError, arg.ID, arg.LeasedUntil)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const cacheExtraction = `-- name: CacheExtraction :exec
+INSERT INTO extraction_cache (
+    key,
+    model,
+    prompt_version,
+    data_json,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (key) DO UPDATE SET
+    data_json = EXCLUDED.data_json,
+    hits = 0,
+    created_at = CURRENT_TIMESTAMP,
+    expires_at = EXCLUDED.expires_at
+`
+
+type CacheExtractionParams struct {
+	Key           string
+	Model         string
+	PromptVersion int32
+	DataJson      []byte
+	ExpiresAt     pgtype.Timestamptz
+}
+
+func (q *Queries) CacheExtraction(ctx context.Context, arg CacheExtractionParams) error {
+	_, err := q.db.Exec(ctx, cacheExtraction,
+		arg.Key,
+		arg.Model,
+		arg.PromptVersion,
+		arg.DataJson,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const claimJob = `-- name: ClaimJob :one
+UPDATE jobs
+SET
+    state = 'running',
+    attempts = attempts + 1,
+    leased_until = now() + make_interval(secs => $1::int)
+WHERE id = (
+    SELECT id FROM jobs
+    WHERE kind = ANY($2::text[])
+    AND (
+        (state = 'queued' AND run_at <= now())
+        OR (state = 'running' AND leased_until < now() AND attempts < max_attempts)
+    )
+    ORDER BY run_at, id
+    FOR UPDATE SKIP LOCKED
+    LIMIT 1
+)
+RETURNING id, kind, dedupe_key, payload, state, attempts, max_attempts, run_at, leased_until, last_error, created_at, finished_at
+`
+
+type ClaimJobParams struct {
+	LeaseSeconds int32
+	Kinds        []string
+}
+
+func (q *Queries) ClaimJob(ctx context.Context, arg ClaimJobParams) (Job, error) {
+	row := q.db.QueryRow(ctx, claimJob, arg.LeaseSeconds, arg.Kinds)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.DedupeKey,
+		&i.Payload,
+		&i.State,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.RunAt,
+		&i.LeasedUntil,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const completeJob = `-- name: CompleteJob :execrows
+UPDATE jobs
+SET
+    state = 'done',
+    leased_until = NULL,
+    finished_at = now()
+WHERE id = $1 AND state = 'running' AND leased_until = $2
+`
+
+type CompleteJobParams struct {
+	ID          int64
+	LeasedUntil pgtype.Timestamptz
+}
+
+func (q *Queries) CompleteJob(ctx context.Context, arg CompleteJobParams) (int64, error) {
+	result, err := q.db.Exec(ctx, completeJob, arg.ID, arg.LeasedUntil)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const consumeRegistrationToken = `-- name: ConsumeRegistrationToken :exec
 UPDATE registration_tokens
 SET
@@ -155,6 +303,34 @@ func (q *Queries) CreateLoginToken(ctx context.Context, arg CreateLoginTokenPara
 	return i, err
 }
 
+const createRecipeSnapshot = `-- name: CreateRecipeSnapshot :exec
+INSERT INTO recipe_snapshots (
+    recipe_id,
+    page_url,
+    content_hash,
+    html_size
+) VALUES (
+    $1, $2, $3, $4
+)
+`
+
+type CreateRecipeSnapshotParams struct {
+	RecipeID    int32
+	PageUrl     string
+	ContentHash string
+	HtmlSize    int32
+}
+
+func (q *Queries) CreateRecipeSnapshot(ctx context.Context, arg CreateRecipeSnapshotParams) error {
+	_, err := q.db.Exec(ctx, createRecipeSnapshot,
+		arg.RecipeID,
+		arg.PageUrl,
+		arg.ContentHash,
+		arg.HtmlSize,
+	)
+	return err
+}
+
 const createRegistrationToken = `-- name: CreateRegistrationToken :exec
 INSERT INTO registration_tokens (
     token,
@@ -174,6 +350,61 @@ func (q *Queries) CreateRegistrationToken(ctx context.Context, arg CreateRegistr
 	return err
 }
 
+const delayJob = `-- name: DelayJob :execrows
+UPDATE jobs
+SET
+    state = 'queued',
+    attempts = GREATEST(attempts - 1, 0),
+    leased_until = NULL,
+    last_error = $1,
+    run_at = $2
+WHERE id = $3 AND state = 'running' AND leased_until = $4
+`
+
+type DelayJobParams struct {
+	LastError   pgtype.Text
+	RunAt       pgtype.Timestamptz
+	ID          int64
+	LeasedUntil pgtype.Timestamptz
+}
+
+func (q *Queries) DelayJob(ctx context.Context, arg DelayJobParams) (int64, error) {
+	result, err := q.db.Exec(ctx, delayJob,
+		arg.LastError,
+		arg.RunAt,
+		arg.ID,
+		arg.LeasedUntil,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteAllExtractions = `-- name: DeleteAllExtractions :execrows
+DELETE FROM extraction_cache
+`
+
+func (q *Queries) DeleteAllExtractions(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAllExtractions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredExtractions = `-- name: DeleteExpiredExtractions :execrows
+DELETE FROM extraction_cache WHERE expires_at <= now()
+`
+
+func (q *Queries) DeleteExpiredExtractions(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredExtractions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteRecipeByID = `-- name: DeleteRecipeByID :exec
 DELETE FROM recipes where id = $1
 `
@@ -183,8 +414,176 @@ func (q *Queries) DeleteRecipeByID(ctx context.Context, id int32) error {
 	return err
 }
 
+const deleteRecipeDataHistory = `-- name: DeleteRecipeDataHistory :exec
+DELETE FROM recipe_data_history WHERE id = $1
+`
+
+func (q *Queries) DeleteRecipeDataHistory(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteRecipeDataHistory, id)
+	return err
+}
+
+const enqueueJob = `-- name: EnqueueJob :exec
+INSERT INTO jobs (
+    kind,
+    dedupe_key,
+    payload,
+    max_attempts
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (dedupe_key) WHERE state IN ('queued', 'running') DO NOTHING
+`
+
+type EnqueueJobParams struct {
+	Kind        string
+	DedupeKey   pgtype.Text
+	Payload     []byte
+	MaxAttempts int32
+}
+
+func (q *Queries) EnqueueJob(ctx context.Context, arg EnqueueJobParams) error {
+	_, err := q.db.Exec(ctx, enqueueJob,
+		arg.Kind,
+		arg.DedupeKey,
+		arg.Payload,
+		arg.MaxAttempts,
+	)
+	return err
+}
+
+const extendJobLease = `-- name: ExtendJobLease :one
+UPDATE jobs
+SET leased_until = now() + make_interval(secs => $1::int)
+WHERE id = $2 AND state = 'running' AND leased_until = $3
+RETURNING leased_until
+`
+
+type ExtendJobLeaseParams struct {
+	LeaseSeconds int32
+	ID           int64
+	LeasedUntil  pgtype.Timestamptz
+}
+
+func (q *Queries) ExtendJobLease(ctx context.Context, arg ExtendJobLeaseParams) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, extendJobLease, arg.LeaseSeconds, arg.ID, arg.LeasedUntil)
+	var leased_until pgtype.Timestamptz
+	err := row.Scan(&leased_until)
+	return leased_until, err
+}
+
+const getCachedExtraction = `-- name: GetCachedExtraction :one
+UPDATE extraction_cache
+SET hits = hits + 1
+WHERE key = $1 AND expires_at > now()
+RETURNING data_json
+`
+
+func (q *Queries) GetCachedExtraction(ctx context.Context, key string) ([]byte, error) {
+	row := q.db.QueryRow(ctx, getCachedExtraction, key)
+	var data_json []byte
+	err := row.Scan(&data_json)
+	return data_json, err
+}
+
+const getGroupLLMUsageByUser = `-- name: GetGroupLLMUsageByUser :many
+SELECT
+    l.user_id,
+    COALESCE(u.name, u.email, '')::TEXT AS user_name,
+    COUNT(*)::INT AS calls,
+    COALESCE(SUM(l.input_tokens), 0)::BIGINT AS input_tokens,
+    COALESCE(SUM(l.output_tokens), 0)::BIGINT AS output_tokens,
+    COALESCE(SUM(l.cost_micros), 0)::BIGINT AS cost_micros
+FROM llm_usage l
+LEFT JOIN users u ON u.id = l.user_id
+WHERE l.group_id = $1 AND l.created_at >= $2
+GROUP BY l.user_id, u.name, u.email
+ORDER BY cost_micros DESC
+`
+
+type GetGroupLLMUsageByUserParams struct {
+	GroupID   pgtype.Int4
+	CreatedAt pgtype.Timestamptz
+}
+
+type GetGroupLLMUsageByUserRow struct {
+	UserID       pgtype.Int4
+	UserName     string
+	Calls        int32
+	InputTokens  int64
+	OutputTokens int64
+	CostMicros   int64
+}
+
+func (q *Queries) GetGroupLLMUsageByUser(ctx context.Context, arg GetGroupLLMUsageByUserParams) ([]GetGroupLLMUsageByUserRow, error) {
+	rows, err := q.db.Query(ctx, getGroupLLMUsageByUser, arg.GroupID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetGroupLLMUsageByUserRow
+	for rows.Next() {
+		var i GetGroupLLMUsageByUserRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.UserName,
+			&i.Calls,
+			&i.InputTokens,
+			&i.OutputTokens,
+			&i.CostMicros,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGroupMembers = `-- name: GetGroupMembers :many
+SELECT u.id, u.name, u.email, gu.is_admin
+FROM users u
+JOIN group_users gu ON u.id = gu.user_id
+WHERE gu.group_id = $1
+ORDER BY gu.id
+`
+
+type GetGroupMembersRow struct {
+	ID      int32
+	Name    pgtype.Text
+	Email   string
+	IsAdmin bool
+}
+
+func (q *Queries) GetGroupMembers(ctx context.Context, groupID int32) ([]GetGroupMembersRow, error) {
+	rows, err := q.db.Query(ctx, getGroupMembers, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetGroupMembersRow
+	for rows.Next() {
+		var i GetGroupMembersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.IsAdmin,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getGroupRecipes = `-- name: GetGroupRecipes :many
-SELECT id, created_by, group_id, url, name, description, data_json, image_url, likes, created_at FROM recipes where group_id = $1
+SELECT id, created_by, group_id, url, name, description, data_json, image_url, likes, created_at, data_source, prep_time_seconds, cook_time_seconds, total_time_seconds, extraction_status, extraction_error, image_key, thumbnail_key, parser_version, prompt_version FROM recipes where group_id = $1
 `
 
 func (q *Queries) GetGroupRecipes(ctx context.Context, groupID int32) ([]Recipe, error) {
@@ -207,6 +606,69 @@ func (q *Queries) GetGroupRecipes(ctx context.Context, groupID int32) ([]Recipe,
 			&i.ImageUrl,
 			&i.Likes,
 			&i.CreatedAt,
+			&i.DataSource,
+			&i.PrepTimeSeconds,
+			&i.CookTimeSeconds,
+			&i.TotalTimeSeconds,
+			&i.ExtractionStatus,
+			&i.ExtractionError,
+			&i.ImageKey,
+			&i.ThumbnailKey,
+			&i.ParserVersion,
+			&i.PromptVersion,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGroupRecipesByTotalTime = `-- name: GetGroupRecipesByTotalTime :many
+SELECT id, created_by, group_id, url, name, description, data_json, image_url, likes, created_at, data_source, prep_time_seconds, cook_time_seconds, total_time_seconds, extraction_status, extraction_error, image_key, thumbnail_key, parser_version, prompt_version FROM recipes
+WHERE group_id = $1
+AND ($2::int = 0 OR total_time_seconds <= $2::int)
+ORDER BY total_time_seconds ASC NULLS LAST, id
+`
+
+type GetGroupRecipesByTotalTimeParams struct {
+	GroupID    int32
+	MaxSeconds int32
+}
+
+func (q *Queries) GetGroupRecipesByTotalTime(ctx context.Context, arg GetGroupRecipesByTotalTimeParams) ([]Recipe, error) {
+	rows, err := q.db.Query(ctx, getGroupRecipesByTotalTime, arg.GroupID, arg.MaxSeconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Recipe
+	for rows.Next() {
+		var i Recipe
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedBy,
+			&i.GroupID,
+			&i.Url,
+			&i.Name,
+			&i.Description,
+			&i.DataJson,
+			&i.ImageUrl,
+			&i.Likes,
+			&i.CreatedAt,
+			&i.DataSource,
+			&i.PrepTimeSeconds,
+			&i.CookTimeSeconds,
+			&i.TotalTimeSeconds,
+			&i.ExtractionStatus,
+			&i.ExtractionError,
+			&i.ImageKey,
+			&i.ThumbnailKey,
+			&i.ParserVersion,
+			&i.PromptVersion,
 		); err != nil {
 			return nil, err
 		}
@@ -219,7 +681,7 @@ func (q *Queries) GetGroupRecipes(ctx context.Context, groupID int32) ([]Recipe,
 }
 
 const getGroupUsers = `-- name: GetGroupUsers :many
-SELECT u.id, u.email, u.name, u.image_url, u.setup_account, u.created_at 
+SELECT u.id, u.email, u.name, u.image_url, u.setup_account, u.created_at, u.unit_system 
 FROM users u
 JOIN group_users gu ON u.id = gu.user_id
 WHERE gu.group_id = $1
@@ -241,6 +703,7 @@ func (q *Queries) GetGroupUsers(ctx context.Context, groupID int32) ([]User, err
 			&i.ImageUrl,
 			&i.SetupAccount,
 			&i.CreatedAt,
+			&i.UnitSystem,
 		); err != nil {
 			return nil, err
 		}
@@ -252,6 +715,94 @@ func (q *Queries) GetGroupUsers(ctx context.Context, groupID int32) ([]User, err
 	return items, nil
 }
 
+const getLLMSpend = `-- name: GetLLMSpend :one
+SELECT
+    COALESCE(SUM(cost_micros) FILTER (WHERE group_id = $1 AND created_at >= $2), 0)::BIGINT AS group_day,
+    COALESCE(SUM(cost_micros) FILTER (WHERE group_id = $1), 0)::BIGINT AS group_month,
+    COALESCE(SUM(cost_micros) FILTER (WHERE user_id = $3 AND created_at >= $2), 0)::BIGINT AS user_day,
+    COALESCE(SUM(cost_micros) FILTER (WHERE user_id = $3), 0)::BIGINT AS user_month
+FROM llm_usage
+WHERE created_at >= $4
+AND (group_id = $1 OR user_id = $3)
+`
+
+type GetLLMSpendParams struct {
+	GroupID    pgtype.Int4
+	DayStart   pgtype.Timestamptz
+	UserID     pgtype.Int4
+	MonthStart pgtype.Timestamptz
+}
+
+type GetLLMSpendRow struct {
+	GroupDay   int64
+	GroupMonth int64
+	UserDay    int64
+	UserMonth  int64
+}
+
+func (q *Queries) GetLLMSpend(ctx context.Context, arg GetLLMSpendParams) (GetLLMSpendRow, error) {
+	row := q.db.QueryRow(ctx, getLLMSpend,
+		arg.GroupID,
+		arg.DayStart,
+		arg.UserID,
+		arg.MonthStart,
+	)
+	var i GetLLMSpendRow
+	err := row.Scan(
+		&i.GroupDay,
+		&i.GroupMonth,
+		&i.UserDay,
+		&i.UserMonth,
+	)
+	return i, err
+}
+
+const getLatestRecipeDataHistory = `-- name: GetLatestRecipeDataHistory :one
+SELECT id, recipe_id, data_json, data_source, prep_time_seconds, cook_time_seconds, total_time_seconds, parser_version, prompt_version, replaced_at FROM recipe_data_history
+WHERE recipe_id = $1
+ORDER BY replaced_at DESC, id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestRecipeDataHistory(ctx context.Context, recipeID int32) (RecipeDataHistory, error) {
+	row := q.db.QueryRow(ctx, getLatestRecipeDataHistory, recipeID)
+	var i RecipeDataHistory
+	err := row.Scan(
+		&i.ID,
+		&i.RecipeID,
+		&i.DataJson,
+		&i.DataSource,
+		&i.PrepTimeSeconds,
+		&i.CookTimeSeconds,
+		&i.TotalTimeSeconds,
+		&i.ParserVersion,
+		&i.PromptVersion,
+		&i.ReplacedAt,
+	)
+	return i, err
+}
+
+const getLatestRecipeSnapshot = `-- name: GetLatestRecipeSnapshot :one
+SELECT id, recipe_id, page_url, content_hash, html_size, created_at FROM recipe_snapshots
+WHERE recipe_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestRecipeSnapshot(ctx context.Context, recipeID int32) (RecipeSnapshot, error) {
+	row := q.db.QueryRow(ctx, getLatestRecipeSnapshot, recipeID)
+	var i RecipeSnapshot
+	err := row.Scan(
+		&i.ID,
+		&i.RecipeID,
+		&i.PageUrl,
+		&i.ContentHash,
+		&i.HtmlSize,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getLoginToken = `-- name: GetLoginToken :one
 SELECT id, user_id, token, consumed_at, created_at, expires_at, creator_ip FROM login_tokens WHERE token = $1 LIMIT 1
 `
@@ -271,8 +822,80 @@ func (q *Queries) GetLoginToken(ctx context.Context, token string) (LoginToken,
 	return i, err
 }
 
+const getRecentGroupLLMUsage = `-- name: GetRecentGroupLLMUsage :many
+SELECT
+    l.id,
+    l.model,
+    l.input_tokens,
+    l.output_tokens,
+    l.latency_ms,
+    l.cost_micros,
+    l.succeeded,
+    l.created_at,
+    l.recipe_id,
+    COALESCE(r.name, '')::TEXT AS recipe_name,
+    COALESCE(u.name, u.email, '')::TEXT AS user_name
+FROM llm_usage l
+LEFT JOIN recipes r ON r.id = l.recipe_id
+LEFT JOIN users u ON u.id = l.user_id
+WHERE l.group_id = $1
+ORDER BY l.created_at DESC, l.id DESC
+LIMIT $2
+`
+
+type GetRecentGroupLLMUsageParams struct {
+	GroupID pgtype.Int4
+	Limit   int32
+}
+
+type GetRecentGroupLLMUsageRow struct {
+	ID           int64
+	Model        string
+	InputTokens  int32
+	OutputTokens int32
+	LatencyMs    int32
+	CostMicros   int64
+	Succeeded    bool
+	CreatedAt    pgtype.Timestamptz
+	RecipeID     pgtype.Int4
+	RecipeName   string
+	UserName     string
+}
+
+func (q *Queries) GetRecentGroupLLMUsage(ctx context.Context, arg GetRecentGroupLLMUsageParams) ([]GetRecentGroupLLMUsageRow, error) {
+	rows, err := q.db.Query(ctx, getRecentGroupLLMUsage, arg.GroupID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentGroupLLMUsageRow
+	for rows.Next() {
+		var i GetRecentGroupLLMUsageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Model,
+			&i.InputTokens,
+			&i.OutputTokens,
+			&i.LatencyMs,
+			&i.CostMicros,
+			&i.Succeeded,
+			&i.CreatedAt,
+			&i.RecipeID,
+			&i.RecipeName,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getRecipeByID = `-- name: GetRecipeByID :one
-SELECT id, created_by, group_id, url, name, description, data_json, image_url, likes, created_at from recipes WHERE id = $1 LIMIT 1
+SELECT id, created_by, group_id, url, name, description, data_json, image_url, likes, created_at, data_source, prep_time_seconds, cook_time_seconds, total_time_seconds, extraction_status, extraction_error, image_key, thumbnail_key, parser_version, prompt_version from recipes WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetRecipeByID(ctx context.Context, id int32) (Recipe, error) {
@@ -289,10 +912,122 @@ func (q *Queries) GetRecipeByID(ctx context.Context, id int32) (Recipe, error) {
 		&i.ImageUrl,
 		&i.Likes,
 		&i.CreatedAt,
+		&i.DataSource,
+		&i.PrepTimeSeconds,
+		&i.CookTimeSeconds,
+		&i.TotalTimeSeconds,
+		&i.ExtractionStatus,
+		&i.ExtractionError,
+		&i.ImageKey,
+		&i.ThumbnailKey,
+		&i.ParserVersion,
+		&i.PromptVersion,
 	)
 	return i, err
 }
 
+const getRecipesToReprocess = `-- name: GetRecipesToReprocess :many
+SELECT id, created_by, group_id, url, name, description, data_json, image_url, likes, created_at, data_source, prep_time_seconds, cook_time_seconds, total_time_seconds, extraction_status, extraction_error, image_key, thumbnail_key, parser_version, prompt_version FROM recipes
+WHERE url IS NOT NULL AND url <> ''
+AND COALESCE(data_source, '') NOT IN ('manual', 'import')
+AND ($1::int = 0 OR group_id = $1::int)
+AND ($2::int = 0 OR id = $2::int)
+AND (
+    $3::bool
+    OR parser_version < $4::int
+    OR ((data_source = 'llm' OR extraction_status = 'failed') AND prompt_version < $5::int)
+)
+ORDER BY id
+`
+
+type GetRecipesToReprocessParams struct {
+	GroupID       int32
+	RecipeID      int32
+	AllVersions   bool
+	ParserVersion int32
+	PromptVersion int32
+}
+
+func (q *Queries) GetRecipesToReprocess(ctx context.Context, arg GetRecipesToReprocessParams) ([]Recipe, error) {
+	rows, err := q.db.Query(ctx, getRecipesToReprocess,
+		arg.GroupID,
+		arg.RecipeID,
+		arg.AllVersions,
+		arg.ParserVersion,
+		arg.PromptVersion,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Recipe
+	for rows.Next() {
+		var i Recipe
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedBy,
+			&i.GroupID,
+			&i.Url,
+			&i.Name,
+			&i.Description,
+			&i.DataJson,
+			&i.ImageUrl,
+			&i.Likes,
+			&i.CreatedAt,
+			&i.DataSource,
+			&i.PrepTimeSeconds,
+			&i.CookTimeSeconds,
+			&i.TotalTimeSeconds,
+			&i.ExtractionStatus,
+			&i.ExtractionError,
+			&i.ImageKey,
+			&i.ThumbnailKey,
+			&i.ParserVersion,
+			&i.PromptVersion,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecipesWithDataHistory = `-- name: GetRecipesWithDataHistory :many
+SELECT DISTINCT r.id FROM recipes r
+JOIN recipe_data_history h ON h.recipe_id = r.id
+WHERE ($1::int = 0 OR r.group_id = $1::int)
+AND ($2::int = 0 OR r.id = $2::int)
+ORDER BY r.id
+`
+
+type GetRecipesWithDataHistoryParams struct {
+	GroupID  int32
+	RecipeID int32
+}
+
+func (q *Queries) GetRecipesWithDataHistory(ctx context.Context, arg GetRecipesWithDataHistoryParams) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getRecipesWithDataHistory, arg.GroupID, arg.RecipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getRegistrationToken = `-- name: GetRegistrationToken :one
 SELECT id, token, email, consumed_at, created_at, expires_at, creator_ip FROM registration_tokens WHERE token = $1 LIMIT 1
 `
@@ -313,7 +1048,7 @@ func (q *Queries) GetRegistrationToken(ctx context.Context, token string) (Regis
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, name, image_url, setup_account, created_at from users WHERE email = $1 LIMIT 1
+SELECT id, email, name, image_url, setup_account, created_at, unit_system from users WHERE email = $1 LIMIT 1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -326,12 +1061,13 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.ImageUrl,
 		&i.SetupAccount,
 		&i.CreatedAt,
+		&i.UnitSystem,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, name, image_url, setup_account, created_at from users WHERE id = $1 LIMIT 1
+SELECT id, email, name, image_url, setup_account, created_at, unit_system from users WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id int32) (User, error) {
@@ -344,12 +1080,13 @@ func (q *Queries) GetUserByID(ctx context.Context, id int32) (User, error) {
 		&i.ImageUrl,
 		&i.SetupAccount,
 		&i.CreatedAt,
+		&i.UnitSystem,
 	)
 	return i, err
 }
 
 const getUserRecipes = `-- name: GetUserRecipes :many
-SELECT id, created_by, group_id, url, name, description, data_json, image_url, likes, created_at FROM recipes where created_by = $1
+SELECT id, created_by, group_id, url, name, description, data_json, image_url, likes, created_at, data_source, prep_time_seconds, cook_time_seconds, total_time_seconds, extraction_status, extraction_error, image_key, thumbnail_key, parser_version, prompt_version FROM recipes where created_by = $1
 `
 
 func (q *Queries) GetUserRecipes(ctx context.Context, createdBy int32) ([]Recipe, error) {
@@ -372,6 +1109,16 @@ func (q *Queries) GetUserRecipes(ctx context.Context, createdBy int32) ([]Recipe
 			&i.ImageUrl,
 			&i.Likes,
 			&i.CreatedAt,
+			&i.DataSource,
+			&i.PrepTimeSeconds,
+			&i.CookTimeSeconds,
+			&i.TotalTimeSeconds,
+			&i.ExtractionStatus,
+			&i.ExtractionError,
+			&i.ImageKey,
+			&i.ThumbnailKey,
+			&i.ParserVersion,
+			&i.PromptVersion,
 		); err != nil {
 			return nil, err
 		}
@@ -421,6 +1168,22 @@ func (q *Queries) IsUserAccountSetupComplete(ctx context.Context, id int32) (pgt
 	return setup_account, err
 }
 
+const isUserGroupAdmin = `-- name: IsUserGroupAdmin :one
+SELECT is_admin FROM group_users WHERE group_id = $1 AND user_id = $2 LIMIT 1
+`
+
+type IsUserGroupAdminParams struct {
+	GroupID int32
+	UserID  int32
+}
+
+func (q *Queries) IsUserGroupAdmin(ctx context.Context, arg IsUserGroupAdminParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isUserGroupAdmin, arg.GroupID, arg.UserID)
+	var is_admin bool
+	err := row.Scan(&is_admin)
+	return is_admin, err
+}
+
 const isUserInGroup = `-- name: IsUserInGroup :one
 SELECT id from group_users WHERE group_id = $1 AND user_id = $2 LIMIT 1
 `
@@ -437,6 +1200,227 @@ func (q *Queries) IsUserInGroup(ctx context.Context, arg IsUserInGroupParams) (i
 	return id, err
 }
 
+const recordLLMUsage = `-- name: RecordLLMUsage :exec
+INSERT INTO llm_usage (
+    group_id,
+    user_id,
+    recipe_id,
+    model,
+    input_tokens,
+    output_tokens,
+    latency_ms,
+    cost_micros,
+    succeeded
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+`
+
+type RecordLLMUsageParams struct {
+	GroupID      pgtype.Int4
+	UserID       pgtype.Int4
+	RecipeID     pgtype.Int4
+	Model        string
+	InputTokens  int32
+	OutputTokens int32
+	LatencyMs    int32
+	CostMicros   int64
+	Succeeded    bool
+}
+
+func (q *Queries) RecordLLMUsage(ctx context.Context, arg RecordLLMUsageParams) error {
+	_, err := q.db.Exec(ctx, recordLLMUsage,
+		arg.GroupID,
+		arg.UserID,
+		arg.RecipeID,
+		arg.Model,
+		arg.InputTokens,
+		arg.OutputTokens,
+		arg.LatencyMs,
+		arg.CostMicros,
+		arg.Succeeded,
+	)
+	return err
+}
+
+const releaseJob = `-- name: ReleaseJob :execrows
+UPDATE jobs
+SET
+    state = 'queued',
+    attempts = GREATEST(attempts - 1, 0),
+    leased_until = NULL
+WHERE id = $1 AND state = 'running' AND leased_until = $2
+`
+
+type ReleaseJobParams struct {
+	ID          int64
+	LeasedUntil pgtype.Timestamptz
+}
+
+func (q *Queries) ReleaseJob(ctx context.Context, arg ReleaseJobParams) (int64, error) {
+	result, err := q.db.Exec(ctx, releaseJob, arg.ID, arg.LeasedUntil)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const restoreRecipeData = `-- name: RestoreRecipeData :exec
+UPDATE recipes
+SET
+    data_json = $1,
+    data_source = $2,
+    prep_time_seconds = $3,
+    cook_time_seconds = $4,
+    total_time_seconds = $5,
+    parser_version = $6,
+    prompt_version = $7,
+    extraction_status = 'done',
+    extraction_error = NULL
+WHERE id = $8
+`
+
+type RestoreRecipeDataParams struct {
+	DataJson         []byte
+	DataSource       pgtype.Text
+	PrepTimeSeconds  pgtype.Int4
+	CookTimeSeconds  pgtype.Int4
+	TotalTimeSeconds pgtype.Int4
+	ParserVersion    int32
+	PromptVersion    int32
+	ID               int32
+}
+
+func (q *Queries) RestoreRecipeData(ctx context.Context, arg RestoreRecipeDataParams) error {
+	_, err := q.db.Exec(ctx, restoreRecipeData,
+		arg.DataJson,
+		arg.DataSource,
+		arg.PrepTimeSeconds,
+		arg.CookTimeSeconds,
+		arg.TotalTimeSeconds,
+		arg.ParserVersion,
+		arg.PromptVersion,
+		arg.ID,
+	)
+	return err
+}
+
+const retryJob = `-- name: RetryJob :execrows
+UPDATE jobs
+SET
+    state = 'queued',
+    leased_until = NULL,
+    last_error = $1,
+    run_at = now() + make_interval(secs => $2::int)
+WHERE id = $3 AND state = 'running' AND leased_until = $4
+`
+
+type RetryJobParams struct {
+	LastError    pgtype.Text
+	DelaySeconds int32
+	ID           int64
+	LeasedUntil  pgtype.Timestamptz
+}
+
+func (q *Queries) RetryJob(ctx context.Context, arg RetryJobParams) (int64, error) {
+	result, err := q.db.Exec(ctx, retryJob,
+		arg.LastError,
+		arg.DelaySeconds,
+		arg.ID,
+		arg.LeasedUntil,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const saveRecipeDataHistory = `-- name: SaveRecipeDataHistory :exec
+INSERT INTO recipe_data_history (
+    recipe_id,
+    data_json,
+    data_source,
+    prep_time_seconds,
+    cook_time_seconds,
+    total_time_seconds,
+    parser_version,
+    prompt_version
+)
+SELECT id, data_json, data_source, prep_time_seconds, cook_time_seconds, total_time_seconds, parser_version, prompt_version
+FROM recipes
+WHERE id = $1 AND data_json IS NOT NULL
+`
+
+func (q *Queries) SaveRecipeDataHistory(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, saveRecipeDataHistory, id)
+	return err
+}
+
+const setRecipeExtractionFailed = `-- name: SetRecipeExtractionFailed :exec
+UPDATE recipes
+SET
+    extraction_status = 'failed',
+    extraction_error = $1,
+    parser_version = $2,
+    prompt_version = $3
+WHERE id = $4
+`
+
+type SetRecipeExtractionFailedParams struct {
+	ExtractionError pgtype.Text
+	ParserVersion   int32
+	PromptVersion   int32
+	ID              int32
+}
+
+func (q *Queries) SetRecipeExtractionFailed(ctx context.Context, arg SetRecipeExtractionFailedParams) error {
+	_, err := q.db.Exec(ctx, setRecipeExtractionFailed,
+		arg.ExtractionError,
+		arg.ParserVersion,
+		arg.PromptVersion,
+		arg.ID,
+	)
+	return err
+}
+
+const setRecipeExtractionStatus = `-- name: SetRecipeExtractionStatus :exec
+UPDATE recipes
+SET
+    extraction_status = $1,
+    extraction_error = $2
+WHERE id = $3
+`
+
+type SetRecipeExtractionStatusParams struct {
+	ExtractionStatus string
+	ExtractionError  pgtype.Text
+	ID               int32
+}
+
+func (q *Queries) SetRecipeExtractionStatus(ctx context.Context, arg SetRecipeExtractionStatusParams) error {
+	_, err := q.db.Exec(ctx, setRecipeExtractionStatus, arg.ExtractionStatus, arg.ExtractionError, arg.ID)
+	return err
+}
+
+const setRecipeImage = `-- name: SetRecipeImage :exec
+UPDATE recipes
+SET
+    image_key = $1,
+    thumbnail_key = $2
+WHERE id = $3
+`
+
+type SetRecipeImageParams struct {
+	ImageKey     pgtype.Text
+	ThumbnailKey pgtype.Text
+	ID           int32
+}
+
+func (q *Queries) SetRecipeImage(ctx context.Context, arg SetRecipeImageParams) error {
+	_, err := q.db.Exec(ctx, setRecipeImage, arg.ImageKey, arg.ThumbnailKey, arg.ID)
+	return err
+}
+
 const updateRecipe = `-- name: UpdateRecipe :exec
 UPDATE recipes 
 SET 
@@ -466,17 +1450,43 @@ func (q *Queries) UpdateRecipe(ctx context.Context, arg UpdateRecipeParams) erro
 const updateRecipeWithJSON = `-- name: UpdateRecipeWithJSON :exec
 UPDATE recipes 
 SET 
-    data_json = $1
-WHERE id = $2
+    data_json = $1,
+    data_source = $2,
+    prep_time_seconds = $3,
+    cook_time_seconds = $4,
+    total_time_seconds = $5,
+    parser_version = $6,
+    prompt_version = $7,
+    image_url = COALESCE(NULLIF(image_url, ''), $8),
+    extraction_status = 'done',
+    extraction_error = NULL
+WHERE id = $9
 `
 
 type UpdateRecipeWithJSONParams struct {
-	DataJson []byte
-	ID       int32
+	DataJson         []byte
+	DataSource       pgtype.Text
+	PrepTimeSeconds  pgtype.Int4
+	CookTimeSeconds  pgtype.Int4
+	TotalTimeSeconds pgtype.Int4
+	ParserVersion    int32
+	PromptVersion    int32
+	ImageUrl         pgtype.Text
+	ID               int32
 }
 
 func (q *Queries) UpdateRecipeWithJSON(ctx context.Context, arg UpdateRecipeWithJSONParams) error {
-	_, err := q.db.Exec(ctx, updateRecipeWithJSON, arg.DataJson, arg.ID)
+	_, err := q.db.Exec(ctx, updateRecipeWithJSON,
+		arg.DataJson,
+		arg.DataSource,
+		arg.PrepTimeSeconds,
+		arg.CookTimeSeconds,
+		arg.TotalTimeSeconds,
+		arg.ParserVersion,
+		arg.PromptVersion,
+		arg.ImageUrl,
+		arg.ID,
+	)
 	return err
 }
 
@@ -498,3 +1508,19 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
 	_, err := q.db.Exec(ctx, updateUser, arg.ImageUrl, arg.Name, arg.ID)
 	return err
 }
+
+const updateUserUnitSystem = `-- name: UpdateUserUnitSystem :exec
+UPDATE users
+SET unit_system = $1
+WHERE id = $2
+`
+
+type UpdateUserUnitSystemParams struct {
+	UnitSystem string
+	ID         int32
+}
+
+func (q *Queries) UpdateUserUnitSystem(ctx context.Context, arg UpdateUserUnitSystemParams) error {
+	_, err := q.db.Exec(ctx, updateUserUnitSystem, arg.UnitSystem, arg.ID)
+	return err
+}
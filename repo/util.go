@@ -8,3 +8,11 @@ func StringPG(value string) pgtype.Text {
 		Valid:  true,
 	}
 }
+
+// NullStringPG stores an empty string as NULL, for values that are unknown rather than blank
+func NullStringPG(value string) pgtype.Text {
+	if value == "" {
+		return pgtype.Text{}
+	}
+	return StringPG(value)
+}
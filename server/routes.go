@@ -1,8 +1,13 @@
 package server
 
 import (
+	"recipeze/appconfig"
+	"recipeze/blob"
+	"recipeze/fetch"
 	"recipeze/handler"
+	"recipeze/parsing"
 	"recipeze/service"
+	"recipeze/usage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -19,9 +24,52 @@ func (s *server) SetupRoutes() {
 			setupStaticAssets(r)
 		})
 
-		recipeService := service.NewRecipeService(s.queries, s.db)
+		fetcher := fetch.New(fetch.Options{})
+		budget := llmBudget()
+		cache := service.NewExtractionCache(s.queries, service.ExtractionCacheOptions{TTL: appconfig.Config.ExtractionCacheTTL})
+		recipeService := service.NewRecipeService(s.queries, s.db, s.newRecipeExtractor(budget), cache, s.events)
 		authService := service.NewAuthService(s.queries, s.db)
+		imageService := service.NewImageService(s.queries, blob.NewFileStore(appconfig.Config.ImageDir), fetcher)
+		snapshotService := service.NewSnapshotService(s.queries, blob.NewFileStore(appconfig.Config.SnapshotDir))
+		processingService := service.NewProcessingService(s.queries, s.jobs, recipeService, imageService, snapshotService, fetcher, s.events)
+		usageService := service.NewUsageService(s.queries, budget)
 
-		handler.InitRouting(r, authService, recipeService)
+		handler.InitRouting(r, authService, recipeService, imageService, snapshotService, processingService, usageService, fetcher, s.events)
 	})
 }
+
+// newRecipeExtractor builds the configured LLM backend, metered and held to the
+// configured budgets, or nil when there is none
+func (s *server) newRecipeExtractor(budget usage.Budget) parsing.RecipeExtractor {
+	extractor, err := parsing.NewRecipeExtractor(parsing.ExtractorConfig{
+		Backend: appconfig.Config.LLMBackend,
+		Model:   appconfig.Config.LLMModel,
+		APIKey:  appconfig.Config.LLMAPIKey,
+		BaseURL: appconfig.Config.LLMBaseURL,
+	})
+	if err != nil {
+		s.log.Warn("LLM extraction disabled, only structured recipe data will be used", "error", err)
+		return nil
+	}
+	s.log.Info("Using LLM backend", "model", extractor.Model())
+	config := appconfig.Config
+	var price *usage.Price
+	if config.LLMPriceSet {
+		price = &usage.Price{Input: config.LLMInputPrice, Output: config.LLMOutputPrice}
+	}
+	return usage.NewMeter(extractor, s.queries, usage.Options{
+		Budget: budget,
+		Price:  price,
+	})
+}
+
+// llmBudget is what groups and users may spend on the LLM, from the config
+func llmBudget() usage.Budget {
+	config := appconfig.Config
+	return usage.Budget{
+		GroupDaily:   usage.Micros(config.LLMGroupDailyBudget),
+		GroupMonthly: usage.Micros(config.LLMGroupMonthlyBudget),
+		UserDaily:    usage.Micros(config.LLMUserDailyBudget),
+		UserMonthly:  usage.Micros(config.LLMUserMonthlyBudget),
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"recipeze/events"
+	"recipeze/jobs"
 	"recipeze/repo"
 )
 
@@ -22,6 +24,8 @@ type server struct {
 	log     *slog.Logger
 	mux     chi.Router
 	server  *http.Server
+	jobs    *jobs.Queue // Background work, such as reading recipes added by link
+	events  *events.Bus // Changes to recipes, for the group's open pages
 }
 
 type NewServerOptions struct {
@@ -35,9 +39,10 @@ func NewServer(opts NewServerOptions) *server {
 	}
 
 	mux := chi.NewMux()
+	queries := repo.New(opts.DB)
 
 	return &server{
-		queries: repo.New(opts.DB),
+		queries: queries,
 		db:      opts.DB,
 		log:     opts.Log,
 		mux:     mux,
@@ -49,6 +54,8 @@ func NewServer(opts NewServerOptions) *server {
 			WriteTimeout:      5 * time.Second,
 			IdleTimeout:       5 * time.Second,
 		},
+		jobs:   jobs.New(queries, jobs.Options{}),
+		events: events.New(opts.DB),
 	}
 }
 
@@ -65,6 +72,19 @@ func (s *server) Start() error {
 	return nil
 }
 
+// ProcessJobs runs the background job workers until ctx is done, then lets
+// running jobs finish. Job handlers are registered as routes are set up.
+func (s *server) ProcessJobs(ctx context.Context) error {
+	return s.jobs.Run(ctx)
+}
+
+// ListenForEvents passes recipe events from every server to this server's open
+// pages until ctx is done, then ends their event streams so Stop doesn't wait
+// on them.
+func (s *server) ListenForEvents(ctx context.Context) error {
+	return s.events.Listen(ctx)
+}
+
 // Stop the server gracefully.
 func (s *server) Stop() error {
 	s.log.Info("Stopping http server")
@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"recipeze/model"
+	"recipeze/parsing"
 	"recipeze/repo"
 	"time"
 
@@ -47,6 +48,15 @@ type AuthService interface {
 
 	// GetGroupUsers provides the users belonging to a group
 	GetGroupUsers(ctx context.Context, groupID int) ([]model.User, error)
+
+	// GetGroupMembers provides the members of a group and which of them are admins
+	GetGroupMembers(ctx context.Context, groupID int) ([]model.GroupMember, error)
+
+	// IsGroupAdmin tells if a user administers a group, such as the one who made it
+	IsGroupAdmin(ctx context.Context, groupID int, userID int) (bool, error)
+
+	// SetUnitSystem saves which units a user wants recipes shown in
+	SetUnitSystem(ctx context.Context, userID int, units parsing.UnitSystem) error
 }
 
 func NewAuthService(queries *repo.Queries, db *pgxpool.Pool) *Auth {
@@ -114,6 +124,7 @@ func (a *Auth) GetUser(ctx context.Context, email string) (*model.User, error) {
 		Name:          pgUser.Name.String,
 		Email:         pgUser.Email,
 		SetupComplete: pgUser.SetupAccount.Bool,
+		Units:         parsing.UnitSystem(pgUser.UnitSystem),
 	}
 	return &user, nil
 }
@@ -139,6 +150,7 @@ func (a *Auth) CreateAccount(ctx context.Context, email string) (*model.User, er
 	err = qtx.AddUserToGroup(ctx, repo.AddUserToGroupParams{
 		GroupID: pgGroup.ID,
 		UserID:  pgUser.ID,
+		IsAdmin: true,
 	})
 	if err != nil {
 		return nil, err
@@ -200,6 +212,7 @@ func (a *Auth) GetLoggedInUser(ctx context.Context, sessionStoken string) (*mode
 		Name:          pgUser.Name.String,
 		Email:         pgUser.Email,
 		SetupComplete: pgUser.SetupAccount.Bool,
+		Units:         parsing.UnitSystem(pgUser.UnitSystem),
 	}
 	return user, nil
 }
@@ -227,12 +240,44 @@ func (a *Auth) GetGroupUsers(ctx context.Context, groupID int) ([]model.User, er
 			Name:          pgUser.Name.String,
 			Email:         pgUser.Email,
 			SetupComplete: pgUser.SetupAccount.Bool,
+			Units:         parsing.UnitSystem(pgUser.UnitSystem),
 		}
 		users = append(users, user)
 	}
 	return users, nil
 }
 
+func (a *Auth) GetGroupMembers(ctx context.Context, groupID int) ([]model.GroupMember, error) {
+	rows, err := a.queries.GetGroupMembers(ctx, int32(groupID))
+	if err != nil {
+		return nil, err
+	}
+	members := make([]model.GroupMember, 0, len(rows))
+	for _, row := range rows {
+		members = append(members, model.GroupMember{
+			ID:      int(row.ID),
+			Name:    row.Name.String,
+			Email:   row.Email,
+			IsAdmin: row.IsAdmin,
+		})
+	}
+	return members, nil
+}
+
+func (a *Auth) IsGroupAdmin(ctx context.Context, groupID int, userID int) (bool, error) {
+	return a.queries.IsUserGroupAdmin(ctx, repo.IsUserGroupAdminParams{
+		GroupID: int32(groupID),
+		UserID:  int32(userID),
+	})
+}
+
+func (a *Auth) SetUnitSystem(ctx context.Context, userID int, units parsing.UnitSystem) error {
+	return a.queries.UpdateUserUnitSystem(ctx, repo.UpdateUserUnitSystemParams{
+		UnitSystem: string(units),
+		ID:         int32(userID),
+	})
+}
+
 func GenerateSecureToken(length int) string {
 	// Create a byte slice to store random bytes
 	b := make([]byte, length)
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"recipeze/parsing"
+	"recipeze/repo"
+)
+
+// DefaultCacheTTL is how long what the LLM read is reused, unless configured
+const DefaultCacheTTL = 30 * 24 * time.Hour
+
+// ExtractionCache keeps what the LLM read from recipe pages in the database,
+// shared by every group. It is a parsing.ExtractionCache.
+type ExtractionCache struct {
+	queries *repo.Queries
+	opts    ExtractionCacheOptions
+}
+
+// ExtractionCacheOptions tune an ExtractionCache, zero values take the defaults
+type ExtractionCacheOptions struct {
+	TTL     time.Duration // How long results are reused, DefaultCacheTTL by default
+	Refresh bool          // Ask the LLM again and replace what is cached, instead of reusing it
+}
+
+// NewExtractionCache creates the extraction cache
+func NewExtractionCache(queries *repo.Queries, opts ExtractionCacheOptions) *ExtractionCache {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultCacheTTL
+	}
+	return &ExtractionCache{
+		queries: queries,
+		opts:    opts,
+	}
+}
+
+func (c *ExtractionCache) Get(ctx context.Context, key string) (*parsing.RecipeCollection, error) {
+	if c.opts.Refresh {
+		return nil, nil
+	}
+	data, err := c.queries.GetCachedExtraction(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var collection parsing.RecipeCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+func (c *ExtractionCache) Put(ctx context.Context, key, model string, collection *parsing.RecipeCollection) error {
+	data, err := json.Marshal(collection)
+	if err != nil {
+		return err
+	}
+	return c.queries.CacheExtraction(ctx, repo.CacheExtractionParams{
+		Key:           key,
+		Model:         model,
+		PromptVersion: parsing.PromptVersion,
+		DataJson:      data,
+		ExpiresAt:     pgtype.Timestamptz{Time: time.Now().Add(c.opts.TTL), Valid: true},
+	})
+}
+
+// Purge removes the expired results, or every result with all, such as after
+// the LLM was found to have misread pages. It gives how many were removed.
+func (c *ExtractionCache) Purge(ctx context.Context, all bool) (int64, error) {
+	if all {
+		return c.queries.DeleteAllExtractions(ctx)
+	}
+	return c.queries.DeleteExpiredExtractions(ctx)
+}
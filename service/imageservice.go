@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Register decoders for the formats recipe sites use
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp"
+
+	"recipeze/blob"
+	"recipeze/fetch"
+	"recipeze/repo"
+)
+
+const (
+	// maxImageBytes is the largest photo that is downloaded
+	maxImageBytes = 10 << 20
+	// maxImagePixels refuses small files that decode to huge images
+	maxImagePixels = 50_000_000
+	// ThumbnailSize is the width and height of the square thumbnails in the recipe list
+	ThumbnailSize = 160
+	// recipeImagePrefix keeps recipe images apart from anything else in the blob store
+	recipeImagePrefix = "recipes/"
+	// stagedPhotoPrefix holds imported photos until a job stores them
+	stagedPhotoPrefix = "imports/"
+)
+
+// imageExtensions are the formats kept as they were downloaded
+var imageExtensions = map[string]string{
+	"jpeg": ".jpg",
+	"png":  ".png",
+	"gif":  ".gif",
+	"webp": ".webp",
+}
+
+type Image struct {
+	queries *repo.Queries
+	store   blob.Store
+	fetcher *fetch.Fetcher
+}
+
+// NewImageService creates the image service, keeping images in store and
+// downloading them with fetcher
+func NewImageService(queries *repo.Queries, store blob.Store, fetcher *fetch.Fetcher) *Image {
+	return &Image{
+		queries: queries,
+		store:   store,
+		fetcher: fetcher,
+	}
+}
+
+type ImageService interface {
+	// CacheRecipeImage downloads the first of the candidate image URLs that works,
+	// stores it with a thumbnail and links both to the recipe
+	CacheRecipeImage(ctx context.Context, recipeID int, candidates []string) error
+
+	// StagePhoto keeps a photo that came with a recipe, such as one in an
+	// export from another recipe manager, until StoreStagedPhoto stores it.
+	// It gives the key to pass on.
+	StagePhoto(ctx context.Context, data []byte) (string, error)
+
+	// StoreStagedPhoto stores a staged photo with a thumbnail, links both to
+	// the recipe and drops the staged copy
+	StoreStagedPhoto(ctx context.Context, recipeID int, key string) error
+
+	// DiscardStagedPhoto drops a staged photo that will not be stored
+	DiscardStagedPhoto(ctx context.Context, key string) error
+
+	// GetRecipeImage opens a cached image or thumbnail by the name stored on the recipe
+	GetRecipeImage(ctx context.Context, name string) (io.ReadCloser, blob.Info, error)
+}
+
+func (i *Image) CacheRecipeImage(ctx context.Context, recipeID int, candidates []string) error {
+	if len(candidates) == 0 {
+		return errors.New("page has no images")
+	}
+	var errs []error
+	for _, candidate := range candidates {
+		name, thumbnail, err := i.storeImage(ctx, candidate)
+		if err != nil {
+			slog.Info("could not cache recipe image, trying the next", "recipeID", recipeID, "url", candidate, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", candidate, err))
+			continue
+		}
+		return i.queries.SetRecipeImage(ctx, repo.SetRecipeImageParams{
+			ImageKey:     repo.StringPG(name),
+			ThumbnailKey: repo.StringPG(thumbnail),
+			ID:           int32(recipeID),
+		})
+	}
+	return fmt.Errorf("no candidate image could be cached: %w", errors.Join(errs...))
+}
+
+func (i *Image) StagePhoto(ctx context.Context, data []byte) (string, error) {
+	if len(data) > maxImageBytes {
+		return "", fmt.Errorf("image is larger than %d bytes", maxImageBytes)
+	}
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:16])
+	if err := i.store.Put(ctx, stagedPhotoPrefix+key, bytes.NewReader(data)); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func (i *Image) StoreStagedPhoto(ctx context.Context, recipeID int, key string) error {
+	r, _, err := i.store.Get(ctx, stagedPhotoPrefix+key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxImageBytes+1))
+	r.Close()
+	if err != nil {
+		return err
+	}
+	name, thumbnail, err := i.saveImage(ctx, data)
+	if err != nil {
+		return err
+	}
+	err = i.queries.SetRecipeImage(ctx, repo.SetRecipeImageParams{
+		ImageKey:     repo.StringPG(name),
+		ThumbnailKey: repo.StringPG(thumbnail),
+		ID:           int32(recipeID),
+	})
+	if err != nil {
+		return err
+	}
+	return i.DiscardStagedPhoto(ctx, key)
+}
+
+func (i *Image) DiscardStagedPhoto(ctx context.Context, key string) error {
+	return i.store.Delete(ctx, stagedPhotoPrefix+key)
+}
+
+func (i *Image) GetRecipeImage(ctx context.Context, name string) (io.ReadCloser, blob.Info, error) {
+	return i.store.Get(ctx, recipeImagePrefix+name)
+}
+
+// storeImage downloads an image and stores it and its thumbnail
+func (i *Image) storeImage(ctx context.Context, imageURL string) (name, thumbnail string, err error) {
+	data, err := i.download(ctx, imageURL)
+	if err != nil {
+		return "", "", err
+	}
+	return i.saveImage(ctx, data)
+}
+
+// saveImage stores an image and its thumbnail. Names come from the image's
+// hash, so the same photo is only stored once and a name always has the same
+// content.
+func (i *Image) saveImage(ctx context.Context, data []byte) (name, thumbnail string, err error) {
+	if len(data) > maxImageBytes {
+		return "", "", fmt.Errorf("image is larger than %d bytes", maxImageBytes)
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", "", fmt.Errorf("not an image: %w", err)
+	}
+	ext, ok := imageExtensions[format]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported image format %q", format)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return "", "", fmt.Errorf("image is too large at %dx%d", config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", "", fmt.Errorf("could not decode image: %w", err)
+	}
+
+	thumb, err := encodeThumbnail(img)
+	if err != nil {
+		return "", "", err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:16])
+	name, thumbnail = hash+ext, hash+"-thumb.jpg"
+	if err := i.store.Put(ctx, recipeImagePrefix+name, bytes.NewReader(data)); err != nil {
+		return "", "", err
+	}
+	if err := i.store.Put(ctx, recipeImagePrefix+thumbnail, bytes.NewReader(thumb)); err != nil {
+		return "", "", err
+	}
+	return name, thumbnail, nil
+}
+
+// download fetches an image, refusing anything that is too big. Whether it is
+// an image at all is found out when it is decoded.
+func (i *Image) download(ctx context.Context, imageURL string) ([]byte, error) {
+	data, _, err := i.fetcher.Download(ctx, imageURL, "image/avif,image/webp,image/*;q=0.8", maxImageBytes)
+	return data, err
+}
+
+// encodeThumbnail crops the image to a square JPEG, on white for images with
+// transparency
+func encodeThumbnail(img image.Image) ([]byte, error) {
+	thumb := imaging.Fill(img, ThumbnailSize, ThumbnailSize, imaging.Center, imaging.Lanczos)
+	background := imaging.New(ThumbnailSize, ThumbnailSize, color.White)
+	flattened := imaging.Overlay(background, thumb, image.Pt(0, 0), 1)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flattened, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("could not encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+
+	"recipeze/events"
+	"recipeze/fetch"
+	"recipeze/jobs"
+	"recipeze/model"
+	"recipeze/parsing"
+	"recipeze/repo"
+	"recipeze/usage"
+)
+
+// Kinds of recipe jobs
+const (
+	jobExtractPage = "extract-page" // Read a recipe added by link from its saved page
+	jobExtractText = "extract-text" // Read pasted text or a page of a PDF
+	jobCacheImage  = "cache-image"  // Keep our own copy of a linked recipe's photo
+	jobImportPhoto = "import-photo" // Store the photo of an imported recipe
+)
+
+// ErrCannotRetry is returned for recipes that have no page to read again
+var ErrCannotRetry = errors.New("recipe has no page to read again")
+
+// recipeJob is the payload of every recipe job
+type recipeJob struct {
+	RecipeID int    `json:"recipe_id"`
+	Text     string `json:"text,omitempty"`
+	Photo    string `json:"photo,omitempty"`     // Key of a staged photo, see ImageService.StagePhoto
+	ImageURL string `json:"image_url,omitempty"` // Photo to download instead
+}
+
+type Processing struct {
+	queries   *repo.Queries
+	queue     *jobs.Queue
+	recipes   RecipeService
+	images    ImageService
+	snapshots SnapshotService
+	fetcher   *fetch.Fetcher
+	events    *events.Bus
+}
+
+// NewProcessingService creates the processing service and registers its jobs
+// with queue. Pages that were not saved are fetched with fetcher, and progress
+// is published on bus.
+func NewProcessingService(queries *repo.Queries, queue *jobs.Queue, recipes RecipeService, images ImageService, snapshots SnapshotService, fetcher *fetch.Fetcher, bus *events.Bus) *Processing {
+	p := &Processing{
+		queries:   queries,
+		queue:     queue,
+		recipes:   recipes,
+		images:    images,
+		snapshots: snapshots,
+		fetcher:   fetcher,
+		events:    bus,
+	}
+	queue.Register(jobExtractPage, p.extractPage)
+	queue.Register(jobExtractText, p.extractText)
+	queue.Register(jobCacheImage, p.cacheImage)
+	queue.Register(jobImportPhoto, p.importPhoto)
+	return p
+}
+
+type ProcessingService interface {
+	// ProcessRecipePage queues reading a recipe added by link, and caching its
+	// photo, from the page saved when it was added
+	ProcessRecipePage(ctx context.Context, recipeID int) error
+
+	// ProcessRecipeText queues reading recipe text, such as pasted text or a page of a PDF
+	ProcessRecipeText(ctx context.Context, recipeID int, text string) error
+
+	// RetryRecipe queues reading a recipe added by link again after it failed,
+	// or ErrCannotRetry for recipes that have no page
+	RetryRecipe(ctx context.Context, recipeID int) error
+
+	// ProcessImportedPhoto queues storing the photo of an imported recipe,
+	// either one packed in the export or one the export links to
+	ProcessImportedPhoto(ctx context.Context, recipeID int, photo []byte, imageURL string) error
+}
+
+func (p *Processing) ProcessRecipePage(ctx context.Context, recipeID int) error {
+	payload := recipeJob{RecipeID: recipeID}
+	if err := p.queue.Enqueue(ctx, jobExtractPage, payload, jobs.EnqueueOptions{Key: extractKey(recipeID)}); err != nil {
+		return err
+	}
+	return p.queue.Enqueue(ctx, jobCacheImage, payload, jobs.EnqueueOptions{Key: fmt.Sprintf("image:%d", recipeID), MaxAttempts: 2})
+}
+
+func (p *Processing) ProcessRecipeText(ctx context.Context, recipeID int, text string) error {
+	return p.queue.Enqueue(ctx, jobExtractText, recipeJob{RecipeID: recipeID, Text: text}, jobs.EnqueueOptions{Key: extractKey(recipeID)})
+}
+
+func (p *Processing) RetryRecipe(ctx context.Context, recipeID int) error {
+	recipe, err := p.recipes.GetRecipeByID(ctx, int32(recipeID))
+	if err != nil {
+		return err
+	}
+	if recipe.Url == "" {
+		return ErrCannotRetry
+	}
+	p.setStatus(ctx, recipe.GroupID, recipeID, model.ExtractionPending, "")
+	if recipe.ImageKey == "" {
+		return p.ProcessRecipePage(ctx, recipeID)
+	}
+	return p.queue.Enqueue(ctx, jobExtractPage, recipeJob{RecipeID: recipeID}, jobs.EnqueueOptions{Key: extractKey(recipeID)})
+}
+
+func (p *Processing) ProcessImportedPhoto(ctx context.Context, recipeID int, photo []byte, imageURL string) error {
+	payload := recipeJob{RecipeID: recipeID, ImageURL: imageURL}
+	if len(photo) > 0 {
+		key, err := p.images.StagePhoto(ctx, photo)
+		if err != nil {
+			return err
+		}
+		payload = recipeJob{RecipeID: recipeID, Photo: key}
+	}
+	if payload.Photo == "" && payload.ImageURL == "" {
+		return nil
+	}
+	return p.queue.Enqueue(ctx, jobImportPhoto, payload, jobs.EnqueueOptions{Key: fmt.Sprintf("image:%d", recipeID), MaxAttempts: 2})
+}
+
+// extractKey keeps a recipe from being read by two jobs at once
+func extractKey(recipeID int) string {
+	return fmt.Sprintf("extract:%d", recipeID)
+}
+
+func (p *Processing) extractPage(ctx context.Context, job *jobs.Job) error {
+	recipe, err := p.jobRecipe(ctx, job)
+	if recipe == nil {
+		return err
+	}
+	id := int(recipe.ID)
+	p.setStatus(ctx, int(recipe.GroupID), id, model.ExtractionRunning, "")
+	pageURL, page, err := recipePage(ctx, p.snapshots, p.fetcher, id, recipe.Url.String)
+	if err == nil {
+		err = p.recipes.ExtractRecipeData(ctx, id, pageURL, page)
+	}
+	return p.finish(ctx, job, recipe, err)
+}
+
+func (p *Processing) extractText(ctx context.Context, job *jobs.Job) error {
+	var payload recipeJob
+	if err := job.Decode(&payload); err != nil {
+		return jobs.Permanent(err)
+	}
+	recipe, err := p.jobRecipe(ctx, job)
+	if recipe == nil {
+		return err
+	}
+	p.setStatus(ctx, int(recipe.GroupID), payload.RecipeID, model.ExtractionRunning, "")
+	err = p.recipes.ExtractRecipeText(ctx, payload.RecipeID, payload.Text)
+	return p.finish(ctx, job, recipe, err)
+}
+
+func (p *Processing) cacheImage(ctx context.Context, job *jobs.Job) error {
+	recipe, err := p.jobRecipe(ctx, job)
+	if recipe == nil {
+		return err
+	}
+	id := int(recipe.ID)
+	pageURL, page, err := recipePage(ctx, p.snapshots, p.fetcher, id, recipe.Url.String)
+	if err != nil {
+		return permanentFailure(err)
+	}
+	candidates := parsing.ImageCandidates(pageURL, page)
+	if len(candidates) == 0 {
+		return nil // Some recipes have no photo
+	}
+	if err := p.images.CacheRecipeImage(ctx, id, candidates); err != nil {
+		return err
+	}
+	publishRecipe(ctx, p.events, events.RecipeUpdated, int(recipe.GroupID), id)
+	return nil
+}
+
+func (p *Processing) importPhoto(ctx context.Context, job *jobs.Job) error {
+	var payload recipeJob
+	if err := job.Decode(&payload); err != nil {
+		return jobs.Permanent(err)
+	}
+	recipe, err := p.jobRecipe(ctx, job)
+	if recipe == nil {
+		if err == nil && payload.Photo != "" {
+			return p.images.DiscardStagedPhoto(ctx, payload.Photo)
+		}
+		return err
+	}
+	id := int(recipe.ID)
+	if payload.Photo != "" {
+		err = p.images.StoreStagedPhoto(ctx, id, payload.Photo)
+	} else {
+		err = p.images.CacheRecipeImage(ctx, id, []string{payload.ImageURL})
+	}
+	if err != nil {
+		if job.LastAttempt() && payload.Photo != "" {
+			if err := p.images.DiscardStagedPhoto(ctx, payload.Photo); err != nil {
+				slog.Info("Could not discard staged photo", "recipeID", id, "error", err)
+			}
+		}
+		return err
+	}
+	publishRecipe(ctx, p.events, events.RecipeUpdated, int(recipe.GroupID), id)
+	return nil
+}
+
+// jobRecipe gets the recipe a job is for. Recipes deleted while their job
+// waited give nil and no error, there is nothing left to do.
+func (p *Processing) jobRecipe(ctx context.Context, job *jobs.Job) (*repo.Recipe, error) {
+	var payload recipeJob
+	if err := job.Decode(&payload); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	recipe, err := p.queries.GetRecipeByID(ctx, int32(payload.RecipeID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &recipe, nil
+}
+
+// finish shows how a job went on its recipe. A recipe that will be read again
+// shows as pending rather than failed, and one put off by a used up LLM budget
+// waits in the queue until the budget resets.
+func (p *Processing) finish(ctx context.Context, job *jobs.Job, recipe *repo.Recipe, err error) error {
+	if err == nil {
+		return nil
+	}
+	groupID, recipeID := int(recipe.GroupID), int(recipe.ID)
+	var exceeded *usage.ExceededError
+	if errors.As(err, &exceeded) && ctx.Err() == nil {
+		p.setStatus(ctx, groupID, recipeID, model.ExtractionPending, exceeded.Error())
+		return jobs.Delay(err, exceeded.Until)
+	}
+	err = permanentFailure(err)
+	if ctx.Err() == nil && (job.LastAttempt() || jobs.IsPermanent(err)) {
+		p.setStatus(ctx, groupID, recipeID, model.ExtractionFailed, err.Error())
+	} else {
+		p.setStatus(ctx, groupID, recipeID, model.ExtractionPending, "")
+	}
+	return err
+}
+
+// permanentFailure marks failures that trying again won't fix: there is no
+// LLM to ask, or the page is gone or refuses us
+func permanentFailure(err error) error {
+	if errors.Is(err, parsing.ErrNoExtractor) {
+		return jobs.Permanent(err)
+	}
+	switch fetch.Reason(err) {
+	case fetch.ErrInvalidURL, fetch.ErrForbiddenAddress, fetch.ErrNotFound, fetch.ErrBlocked, fetch.ErrNotWebPage, fetch.ErrTooLarge:
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// setStatus shows where a recipe is in being read, on its group's open pages
+// too. The message tells why it failed or is waiting, if it is.
+func (p *Processing) setStatus(ctx context.Context, groupID, recipeID int, status model.ExtractionStatus, message string) {
+	err := p.queries.SetRecipeExtractionStatus(context.WithoutCancel(ctx), repo.SetRecipeExtractionStatusParams{
+		ExtractionStatus: string(status),
+		ExtractionError:  repo.NullStringPG(message),
+		ID:               int32(recipeID),
+	})
+	if err != nil {
+		slog.Error("Could not set recipe status", "recipeID", recipeID, "status", status, "error", err)
+		return
+	}
+	publishRecipe(ctx, p.events, events.RecipeUpdated, groupID, recipeID)
+}
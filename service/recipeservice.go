@@ -3,24 +3,43 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"recipeze/events"
+	"recipeze/importer"
 	"recipeze/model"
 	"recipeze/parsing"
 	"recipeze/repo"
+	"recipeze/usage"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
-	//"github.com/jackc/pgx/v5/pgtype"
 )
 
+// ErrNoDataHistory is returned when rolling back a recipe that was never re-extracted
+var ErrNoDataHistory = errors.New("recipe has no earlier data")
+
 type Recipe struct {
-	queries *repo.Queries
-	db      *pgxpool.Pool
+	queries  *repo.Queries
+	db       *pgxpool.Pool
+	pipeline *parsing.Pipeline
+	events   *events.Bus
 }
 
-func NewRecipeService(queries *repo.Queries, db *pgxpool.Pool) *Recipe {
+// NewRecipeService creates the recipe service. extractor may be nil, in which
+// case recipes are only extracted from structured page data. What it reads is
+// reused from cache, and changes to recipes are published on bus.
+func NewRecipeService(queries *repo.Queries, db *pgxpool.Pool, extractor parsing.RecipeExtractor, cache parsing.ExtractionCache, bus *events.Bus) *Recipe {
 	return &Recipe{
-		queries: queries,
-		db:      db,
+		queries:  queries,
+		db:       db,
+		pipeline: parsing.NewPipeline(extractor).WithCache(cache),
+		events:   bus,
 	}
 }
 
@@ -31,6 +50,10 @@ type RecipeService interface {
 	// GetRecipes retrieves all recipes
 	GetGroupRecipes(ctx context.Context, group_id int) ([]model.Recipe, error)
 
+	// GetGroupRecipesByTotalTime retrieves recipes quickest first, leaving out
+	// those that take longer than maxTotal unless it is zero
+	GetGroupRecipesByTotalTime(ctx context.Context, groupID int, maxTotal time.Duration) ([]model.Recipe, error)
+
 	// GetRecipeByID retrieves a recipe by its ID
 	GetRecipeByID(ctx context.Context, id int32) (*model.Recipe, error)
 
@@ -40,15 +63,46 @@ type RecipeService interface {
 	// UpdateRecipe modifies an existing recipe
 	UpdateRecipe(ctx context.Context, args repo.UpdateRecipeParams) error
 
-	//
-	UpdateRecipeWithJSON(ctx context.Context, json string, recipeID int) error
+	// UpdateRecipeData stores the extracted recipe data and which path produced it
+	UpdateRecipeData(ctx context.Context, recipeID int, collection *parsing.RecipeCollection, source parsing.Source) error
+
+	// ExtractRecipeData runs the extraction pipeline over a fetched page and stores the result
+	ExtractRecipeData(ctx context.Context, recipeID int, pageURL string, page []byte) error
+
+	// ExtractRecipeText runs the extraction pipeline over pasted recipe text and stores the result
+	ExtractRecipeText(ctx context.Context, recipeID int, text string) error
+
+	// ReextractRecipeData runs the extraction pipeline over a recipe's page again,
+	// keeping the data it had so it can be rolled back
+	ReextractRecipeData(ctx context.Context, recipeID int, pageURL string, page []byte) error
+
+	// RollbackRecipeData puts back the data a recipe had before it was last
+	// re-extracted, or returns ErrNoDataHistory
+	RollbackRecipeData(ctx context.Context, recipeID int) error
+
+	// SaveReviewedRecipe stores a recipe's data as corrected by hand
+	SaveReviewedRecipe(ctx context.Context, recipeID int, reviewed ReviewedRecipe) error
+
+	// ImportRecipe adds a recipe read from another recipe manager's export to a group
+	ImportRecipe(ctx context.Context, item importer.Item, userID int, groupID int) (id int, err error)
+}
+
+// ReviewedRecipe is a recipe's data as it was checked and corrected in the review editor
+type ReviewedRecipe struct {
+	Name         string
+	Servings     int
+	Ingredients  []string // One ingredient per line, as in "1 cup flour"
+	Instructions []string
+	Notes        []string
+	// Lines after a heading like "== Frosting ==" belong to that part of the
+	// recipe, see parsing.SplitSections
 }
 
 func (r *Recipe) AddRecipe(ctx context.Context, url, name, description string, imgURL string, userID int, groupID int) (id int, err error) {
 	args := repo.AddRecipeParams{
 		CreatedBy:   int32(userID),
 		GroupID:     int32(groupID),
-		Url:         repo.StringPG(url),
+		Url:         repo.NullStringPG(url), // Pasted recipes have no page
 		Name:        repo.StringPG(name),
 		Description: repo.StringPG(description),
 		ImageUrl:    repo.StringPG(imgURL),
@@ -57,16 +111,279 @@ func (r *Recipe) AddRecipe(ctx context.Context, url, name, description string, i
 	if err != nil {
 		return 0, err
 	}
+	publishRecipe(ctx, r.events, events.RecipeCreated, groupID, int(recipeid))
 
 	return int(recipeid), nil
 }
 
-func (r *Recipe) UpdateRecipeWithJSON(ctx context.Context, json string, recipeID int) error {
-	err := r.queries.UpdateRecipeWithJSON(ctx, repo.UpdateRecipeWithJSONParams{
-		DataJson: []byte(json),
-		ID:       int32(recipeID),
+func (r *Recipe) UpdateRecipeData(ctx context.Context, recipeID int, collection *parsing.RecipeCollection, source parsing.Source) error {
+	args, err := recipeDataParams(recipeID, collection, source)
+	if err != nil {
+		return err
+	}
+	if err := r.queries.UpdateRecipeWithJSON(ctx, args); err != nil {
+		return err
+	}
+	r.publishUpdate(ctx, recipeID)
+	return nil
+}
+
+// recipeDataParams prepares extracted data for storing, with the versions of
+// the parser and prompt that produced it
+func recipeDataParams(recipeID int, collection *parsing.RecipeCollection, source parsing.Source) (repo.UpdateRecipeWithJSONParams, error) {
+	data, err := json.Marshal(collection)
+	if err != nil {
+		return repo.UpdateRecipeWithJSONParams{}, err
+	}
+	prep, cook, total := collection.Times()
+	var image pgtype.Text
+	if len(collection.Recipes) > 0 && collection.Recipes[0].Image != "" {
+		// Only used when the page's meta tags had no image
+		image = repo.StringPG(collection.Recipes[0].Image)
+	}
+	parserVersion, promptVersion := source.Versions()
+	return repo.UpdateRecipeWithJSONParams{
+		DataJson:         data,
+		DataSource:       repo.NullStringPG(string(source)),
+		PrepTimeSeconds:  durationPG(prep),
+		CookTimeSeconds:  durationPG(cook),
+		TotalTimeSeconds: durationPG(total),
+		ParserVersion:    int32(parserVersion),
+		PromptVersion:    int32(promptVersion),
+		ImageUrl:         image,
+		ID:               int32(recipeID),
+	}, nil
+}
+
+func (r *Recipe) ExtractRecipeData(ctx context.Context, recipeID int, pageURL string, page []byte) error {
+	scoped, err := r.usageScope(ctx, recipeID)
+	if err != nil {
+		return err
+	}
+	collection, source, err := r.pipeline.Extract(scoped, pageURL, page)
+	if errors.Is(err, parsing.ErrOverBudget) {
+		return err // Tried again once the budget allows
+	}
+	if err != nil {
+		r.setExtractionFailed(ctx, recipeID, err)
+		return err
+	}
+	return r.UpdateRecipeData(ctx, recipeID, collection, source)
+}
+
+func (r *Recipe) ExtractRecipeText(ctx context.Context, recipeID int, text string) error {
+	scoped, err := r.usageScope(ctx, recipeID)
+	if err != nil {
+		return err
+	}
+	collection, source, err := r.pipeline.ExtractText(scoped, text)
+	if errors.Is(err, parsing.ErrOverBudget) {
+		return err
+	}
+	if err != nil {
+		// Keep the text as notes, so it can be sorted into ingredients and steps by hand
+		var notes []string
+		for _, line := range strings.Split(text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				notes = append(notes, line)
+			}
+		}
+		kept := &parsing.RecipeCollection{Recipes: []parsing.Recipe{{Ingredients: []parsing.Ingredient{}, Notes: notes}}}
+		if updateErr := r.UpdateRecipeData(ctx, recipeID, kept, ""); updateErr != nil {
+			slog.Error("Could not keep pasted recipe text", "recipeID", recipeID, "error", updateErr)
+		}
+		r.setExtractionFailed(ctx, recipeID, err)
+		return err
+	}
+	return r.UpdateRecipeData(ctx, recipeID, collection, source)
+}
+
+// ReextractRecipeData runs the extraction pipeline over a recipe's page again.
+// The data it replaces is kept for RollbackRecipeData, and a recipe that had
+// data keeps it when the extraction fails.
+func (r *Recipe) ReextractRecipeData(ctx context.Context, recipeID int, pageURL string, page []byte) error {
+	scoped, err := r.usageScope(ctx, recipeID)
+	if err != nil {
+		return err
+	}
+	collection, source, err := r.pipeline.Extract(scoped, pageURL, page)
+	if errors.Is(err, parsing.ErrOverBudget) {
+		return err
+	}
+	if err != nil {
+		recipe, getErr := r.queries.GetRecipeByID(ctx, int32(recipeID))
+		if getErr == nil && recipe.ExtractionStatus != string(model.ExtractionDone) {
+			r.setExtractionFailed(ctx, recipeID, err)
+		}
+		return err
+	}
+	args, err := recipeDataParams(recipeID, collection, source)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	if err := qtx.SaveRecipeDataHistory(ctx, int32(recipeID)); err != nil {
+		return err
+	}
+	if err := qtx.UpdateRecipeWithJSON(ctx, args); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	r.publishUpdate(ctx, recipeID)
+	return nil
+}
+
+func (r *Recipe) RollbackRecipeData(ctx context.Context, recipeID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.queries.WithTx(tx)
+
+	previous, err := qtx.GetLatestRecipeDataHistory(ctx, int32(recipeID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNoDataHistory
+	}
+	if err != nil {
+		return err
+	}
+	err = qtx.RestoreRecipeData(ctx, repo.RestoreRecipeDataParams{
+		DataJson:         previous.DataJson,
+		DataSource:       previous.DataSource,
+		PrepTimeSeconds:  previous.PrepTimeSeconds,
+		CookTimeSeconds:  previous.CookTimeSeconds,
+		TotalTimeSeconds: previous.TotalTimeSeconds,
+		ParserVersion:    previous.ParserVersion,
+		PromptVersion:    previous.PromptVersion,
+		ID:               int32(recipeID),
+	})
+	if err != nil {
+		return err
+	}
+	if err := qtx.DeleteRecipeDataHistory(ctx, previous.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	r.publishUpdate(ctx, recipeID)
+	return nil
+}
+
+// setExtractionFailed records the failure so the recipe does not look like it
+// is still being processed, and which versions failed so it is not retried
+// until they change
+func (r *Recipe) setExtractionFailed(ctx context.Context, recipeID int, err error) {
+	parserVersion, promptVersion := r.pipeline.Versions()
+	failErr := r.queries.SetRecipeExtractionFailed(ctx, repo.SetRecipeExtractionFailedParams{
+		ExtractionError: repo.StringPG(err.Error()),
+		ParserVersion:   int32(parserVersion),
+		PromptVersion:   int32(promptVersion),
+		ID:              int32(recipeID),
+	})
+	if failErr != nil {
+		slog.Error("Could not mark recipe extraction as failed", "recipeID", recipeID, "error", failErr)
+		return
+	}
+	r.publishUpdate(ctx, recipeID)
+}
+
+func (r *Recipe) SaveReviewedRecipe(ctx context.Context, recipeID int, reviewed ReviewedRecipe) error {
+	recipe, err := r.GetRecipeByID(ctx, int32(recipeID))
+	if err != nil {
+		return err
+	}
+
+	// Start from what was extracted, so times, nutrition and the photo are kept
+	parts := []parsing.Recipe{{}}
+	if recipe.Data != nil && len(recipe.Data.Recipes) > 0 {
+		parts = slices.Clone(recipe.Data.Recipes)
+	}
+	for i := range parts {
+		parts[i].Ingredients = []parsing.Ingredient{}
+		parts[i].Instructions = nil
+		parts[i].Notes = nil
+		parts[i].StepMedia = nil // The steps may have moved
+	}
+	parts[0].Name = reviewed.Name
+	parts[0].Servings = reviewed.Servings
+
+	// Sections go to the part with their name, or a new part after the others
+	part := func(name string) *parsing.Recipe {
+		if name == "" {
+			return &parts[0]
+		}
+		for i := 1; i < len(parts); i++ {
+			if strings.EqualFold(parts[i].Name, name) {
+				return &parts[i]
+			}
+		}
+		parts = append(parts, parsing.Recipe{Name: name, Ingredients: []parsing.Ingredient{}})
+		return &parts[len(parts)-1]
+	}
+	for _, section := range parsing.SplitSections(reviewed.Ingredients) {
+		target := part(section.Name)
+		for _, line := range section.Lines {
+			if ingredient := parsing.ParseIngredient(line); ingredient.Name != "" {
+				target.Ingredients = append(target.Ingredients, ingredient)
+			}
+		}
+	}
+	for _, section := range parsing.SplitSections(reviewed.Instructions) {
+		target := part(section.Name)
+		target.Instructions = append(target.Instructions, section.Lines...)
+	}
+	for _, section := range parsing.SplitSections(reviewed.Notes) {
+		target := part(section.Name)
+		target.Notes = append(target.Notes, section.Lines...)
+	}
+	// Parts whose lines were all removed are gone
+	kept := parts[:1]
+	for _, part := range parts[1:] {
+		if len(part.Ingredients) > 0 || len(part.Instructions) > 0 || len(part.Notes) > 0 {
+			kept = append(kept, part)
+		}
+	}
+
+	source := parsing.Source(recipe.DataSource)
+	if source == "" {
+		source = parsing.SourceManual
+	}
+	err = r.UpdateRecipe(ctx, repo.UpdateRecipeParams{
+		ID:          int32(recipeID),
+		Name:        repo.StringPG(reviewed.Name),
+		Url:         repo.NullStringPG(recipe.Url),
+		Description: repo.StringPG(recipe.Description),
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	return r.UpdateRecipeData(ctx, recipeID, &parsing.RecipeCollection{Recipes: kept}, source)
+}
+
+func (r *Recipe) ImportRecipe(ctx context.Context, item importer.Item, userID int, groupID int) (int, error) {
+	id, err := r.AddRecipe(ctx, item.SourceURL, item.Name, item.Description, item.ImageURL, userID, groupID)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.UpdateRecipeData(ctx, id, item.Collection, parsing.SourceImport); err != nil {
+		// Don't leave a recipe behind that looks like it is still being read
+		if deleteErr := r.DeleteRecipeByID(ctx, id); deleteErr != nil {
+			slog.Error("Could not remove recipe after failed import", "recipeID", id, "error", deleteErr)
+		}
+		return 0, err
+	}
+	return id, nil
 }
 
 func (r *Recipe) GetGroupRecipes(ctx context.Context, group_id int) ([]model.Recipe, error) {
@@ -82,6 +399,21 @@ func (r *Recipe) GetGroupRecipes(ctx context.Context, group_id int) ([]model.Rec
 	return recipes, nil
 }
 
+func (r *Recipe) GetGroupRecipesByTotalTime(ctx context.Context, groupID int, maxTotal time.Duration) ([]model.Recipe, error) {
+	recipesPG, err := r.queries.GetGroupRecipesByTotalTime(ctx, repo.GetGroupRecipesByTotalTimeParams{
+		GroupID:    int32(groupID),
+		MaxSeconds: int32(maxTotal / time.Second),
+	})
+	if err != nil {
+		return nil, err
+	}
+	recipes := make([]model.Recipe, 0, len(recipesPG))
+	for _, recipePG := range recipesPG {
+		recipes = append(recipes, newRecipe(recipePG))
+	}
+	return recipes, nil
+}
+
 func (r *Recipe) GetRecipeByID(ctx context.Context, id int32) (*model.Recipe, error) {
 	recipePG, err := r.queries.GetRecipeByID(ctx, id)
 	if err != nil {
@@ -92,10 +424,16 @@ func (r *Recipe) GetRecipeByID(ctx context.Context, id int32) (*model.Recipe, er
 }
 
 func (r *Recipe) DeleteRecipeByID(ctx context.Context, id int) error {
-	err := r.queries.DeleteRecipeByID(ctx, int32(id))
+	// The group is needed to tell it, and is gone with the recipe
+	recipe, err := r.queries.GetRecipeByID(ctx, int32(id))
 	if err != nil {
 		return err
 	}
+	err = r.queries.DeleteRecipeByID(ctx, int32(id))
+	if err != nil {
+		return err
+	}
+	publishRecipe(ctx, r.events, events.RecipeDeleted, int(recipe.GroupID), id)
 	return nil
 }
 
@@ -104,15 +442,55 @@ func (r *Recipe) UpdateRecipe(ctx context.Context, args repo.UpdateRecipeParams)
 		args.Name.String = "Recipe"
 	}
 	err := r.queries.UpdateRecipe(ctx, args)
-	return err
+	if err != nil {
+		return err
+	}
+	r.publishUpdate(ctx, int(args.ID))
+	return nil
+}
+
+// usageScope charges the LLM calls made for a recipe to its group and to the
+// user who added it. Without them budgets can't be checked, so a recipe that
+// can't be looked up is an error rather than a call nobody pays for.
+func (r *Recipe) usageScope(ctx context.Context, recipeID int) (context.Context, error) {
+	recipe, err := r.queries.GetRecipeByID(ctx, int32(recipeID))
+	if err != nil {
+		return nil, fmt.Errorf("could not get recipe to charge its LLM usage: %w", err)
+	}
+	return usage.WithScope(ctx, usage.Scope{
+		GroupID:  int(recipe.GroupID),
+		UserID:   int(recipe.CreatedBy),
+		RecipeID: recipeID,
+	}), nil
+}
+
+// publishUpdate tells the recipe's group that it changed
+func (r *Recipe) publishUpdate(ctx context.Context, recipeID int) {
+	recipe, err := r.queries.GetRecipeByID(ctx, int32(recipeID))
+	if err != nil {
+		slog.Warn("Could not get recipe to publish its change", "recipeID", recipeID, "error", err)
+		return
+	}
+	publishRecipe(ctx, r.events, events.RecipeUpdated, int(recipe.GroupID), recipeID)
+}
+
+// publishRecipe tells the open pages of a group about a change to one of its
+// recipes. The change has happened either way, so failing to tell is logged.
+func publishRecipe(ctx context.Context, bus *events.Bus, kind events.Kind, groupID, recipeID int) {
+	err := bus.Publish(context.WithoutCancel(ctx), events.Event{Kind: kind, GroupID: groupID, RecipeID: recipeID})
+	if err != nil {
+		slog.Warn("Could not publish recipe event", "recipeID", recipeID, "kind", kind, "error", err)
+	}
 }
 
 func newRecipe(pg repo.Recipe) model.Recipe {
 	// Parse the generated JSON
 	var collection parsing.RecipeCollection
-	err := json.Unmarshal([]byte(pg.DataJson), &collection)
-	if err != nil {
-		slog.Error("Error unmarshaling recipe json", "error", err)
+	if len(pg.DataJson) > 0 {
+		err := json.Unmarshal([]byte(pg.DataJson), &collection)
+		if err != nil {
+			slog.Error("Error unmarshaling recipe json", "error", err)
+		}
 	}
 
 	return model.Recipe{
@@ -121,6 +499,31 @@ func newRecipe(pg repo.Recipe) model.Recipe {
 		Url:         pg.Url.String,
 		Description: pg.Description.String,
 		ImageURL:    pg.ImageUrl.String,
+		GroupID:     int(pg.GroupID),
 		Data:        &collection,
+		DataSource:  pg.DataSource.String,
+		PrepTime:    durationFromPG(pg.PrepTimeSeconds),
+		CookTime:    durationFromPG(pg.CookTimeSeconds),
+		TotalTime:   durationFromPG(pg.TotalTimeSeconds),
+		Status:      model.ExtractionStatus(pg.ExtractionStatus),
+		StatusError: pg.ExtractionError.String,
+		ImageKey:    pg.ImageKey.String,
+		ThumbKey:    pg.ThumbnailKey.String,
+	}
+}
+
+// durationPG stores unknown times as NULL so they sort last, and so are times
+// too long to be real
+func durationPG(d parsing.Duration) pgtype.Int4 {
+	if d <= 0 || d > parsing.MaxDuration {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: int32(d.Seconds()), Valid: true}
+}
+
+func durationFromPG(seconds pgtype.Int4) parsing.Duration {
+	if !seconds.Valid {
+		return 0
 	}
+	return parsing.Duration(time.Duration(seconds.Int32) * time.Second)
 }
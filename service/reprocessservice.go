@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"recipeze/fetch"
+	"recipeze/parsing"
+	"recipeze/repo"
+)
+
+// Reprocessor re-runs extraction for recipes whose data came from an older
+// parser or prompt, reading their saved pages where it can
+type Reprocessor struct {
+	queries   *repo.Queries
+	recipes   RecipeService
+	snapshots SnapshotService
+	fetcher   *fetch.Fetcher
+}
+
+// NewReprocessor creates a reprocessor. Pages without a saved copy are fetched
+// with fetcher, and saved for next time.
+func NewReprocessor(queries *repo.Queries, recipes RecipeService, snapshots SnapshotService, fetcher *fetch.Fetcher) *Reprocessor {
+	return &Reprocessor{
+		queries:   queries,
+		recipes:   recipes,
+		snapshots: snapshots,
+		fetcher:   fetcher,
+	}
+}
+
+// ReprocessOptions picks the recipes to re-extract. Without a group or recipe,
+// recipes in every group are picked.
+type ReprocessOptions struct {
+	GroupID     int
+	RecipeID    int
+	AllVersions bool // Also pick recipes already extracted by the current versions
+	DryRun      bool // Only list the recipes that would be picked
+}
+
+// ReprocessResult counts what happened to the picked recipes
+type ReprocessResult struct {
+	Picked  int
+	Updated int
+	Failed  int
+}
+
+// Reprocess re-extracts the picked recipes one at a time. A recipe that fails
+// keeps its data and is counted, the rest carry on.
+func (p *Reprocessor) Reprocess(ctx context.Context, opts ReprocessOptions) (ReprocessResult, error) {
+	recipes, err := p.queries.GetRecipesToReprocess(ctx, repo.GetRecipesToReprocessParams{
+		GroupID:       int32(opts.GroupID),
+		RecipeID:      int32(opts.RecipeID),
+		AllVersions:   opts.AllVersions,
+		ParserVersion: parsing.ParserVersion,
+		PromptVersion: parsing.PromptVersion,
+	})
+	if err != nil {
+		return ReprocessResult{}, err
+	}
+
+	result := ReprocessResult{Picked: len(recipes)}
+	for _, recipe := range recipes {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		log := slog.With("recipeID", recipe.ID, "url", recipe.Url.String, "parserVersion", recipe.ParserVersion, "promptVersion", recipe.PromptVersion)
+		if opts.DryRun {
+			log.Info("Would re-extract recipe")
+			continue
+		}
+		pageURL, page, err := recipePage(ctx, p.snapshots, p.fetcher, int(recipe.ID), recipe.Url.String)
+		if err != nil {
+			log.Warn("Could not get recipe page", "error", err)
+			result.Failed++
+			continue
+		}
+		if err := p.recipes.ReextractRecipeData(ctx, int(recipe.ID), pageURL, page); err != nil {
+			log.Warn("Could not re-extract recipe", "error", err)
+			result.Failed++
+			continue
+		}
+		log.Info("Re-extracted recipe")
+		result.Updated++
+	}
+	return result, nil
+}
+
+// Rollback puts back the data the picked recipes had before they were last
+// re-extracted, for when a new parser or prompt does worse. AllVersions is
+// ignored, only recipes that were re-extracted are picked.
+func (p *Reprocessor) Rollback(ctx context.Context, opts ReprocessOptions) (ReprocessResult, error) {
+	ids, err := p.queries.GetRecipesWithDataHistory(ctx, repo.GetRecipesWithDataHistoryParams{
+		GroupID:  int32(opts.GroupID),
+		RecipeID: int32(opts.RecipeID),
+	})
+	if err != nil {
+		return ReprocessResult{}, err
+	}
+
+	result := ReprocessResult{Picked: len(ids)}
+	for _, id := range ids {
+		if opts.DryRun {
+			slog.Info("Would roll back recipe", "recipeID", id)
+			continue
+		}
+		if err := p.recipes.RollbackRecipeData(ctx, int(id)); err != nil {
+			slog.Warn("Could not roll back recipe", "recipeID", id, "error", err)
+			result.Failed++
+			continue
+		}
+		result.Updated++
+	}
+	return result, nil
+}
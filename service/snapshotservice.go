@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+
+	"recipeze/blob"
+	"recipeze/fetch"
+	"recipeze/model"
+	"recipeze/parsing"
+	"recipeze/repo"
+)
+
+const (
+	// snapshotPrefix keeps saved pages apart from anything else in the blob store
+	snapshotPrefix = "snapshots/"
+	// maxSnapshotBytes is the largest saved page that is read back, well over
+	// what the fetcher downloads
+	maxSnapshotBytes = 50 << 20
+)
+
+// ErrNoSnapshot is returned for recipes that were saved without a copy of
+// their page, such as pasted or imported ones
+var ErrNoSnapshot = errors.New("recipe has no saved copy")
+
+type Snapshot struct {
+	queries *repo.Queries
+	store   blob.Store
+}
+
+// NewSnapshotService creates the snapshot service, keeping saved pages in store
+func NewSnapshotService(queries *repo.Queries, store blob.Store) *Snapshot {
+	return &Snapshot{
+		queries: queries,
+		store:   store,
+	}
+}
+
+type SnapshotService interface {
+	// SaveSnapshot keeps a compressed copy of a recipe's page and its text, so
+	// the recipe outlives the site it came from
+	SaveSnapshot(ctx context.Context, recipeID int, pageURL string, page []byte) error
+
+	// GetSnapshot gives the newest saved copy of a recipe's page, or ErrNoSnapshot
+	GetSnapshot(ctx context.Context, recipeID int) (*model.Snapshot, error)
+}
+
+// SaveSnapshot stores the page and its text under the page's hash, so a page
+// saved by several groups is only stored once
+func (s *Snapshot) SaveSnapshot(ctx context.Context, recipeID int, pageURL string, page []byte) error {
+	sum := sha256.Sum256(page)
+	hash := hex.EncodeToString(sum[:])
+	latest, err := s.queries.GetLatestRecipeSnapshot(ctx, int32(recipeID))
+	if err == nil && latest.ContentHash == hash {
+		return nil
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	if err := s.putCompressed(ctx, snapshotKey(hash, "html"), page); err != nil {
+		return fmt.Errorf("could not store page: %w", err)
+	}
+	if err := s.putCompressed(ctx, snapshotKey(hash, "txt"), parsing.HtmlToText(page)); err != nil {
+		return fmt.Errorf("could not store page text: %w", err)
+	}
+	return s.queries.CreateRecipeSnapshot(ctx, repo.CreateRecipeSnapshotParams{
+		RecipeID:    int32(recipeID),
+		PageUrl:     pageURL,
+		ContentHash: hash,
+		HtmlSize:    int32(len(page)),
+	})
+}
+
+func (s *Snapshot) GetSnapshot(ctx context.Context, recipeID int) (*model.Snapshot, error) {
+	row, err := s.queries.GetLatestRecipeSnapshot(ctx, int32(recipeID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoSnapshot
+	}
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.getCompressed(ctx, snapshotKey(row.ContentHash, "html"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read page: %w", err)
+	}
+	text, err := s.getCompressed(ctx, snapshotKey(row.ContentHash, "txt"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read page text: %w", err)
+	}
+	return &model.Snapshot{
+		RecipeID: recipeID,
+		URL:      row.PageUrl,
+		HTML:     page,
+		Text:     string(text),
+		SavedAt:  row.CreatedAt.Time,
+	}, nil
+}
+
+// recipePage gives the saved copy of a recipe's page, or fetches and saves it
+// when none was kept
+func recipePage(ctx context.Context, snapshots SnapshotService, fetcher *fetch.Fetcher, recipeID int, recipeURL string) (pageURL string, page []byte, err error) {
+	snapshot, err := snapshots.GetSnapshot(ctx, recipeID)
+	if err == nil {
+		return snapshot.URL, snapshot.HTML, nil
+	}
+	if !errors.Is(err, ErrNoSnapshot) {
+		slog.Warn("Could not read saved recipe page, fetching it instead", "recipeID", recipeID, "error", err)
+	}
+
+	fetched, err := fetcher.Page(ctx, recipeURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("no saved copy and fetching failed: %w", err)
+	}
+	if err := snapshots.SaveSnapshot(ctx, recipeID, fetched.URL, fetched.HTML); err != nil {
+		slog.Warn("Could not save recipe page", "recipeID", recipeID, "error", err)
+	}
+	return fetched.URL, fetched.HTML, nil
+}
+
+// snapshotKey names a saved page, or its text, by the page's hash
+func snapshotKey(hash, ext string) string {
+	return snapshotPrefix + hash + "." + ext + ".gz"
+}
+
+func (s *Snapshot) putCompressed(ctx context.Context, key string, data []byte) error {
+	var buf bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := gz.Write(data); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return s.store.Put(ctx, key, &buf)
+}
+
+func (s *Snapshot) getCompressed(ctx context.Context, key string) ([]byte, error) {
+	r, _, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	data, err := io.ReadAll(io.LimitReader(gz, maxSnapshotBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSnapshotBytes {
+		return nil, fmt.Errorf("saved page is larger than %d bytes", maxSnapshotBytes)
+	}
+	return data, nil
+}
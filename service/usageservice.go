@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"recipeze/model"
+	"recipeze/repo"
+	"recipeze/usage"
+)
+
+// recentLLMCalls is how many of a group's latest LLM calls its usage page lists
+const recentLLMCalls = 50
+
+type Usage struct {
+	queries *repo.Queries
+	budget  usage.Budget
+}
+
+// NewUsageService creates the usage service, reporting spend against budget
+func NewUsageService(queries *repo.Queries, budget usage.Budget) *Usage {
+	return &Usage{
+		queries: queries,
+		budget:  budget,
+	}
+}
+
+type UsageService interface {
+	// GetGroupUsage gives what a group has spent on the LLM this month, by
+	// user, and its latest calls
+	GetGroupUsage(ctx context.Context, groupID int) (*model.LLMUsage, error)
+}
+
+func (u *Usage) GetGroupUsage(ctx context.Context, groupID int) (*model.LLMUsage, error) {
+	now := time.Now()
+	monthStart := usage.MonthStart(now)
+	group := pgtype.Int4{Int32: int32(groupID), Valid: true}
+
+	spend, err := u.queries.GetLLMSpend(ctx, repo.GetLLMSpendParams{
+		GroupID:    group,
+		DayStart:   timestampPG(usage.DayStart(now)),
+		MonthStart: timestampPG(monthStart),
+	})
+	if err != nil {
+		return nil, err
+	}
+	byUser, err := u.queries.GetGroupLLMUsageByUser(ctx, repo.GetGroupLLMUsageByUserParams{
+		GroupID:   group,
+		CreatedAt: timestampPG(monthStart),
+	})
+	if err != nil {
+		return nil, err
+	}
+	recent, err := u.queries.GetRecentGroupLLMUsage(ctx, repo.GetRecentGroupLLMUsageParams{
+		GroupID: group,
+		Limit:   recentLLMCalls,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.LLMUsage{
+		Since:  monthStart,
+		Today:  spend.GroupDay,
+		Month:  spend.GroupMonth,
+		Budget: model.LLMBudget(u.budget),
+	}
+	for _, row := range byUser {
+		report.Members = append(report.Members, model.MemberLLMUsage{
+			Name:         row.UserName,
+			Calls:        int(row.Calls),
+			InputTokens:  row.InputTokens,
+			OutputTokens: row.OutputTokens,
+			Cost:         row.CostMicros,
+		})
+	}
+	for _, row := range recent {
+		report.Recent = append(report.Recent, model.LLMCall{
+			Model:        row.Model,
+			RecipeID:     int(row.RecipeID.Int32),
+			RecipeName:   row.RecipeName,
+			UserName:     row.UserName,
+			InputTokens:  int(row.InputTokens),
+			OutputTokens: int(row.OutputTokens),
+			Latency:      time.Duration(row.LatencyMs) * time.Millisecond,
+			Cost:         row.CostMicros,
+			Succeeded:    row.Succeeded,
+			At:           row.CreatedAt.Time,
+		})
+	}
+	return report, nil
+}
+
+func timestampPG(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
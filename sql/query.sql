@@ -14,12 +14,43 @@ RETURNING id;
 -- name: UpdateRecipeWithJSON :exec
 UPDATE recipes 
 SET 
-    data_json = $1
-WHERE id = $2;
+    data_json = sqlc.arg(data_json),
+    data_source = sqlc.arg(data_source),
+    prep_time_seconds = sqlc.arg(prep_time_seconds),
+    cook_time_seconds = sqlc.arg(cook_time_seconds),
+    total_time_seconds = sqlc.arg(total_time_seconds),
+    parser_version = sqlc.arg(parser_version),
+    prompt_version = sqlc.arg(prompt_version),
+    image_url = COALESCE(NULLIF(image_url, ''), sqlc.narg(image_url)),
+    extraction_status = 'done',
+    extraction_error = NULL
+WHERE id = sqlc.arg(id);
+
+-- name: SetRecipeExtractionFailed :exec
+UPDATE recipes
+SET
+    extraction_status = 'failed',
+    extraction_error = $1,
+    parser_version = $2,
+    prompt_version = $3
+WHERE id = $4;
+
+-- name: SetRecipeImage :exec
+UPDATE recipes
+SET
+    image_key = $1,
+    thumbnail_key = $2
+WHERE id = $3;
 
 -- name: GetGroupRecipes :many 
 SELECT * FROM recipes where group_id = $1;
 
+-- name: GetGroupRecipesByTotalTime :many
+SELECT * FROM recipes
+WHERE group_id = sqlc.arg(group_id)
+AND (sqlc.arg(max_seconds)::int = 0 OR total_time_seconds <= sqlc.arg(max_seconds)::int)
+ORDER BY total_time_seconds ASC NULLS LAST, id;
+
 -- name: GetUserRecipes :many
 SELECT * FROM recipes where created_by = $1;
 
@@ -29,6 +60,80 @@ SELECT * from recipes WHERE id = $1 LIMIT 1;
 -- name: DeleteRecipeByID :exec
 DELETE FROM recipes where id = $1;
 
+-- name: GetRecipesToReprocess :many
+SELECT * FROM recipes
+WHERE url IS NOT NULL AND url <> ''
+AND COALESCE(data_source, '') NOT IN ('manual', 'import')
+AND (sqlc.arg(group_id)::int = 0 OR group_id = sqlc.arg(group_id)::int)
+AND (sqlc.arg(recipe_id)::int = 0 OR id = sqlc.arg(recipe_id)::int)
+AND (
+    sqlc.arg(all_versions)::bool
+    OR parser_version < sqlc.arg(parser_version)::int
+    OR ((data_source = 'llm' OR extraction_status = 'failed') AND prompt_version < sqlc.arg(prompt_version)::int)
+)
+ORDER BY id;
+
+-- name: SaveRecipeDataHistory :exec
+INSERT INTO recipe_data_history (
+    recipe_id,
+    data_json,
+    data_source,
+    prep_time_seconds,
+    cook_time_seconds,
+    total_time_seconds,
+    parser_version,
+    prompt_version
+)
+SELECT id, data_json, data_source, prep_time_seconds, cook_time_seconds, total_time_seconds, parser_version, prompt_version
+FROM recipes
+WHERE id = $1 AND data_json IS NOT NULL;
+
+-- name: GetLatestRecipeDataHistory :one
+SELECT * FROM recipe_data_history
+WHERE recipe_id = $1
+ORDER BY replaced_at DESC, id DESC
+LIMIT 1;
+
+-- name: GetRecipesWithDataHistory :many
+SELECT DISTINCT r.id FROM recipes r
+JOIN recipe_data_history h ON h.recipe_id = r.id
+WHERE (sqlc.arg(group_id)::int = 0 OR r.group_id = sqlc.arg(group_id)::int)
+AND (sqlc.arg(recipe_id)::int = 0 OR r.id = sqlc.arg(recipe_id)::int)
+ORDER BY r.id;
+
+-- name: RestoreRecipeData :exec
+UPDATE recipes
+SET
+    data_json = $1,
+    data_source = $2,
+    prep_time_seconds = $3,
+    cook_time_seconds = $4,
+    total_time_seconds = $5,
+    parser_version = $6,
+    prompt_version = $7,
+    extraction_status = 'done',
+    extraction_error = NULL
+WHERE id = $8;
+
+-- name: DeleteRecipeDataHistory :exec
+DELETE FROM recipe_data_history WHERE id = $1;
+
+-- name: CreateRecipeSnapshot :exec
+INSERT INTO recipe_snapshots (
+    recipe_id,
+    page_url,
+    content_hash,
+    html_size
+) VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: GetLatestRecipeSnapshot :one
+SELECT * FROM recipe_snapshots
+WHERE recipe_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT 1;
+
 -- name: UpdateRecipe :exec
 UPDATE recipes 
 SET 
@@ -56,9 +161,10 @@ RETURNING *;
 -- name: AddUserToGroup :exec
 INSERT INTO group_users (
     group_id,
-    user_id
+    user_id,
+    is_admin
 ) VALUES (
-    $1, $2
+    $1, $2, $3
 );
 
 -- name: GetGroupUsers :many
@@ -76,6 +182,16 @@ WHERE group_users.group_id = $1;
 -- name: IsUserInGroup :one
 SELECT id from group_users WHERE group_id = $1 AND user_id = $2 LIMIT 1;
 
+-- name: IsUserGroupAdmin :one
+SELECT is_admin FROM group_users WHERE group_id = $1 AND user_id = $2 LIMIT 1;
+
+-- name: GetGroupMembers :many
+SELECT u.id, u.name, u.email, gu.is_admin
+FROM users u
+JOIN group_users gu ON u.id = gu.user_id
+WHERE gu.group_id = $1
+ORDER BY gu.id;
+
 -- name: IsUserAccountSetupComplete :one
 select setup_account from users where id = $1;
 
@@ -92,6 +208,11 @@ SET
     name = $2
 WHERE id = $3;
 
+-- name: UpdateUserUnitSystem :exec
+UPDATE users
+SET unit_system = $1
+WHERE id = $2;
+
 -- name: CreateRegistrationToken :exec
 INSERT INTO registration_tokens (
     token,
@@ -120,4 +241,187 @@ INSERT INTO login_tokens (
 RETURNING *;
 
 -- name: GetLoginToken :one
-SELECT * FROM login_tokens WHERE token = $1 LIMIT 1;
\ No newline at end of file
+SELECT * FROM login_tokens WHERE token = $1 LIMIT 1;
+
+-- name: SetRecipeExtractionStatus :exec
+UPDATE recipes
+SET
+    extraction_status = $1,
+    extraction_error = $2
+WHERE id = $3;
+
+-- name: EnqueueJob :exec
+INSERT INTO jobs (
+    kind,
+    dedupe_key,
+    payload,
+    max_attempts
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (dedupe_key) WHERE state IN ('queued', 'running') DO NOTHING;
+
+-- name: ClaimJob :one
+UPDATE jobs
+SET
+    state = 'running',
+    attempts = attempts + 1,
+    leased_until = now() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+WHERE id = (
+    SELECT id FROM jobs
+    WHERE kind = ANY(sqlc.arg(kinds)::text[])
+    AND (
+        (state = 'queued' AND run_at <= now())
+        OR (state = 'running' AND leased_until < now() AND attempts < max_attempts)
+    )
+    ORDER BY run_at, id
+    FOR UPDATE SKIP LOCKED
+    LIMIT 1
+)
+RETURNING *;
+
+-- name: BuryAbandonedJobs :execrows
+UPDATE jobs
+SET
+    state = 'dead',
+    leased_until = NULL,
+    last_error = 'the worker stopped during the last attempt',
+    finished_at = now()
+WHERE state = 'running' AND leased_until < now() AND attempts >= max_attempts;
+
+-- name: ExtendJobLease :one
+UPDATE jobs
+SET leased_until = now() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+WHERE id = sqlc.arg(id) AND state = 'running' AND leased_until = sqlc.arg(leased_until)
+RETURNING leased_until;
+
+-- name: CompleteJob :execrows
+UPDATE jobs
+SET
+    state = 'done',
+    leased_until = NULL,
+    finished_at = now()
+WHERE id = sqlc.arg(id) AND state = 'running' AND leased_until = sqlc.arg(leased_until);
+
+-- name: RetryJob :execrows
+UPDATE jobs
+SET
+    state = 'queued',
+    leased_until = NULL,
+    last_error = sqlc.arg(last_error),
+    run_at = now() + make_interval(secs => sqlc.arg(delay_seconds)::int)
+WHERE id = sqlc.arg(id) AND state = 'running' AND leased_until = sqlc.arg(leased_until);
+
+-- name: DelayJob :execrows
+UPDATE jobs
+SET
+    state = 'queued',
+    attempts = GREATEST(attempts - 1, 0),
+    leased_until = NULL,
+    last_error = sqlc.arg(last_error),
+    run_at = sqlc.arg(run_at)
+WHERE id = sqlc.arg(id) AND state = 'running' AND leased_until = sqlc.arg(leased_until);
+
+-- name: BuryJob :execrows
+UPDATE jobs
+SET
+    state = 'dead',
+    leased_until = NULL,
+    last_error = sqlc.arg(last_error),
+    finished_at = now()
+WHERE id = sqlc.arg(id) AND state = 'running' AND leased_until = sqlc.arg(leased_until);
+
+-- name: ReleaseJob :execrows
+UPDATE jobs
+SET
+    state = 'queued',
+    attempts = GREATEST(attempts - 1, 0),
+    leased_until = NULL
+WHERE id = sqlc.arg(id) AND state = 'running' AND leased_until = sqlc.arg(leased_until);
+
+-- name: RecordLLMUsage :exec
+INSERT INTO llm_usage (
+    group_id,
+    user_id,
+    recipe_id,
+    model,
+    input_tokens,
+    output_tokens,
+    latency_ms,
+    cost_micros,
+    succeeded
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+);
+
+-- name: GetLLMSpend :one
+SELECT
+    COALESCE(SUM(cost_micros) FILTER (WHERE group_id = sqlc.arg(group_id) AND created_at >= sqlc.arg(day_start)), 0)::BIGINT AS group_day,
+    COALESCE(SUM(cost_micros) FILTER (WHERE group_id = sqlc.arg(group_id)), 0)::BIGINT AS group_month,
+    COALESCE(SUM(cost_micros) FILTER (WHERE user_id = sqlc.arg(user_id) AND created_at >= sqlc.arg(day_start)), 0)::BIGINT AS user_day,
+    COALESCE(SUM(cost_micros) FILTER (WHERE user_id = sqlc.arg(user_id)), 0)::BIGINT AS user_month
+FROM llm_usage
+WHERE created_at >= sqlc.arg(month_start)
+AND (group_id = sqlc.arg(group_id) OR user_id = sqlc.arg(user_id));
+
+-- name: GetGroupLLMUsageByUser :many
+SELECT
+    l.user_id,
+    COALESCE(u.name, u.email, '')::TEXT AS user_name,
+    COUNT(*)::INT AS calls,
+    COALESCE(SUM(l.input_tokens), 0)::BIGINT AS input_tokens,
+    COALESCE(SUM(l.output_tokens), 0)::BIGINT AS output_tokens,
+    COALESCE(SUM(l.cost_micros), 0)::BIGINT AS cost_micros
+FROM llm_usage l
+LEFT JOIN users u ON u.id = l.user_id
+WHERE l.group_id = $1 AND l.created_at >= $2
+GROUP BY l.user_id, u.name, u.email
+ORDER BY cost_micros DESC;
+
+-- name: GetRecentGroupLLMUsage :many
+SELECT
+    l.id,
+    l.model,
+    l.input_tokens,
+    l.output_tokens,
+    l.latency_ms,
+    l.cost_micros,
+    l.succeeded,
+    l.created_at,
+    l.recipe_id,
+    COALESCE(r.name, '')::TEXT AS recipe_name,
+    COALESCE(u.name, u.email, '')::TEXT AS user_name
+FROM llm_usage l
+LEFT JOIN recipes r ON r.id = l.recipe_id
+LEFT JOIN users u ON u.id = l.user_id
+WHERE l.group_id = $1
+ORDER BY l.created_at DESC, l.id DESC
+LIMIT $2;
+
+-- name: GetCachedExtraction :one
+UPDATE extraction_cache
+SET hits = hits + 1
+WHERE key = $1 AND expires_at > now()
+RETURNING data_json;
+
+-- name: CacheExtraction :exec
+INSERT INTO extraction_cache (
+    key,
+    model,
+    prompt_version,
+    data_json,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (key) DO UPDATE SET
+    data_json = EXCLUDED.data_json,
+    hits = 0,
+    created_at = CURRENT_TIMESTAMP,
+    expires_at = EXCLUDED.expires_at;
+
+-- name: DeleteExpiredExtractions :execrows
+DELETE FROM extraction_cache WHERE expires_at <= now();
+
+-- name: DeleteAllExtractions :execrows
+DELETE FROM extraction_cache;
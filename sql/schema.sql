@@ -4,7 +4,8 @@ CREATE TABLE users (
     name VARCHAR(128),
     image_url VARCHAR(255),
     setup_account BOOLEAN DEFAULT FALSE,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    unit_system VARCHAR(16) NOT NULL DEFAULT ''
 );
 
 CREATE TABLE groups (
@@ -17,6 +18,7 @@ CREATE TABLE group_users (
     id SERIAL PRIMARY KEY,
     group_id INT NOT NULL,
     user_id INT NOT NULL,
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE, -- Can see the group's LLM usage
     CONSTRAINT fk_group FOREIGN KEY (group_id)
     REFERENCES groups(id) ON DELETE CASCADE,
     CONSTRAINT fk_user FOREIGN KEY (user_id)
@@ -34,12 +36,53 @@ CREATE TABLE recipes (
     image_url VARCHAR(255),
     likes INT DEFAULT 0,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    data_source VARCHAR(32),
+    prep_time_seconds INT,
+    cook_time_seconds INT,
+    total_time_seconds INT,
+    extraction_status VARCHAR(16) NOT NULL DEFAULT 'pending', -- pending, running, done or failed
+    extraction_error TEXT,
+    image_key VARCHAR(128), -- Cached copy of the photo in the blob store
+    thumbnail_key VARCHAR(128),
+    parser_version INT NOT NULL DEFAULT 0, -- parsing.ParserVersion that produced data_json
+    prompt_version INT NOT NULL DEFAULT 0, -- parsing.PromptVersion, when the LLM was asked
     CONSTRAINT fk_creator FOREIGN KEY (created_by)
     REFERENCES users(id) ON DELETE CASCADE,
     CONSTRAINT fk_group FOREIGN KEY (group_id)
     REFERENCES groups(id) ON DELETE CASCADE
 );
 
+-- Data a recipe had before it was re-extracted, so a regression can be rolled back
+CREATE TABLE recipe_data_history (
+    id SERIAL PRIMARY KEY,
+    recipe_id INT NOT NULL,
+    data_json BYTEA,
+    data_source VARCHAR(32),
+    prep_time_seconds INT,
+    cook_time_seconds INT,
+    total_time_seconds INT,
+    parser_version INT NOT NULL,
+    prompt_version INT NOT NULL,
+    replaced_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_recipe FOREIGN KEY (recipe_id)
+    REFERENCES recipes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX recipe_data_history_recipe_id ON recipe_data_history (recipe_id);
+
+CREATE TABLE recipe_snapshots (
+    id SERIAL PRIMARY KEY,
+    recipe_id INT NOT NULL,
+    page_url TEXT NOT NULL, -- Where the page ended up, after redirects
+    content_hash CHAR(64) NOT NULL, -- SHA-256 of the page, which names its blobs
+    html_size INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_recipe FOREIGN KEY (recipe_id)
+    REFERENCES recipes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX recipe_snapshots_recipe_id ON recipe_snapshots (recipe_id);
+
 CREATE TABLE registration_tokens (
     id SERIAL PRIMARY KEY,
     token TEXT NOT NULL UNIQUE,
@@ -60,4 +103,62 @@ CREATE TABLE login_tokens (
     creator_ip VARCHAR(45),
     CONSTRAINT fk_user FOREIGN KEY (user_id)
     REFERENCES users(id) ON DELETE CASCADE
-);
\ No newline at end of file
+);
+
+-- Background work, see package jobs
+CREATE TABLE jobs (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(64) NOT NULL,
+    dedupe_key VARCHAR(128), -- At most one queued or running job has the same key
+    payload JSONB NOT NULL DEFAULT '{}',
+    state VARCHAR(16) NOT NULL DEFAULT 'queued', -- queued, running, done or dead
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 3,
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    leased_until TIMESTAMP WITH TIME ZONE, -- A running job past its lease is picked up again
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX jobs_ready ON jobs (run_at) WHERE state IN ('queued', 'running');
+CREATE UNIQUE INDEX jobs_active_dedupe_key ON jobs (dedupe_key) WHERE state IN ('queued', 'running');
+
+-- One row per call to the LLM, for budgets and the usage page. Calls outlive
+-- the recipe and user they were made for.
+CREATE TABLE llm_usage (
+    id BIGSERIAL PRIMARY KEY,
+    group_id INT,
+    user_id INT, -- Who added the recipe
+    recipe_id INT,
+    model VARCHAR(128) NOT NULL,
+    input_tokens INT NOT NULL,
+    output_tokens INT NOT NULL,
+    latency_ms INT NOT NULL,
+    cost_micros BIGINT NOT NULL, -- Estimated cost in millionths of a dollar
+    succeeded BOOLEAN NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_group FOREIGN KEY (group_id)
+    REFERENCES groups(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT fk_recipe FOREIGN KEY (recipe_id)
+    REFERENCES recipes(id) ON DELETE SET NULL
+);
+
+CREATE INDEX llm_usage_group_created ON llm_usage (group_id, created_at);
+CREATE INDEX llm_usage_user_created ON llm_usage (user_id, created_at);
+
+-- What the LLM read from a page's text, shared by every group that saves the
+-- same page, see parsing.ExtractionCache
+CREATE TABLE extraction_cache (
+    key VARCHAR(64) PRIMARY KEY, -- parsing.CacheKey of the text, model and prompt version
+    model VARCHAR(128) NOT NULL,
+    prompt_version INT NOT NULL,
+    data_json BYTEA NOT NULL,
+    hits INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX extraction_cache_expires_at ON extraction_cache (expires_at);
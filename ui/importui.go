@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"fmt"
+
+	. "maragu.dev/gomponents"
+	hx "maragu.dev/gomponents-htmx"
+	. "maragu.dev/gomponents/html"
+
+	"recipeze/model"
+)
+
+// MaxImportBytes is the largest export that can be uploaded, big enough for a
+// few thousand recipes with photos
+const MaxImportBytes = 100 << 20
+
+// ImportResult is how one recipe from an export was added
+type ImportResult struct {
+	Name     string
+	RecipeID int    // Zero when it could not be added
+	Error    string // Why it could not be added
+	Problems []string
+}
+
+// importForm uploads an export from another recipe manager into one of the
+// user's groups
+func importForm(groupID int, groups []model.Group) Node {
+	return Form(
+		hx.Post(fmt.Sprintf("/g/%d/recipes/import", groupID)),
+		hx.Target("#recipe-detail"),
+		hx.Swap("innerHTML"),
+		hx.Encoding("multipart/form-data"),
+		Attr("hx-on::after-request", "document.querySelector('#modal-container').innerHTML = ''"),
+
+		Div(
+			Class("mb-4"),
+			Label(Class("block text-sm font-medium text-gray-700"), For("import-file"), Text("Export file")),
+			Input(Type("file"), ID("import-file"), Name("file"), Required(),
+				Accept(".paprikarecipes,.paprikarecipe,.zip,.json,.mmf,.mxp,.txt,.html,.cook"),
+				Class("mt-1 block w-full text-sm")),
+			P(Class("mt-1 text-xs text-gray-500"), Text("From Paprika, Mealie, Tandoor, MealMaster or Recipe Keeper, or Cooklang files: one .cook file or a zipped folder of them.")),
+		),
+		If(len(groups) > 1,
+			Div(
+				Class("mb-4"),
+				Label(Class("block text-sm font-medium text-gray-700"), For("import-group"), Text("Add to group")),
+				Select(ID("import-group"), Name("group"), Class("mt-1 block w-full rounded-md border-gray-300 shadow-sm"),
+					Map(groups, func(group model.Group) Node {
+						return Option(Value(fmt.Sprint(group.ID)), If(group.ID == groupID, Selected()), Text(group.Name))
+					}),
+				),
+			),
+		),
+		recipeModalButtons("Import Recipes"),
+	)
+}
+
+// ImportReportPartial lists each recipe from an export and what could not be
+// carried over from it
+func ImportReportPartial(groupID int, format string, results []ImportResult) Node {
+	var added, withProblems int
+	for _, result := range results {
+		if result.RecipeID != 0 {
+			added++
+		}
+		if result.RecipeID != 0 && len(result.Problems) > 0 {
+			withProblems++
+		}
+	}
+
+	return Div(
+		H2(Class("text-xl font-bold mb-2"), Text("Import from "+format)),
+		P(Class("text-sm text-gray-600 mb-4"),
+			Text(fmt.Sprintf("Added %d of %d recipes.", added, len(results))),
+			If(withProblems > 0, Text(fmt.Sprintf(" %d need a look, see below.", withProblems))),
+		),
+		Ul(Class("divide-y divide-gray-200"),
+			Map(results, func(result ImportResult) Node {
+				return importResultItem(groupID, result)
+			}),
+		),
+	)
+}
+
+func importResultItem(groupID int, result ImportResult) Node {
+	status, class := "Added", "text-green-700"
+	switch {
+	case result.RecipeID == 0:
+		status, class = "Not added", "text-red-700"
+	case len(result.Problems) > 0:
+		status, class = "Added, check it", "text-yellow-700"
+	}
+	name := Node(Span(Class("font-medium"), Text(result.Name)))
+	if result.RecipeID != 0 {
+		name = A(
+			Class("font-medium text-blue-600 hover:underline cursor-pointer"),
+			hx.Get(fmt.Sprintf("/g/%d/recipe/%d", groupID, result.RecipeID)),
+			hx.Target("#recipe-detail"),
+			Text(result.Name),
+		)
+	}
+	return Li(Class("py-2"),
+		Div(Class("flex justify-between"),
+			name,
+			Span(Class("text-sm "+class), Text(status)),
+		),
+		If(result.Error != "", P(Class("text-sm text-red-700"), Text(result.Error))),
+		If(len(result.Problems) > 0,
+			Ul(Class("list-disc ml-5 text-sm text-gray-600"),
+				Map(result.Problems, func(problem string) Node { return Li(Text(problem)) }),
+			),
+		),
+	)
+}
@@ -2,18 +2,28 @@ package ui
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	. "maragu.dev/gomponents"
 	"maragu.dev/gomponents-heroicons/v3/solid"
 	hx "maragu.dev/gomponents-htmx"
 	. "maragu.dev/gomponents/html"
 
+	"recipeze/export"
 	"recipeze/model"
 	"recipeze/parsing"
 )
 
+// DetailOptions changes how a recipe is presented without changing its data
+type DetailOptions struct {
+	Units    parsing.UnitSystem
+	Servings int // Scale ingredients to this many servings, 0 keeps the recipe's own
+}
+
 // RecipePage shows the main recipe listing with a detail view
-func RecipePage(props PageProps, recipes []model.Recipe, group model.Group) Node {
+func RecipePage(props PageProps, recipes []model.Recipe, group model.Group, opts DetailOptions) Node {
 	defaultId := 0
 	var defaultRecipe *model.Recipe
 	if len(recipes) > 0 {
@@ -49,10 +59,16 @@ func RecipePage(props PageProps, recipes []model.Recipe, group model.Group) Node
 			),
 		),
 
+		// app.js keeps the columns up to date with the group's recipe events
 		Div(Class("flex flex-col md:flex-row gap-6"),
+			Data("events", fmt.Sprintf("/g/%d/events", group.ID)),
+			Data("group", fmt.Sprint(group.ID)),
 			// Left column - Recipe List
 			Div(Class("w-full md:w-1/3"),
-				H1(Class("text-2xl font-bold mb-4"), Text("Recipes")),
+				Div(Class("flex items-center justify-between mb-4"),
+					H1(Class("text-2xl font-bold"), Text("Recipes")),
+					recipeTimeFilter(group.ID),
+				),
 				Div(ID("recipe-list"),
 					RecipeListPartial(recipes, defaultId, group.ID),
 				),
@@ -60,7 +76,7 @@ func RecipePage(props PageProps, recipes []model.Recipe, group model.Group) Node
 			// Right column - Recipe Detail
 			Div(Class("w-full md:w-2/3 bg-gray-50 p-4 rounded-lg"),
 				Div(ID("recipe-detail"),
-					RecipeDetailPartial(defaultRecipe, group.ID),
+					RecipeDetailPartial(defaultRecipe, group.ID, opts),
 				),
 			),
 		),
@@ -99,29 +115,352 @@ func RecipeListItemPartial(recipe *model.Recipe, selectedID int, groupID int) No
 			hx.Target("#recipe-detail"),
 			// Add class operations to clear previous selection
 			Attr("hx-on::before-request", "document.querySelectorAll('.active-recipe').forEach(el => { el.classList.remove('bg-blue-100', 'hover:bg-blue-200', 'active-recipe'); el.classList.add('hover:bg-gray-100', 'inactive-recipe'); })"),
-			Text(recipe.Name),
+			Span(Class("flex items-center justify-between gap-2"),
+				Span(Class("flex items-center gap-2"),
+					If(recipe.ThumbKey != "", Img(
+						Src(RecipeImageURL(recipe.ThumbKey)),
+						Alt(""),
+						Width("40"), Height("40"),
+						Class("h-10 w-10 rounded object-cover shrink-0"),
+						Loading("lazy"),
+					)),
+					Text(recipe.Name),
+				),
+				If(recipe.TotalTime > 0,
+					Span(Class("text-xs text-gray-500 whitespace-nowrap"), Text(recipe.TotalTime.String())),
+				),
+			),
+		),
+	)
+}
+
+// recipeTimeFilter narrows the recipe list to recipes that fit in the time available
+func recipeTimeFilter(groupID int) Node {
+	return Select(
+		Name("max_time"),
+		Class("text-sm rounded-md border-gray-300 bg-white py-1 px-2"),
+		Attr("aria-label", "Filter recipes by total time"),
+		hx.Get(fmt.Sprintf("/g/%d/recipes", groupID)),
+		hx.Target("#recipe-list"),
+		hx.Trigger("change"),
+		Option(Value(""), Text("All recipes")),
+		Option(Value("0"), Text("Quickest first")),
+		Option(Value("30"), Text("Under 30 min")),
+		Option(Value("45"), Text("Under 45 min")),
+		Option(Value("60"), Text("Under 1 hr")),
+	)
+}
+
+// extractionStatus tells the user when the recipe data is missing and why.
+// Recipes added by link can be read again when they failed.
+func extractionStatus(recipe *model.Recipe, groupID int) Node {
+	switch recipe.Status {
+	case model.ExtractionPending, model.ExtractionRunning:
+		if recipe.StatusError != "" {
+			// Put off, such as until the group's LLM budget resets
+			return P(Class("text-sm text-gray-500 italic"), Text("Waiting to read the recipe: "+recipe.StatusError+"."))
+		}
+		return P(Class("text-sm text-gray-500 italic"), Text("Reading the recipe, this can take a moment..."))
+	case model.ExtractionFailed:
+		return Div(Class("flex flex-wrap items-center gap-2"),
+			ErrorPartial("We could not read the ingredients from this recipe's page."),
+			If(recipe.Url != "", Button(
+				Class("inline-flex items-center px-3 py-1 border border-gray-300 rounded-md text-sm text-gray-700 bg-white hover:bg-gray-50 cursor-pointer"),
+				hx.Post(fmt.Sprintf("/g/%d/recipe/%d/retry", groupID, recipe.ID)),
+				hx.Target("#recipe-detail"),
+				Text("Retry"),
+			)),
+		)
+	default:
+		return nil
+	}
+}
+
+// extractionPolling reloads the recipe details every few seconds while the
+// recipe is still being read, so the ingredients show up once they are ready
+func extractionPolling(recipe *model.Recipe, groupID int) Node {
+	if recipe.Status != model.ExtractionPending && recipe.Status != model.ExtractionRunning {
+		return nil
+	}
+	return Div(
+		hx.Get(fmt.Sprintf("/g/%d/recipe/%d", groupID, recipe.ID)),
+		hx.Trigger("load delay:3s"),
+		hx.Target("#recipe-detail"),
+	)
+}
+
+// recipeTimes shows the prep, cook and total time when they are known
+func recipeTimes(recipe *model.Recipe) Node {
+	var parts []Node
+	for _, t := range []struct {
+		label    string
+		duration parsing.Duration
+	}{
+		{"Prep", recipe.PrepTime},
+		{"Cook", recipe.CookTime},
+		{"Total", recipe.TotalTime},
+	} {
+		if t.duration <= 0 {
+			continue
+		}
+		parts = append(parts, Span(
+			Span(Class("font-medium"), Text(t.label+" ")),
+			Text(t.duration.String()),
+		))
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return Div(Class("flex flex-wrap gap-4 text-sm text-gray-700 mb-4"), Group(parts))
+}
+
+// unitSelector switches between the units the recipe was written in and the user's preferred system
+func unitSelector(recipe *model.Recipe, groupID int, opts DetailOptions) Node {
+	current := opts.Units
+	var buttons []Node
+	for _, o := range []struct {
+		label string
+		units parsing.UnitSystem
+	}{
+		{"As written", parsing.UnitsOriginal},
+		{"Metric", parsing.UnitsMetric},
+		{"US", parsing.UnitsUS},
+	} {
+		class := "px-2 py-1 cursor-pointer bg-white hover:bg-gray-100"
+		if o.units == current {
+			class = "px-2 py-1 cursor-pointer bg-blue-500 text-white"
+		}
+		buttons = append(buttons, Button(
+			Class(class),
+			hx.Post(fmt.Sprintf("/g/%d/recipe/%d/units", groupID, recipe.ID)),
+			hx.Vals(fmt.Sprintf(`{"units":%q,"servings":%d}`, o.units, opts.Servings)),
+			hx.Target("#recipe-detail"),
+			Attr("aria-pressed", fmt.Sprint(o.units == current)),
+			Text(o.label),
+		))
+	}
+	return Div(Class("inline-flex rounded-md border border-gray-300 overflow-hidden text-xs"),
+		Attr("role", "group"),
+		Attr("aria-label", "Units"),
+		Group(buttons),
+	)
+}
+
+// servingsControl scales the ingredient list up or down, when we know how many the recipe serves
+func servingsControl(recipe *model.Recipe, groupID int, servings int) Node {
+	if recipe.Data == nil || len(recipe.Data.Recipes) == 0 || recipe.Data.Recipes[0].Servings <= 0 {
+		return nil
+	}
+	if servings <= 0 {
+		servings = recipe.Data.Recipes[0].Servings
+	}
+	url := fmt.Sprintf("/g/%d/recipe/%d/scale", groupID, recipe.ID)
+	stepButton := func(label, symbol string, to int) Node {
+		return Button(
+			Class("px-2 py-1 cursor-pointer bg-white hover:bg-gray-100 disabled:opacity-50 disabled:cursor-default"),
+			hx.Get(fmt.Sprintf("%s?servings=%d", url, to)),
+			hx.Target("#recipe-detail"),
+			If(to < 1 || to > MaxServings, Disabled()),
+			Attr("aria-label", label),
+			Text(symbol),
+		)
+	}
+	return Div(Class("inline-flex items-center rounded-md border border-gray-300 overflow-hidden text-xs"),
+		stepButton("Fewer servings", "−", servings-1),
+		Input(
+			Type("number"),
+			Name("servings"),
+			Value(fmt.Sprint(servings)),
+			Min("1"),
+			Max(fmt.Sprint(MaxServings)),
+			Class("w-12 text-center border-x border-gray-300 py-1"),
+			Attr("aria-label", "Servings"),
+			hx.Get(url),
+			hx.Trigger("change"),
+			hx.Target("#recipe-detail"),
 		),
+		stepButton("More servings", "+", servings+1),
+		Span(Class("px-2 text-gray-600"), Text("servings")),
+	)
+}
+
+// MaxServings keeps the servings control to amounts a kitchen can make
+const MaxServings = 100
+
+// recipeIngredients lists the ingredients grouped by grocery aisle, so the list reads like a walk through the store
+func recipeIngredients(data *parsing.RecipeCollection, units parsing.UnitSystem) Node {
+	if data == nil {
+		return nil
+	}
+	var ingredients []parsing.Ingredient
+	for _, recipe := range data.Recipes {
+		ingredients = append(ingredients, recipe.Ingredients...)
+	}
+	return Div(Class("mb-4 space-y-2"),
+		Map(parsing.GroupByAisle(ingredients), func(group parsing.AisleGroup) Node {
+			return Div(
+				H4(Class("text-sm font-semibold text-gray-600 uppercase tracking-wide"), Text(group.Aisle.Label())),
+				Ul(Class("list-disc list-inside break-words"),
+					Map(group.Ingredients, func(ingredient parsing.Ingredient) Node {
+						return Li(Text(parsing.FormatIngredient(parsing.ConvertIngredient(ingredient, units))))
+					}),
+				),
+			)
+		}),
+	)
+}
+
+// recipeInstructions lists the steps, with oven temperatures in the user's units.
+// Sub-recipes like "For the frosting" get their own heading.
+func recipeInstructions(recipe *model.Recipe, units parsing.UnitSystem) Node {
+	if recipe.Data == nil {
+		return nil
+	}
+	var sections []Node
+	for i, part := range recipe.Data.Recipes {
+		if len(part.Instructions) == 0 {
+			continue
+		}
+		media := map[int]parsing.StepMedia{}
+		for _, m := range part.StepMedia {
+			media[m.Step] = m
+		}
+		var steps []Node
+		for j, step := range part.Instructions {
+			steps = append(steps, Li(
+				Text(parsing.ConvertTemperatures(step, units)),
+				stepMedia(media[j]),
+			))
+		}
+		sections = append(sections, Div(
+			If(i > 0 && part.Name != "", H4(Class("font-semibold mt-2"), Text(part.Name))),
+			Ol(Class("list-decimal list-inside space-y-1"), Group(steps)),
+		))
+	}
+	if len(sections) == 0 {
+		return nil
+	}
+	video := recipe.Data.Recipes[0].Video
+	return Div(Class("mb-4"),
+		H3(Class("text-lg font-semibold mb-1 mt-4"), Text("Instructions")),
+		If(video != "", videoLink(video, "Watch the recipe video")),
+		Group(sections),
 	)
 }
 
+// stepMedia shows the video link the page had for a step. Step photos are not
+// shown, loading them from the recipe's site would tell it who is viewing.
+func stepMedia(media parsing.StepMedia) Node {
+	return If(media.Video != "", Div(videoLink(media.Video, "Watch this step")))
+}
+
+// videoLink opens a recipe video on the site that hosts it. Data saved before
+// video URLs were checked may hold other schemes, those are not linked.
+func videoLink(url, label string) Node {
+	url = parsing.WebURL(url)
+	if url == "" {
+		return nil
+	}
+	return A(
+		Attr("href", url),
+		Attr("target", "_blank"),
+		Attr("rel", "noopener noreferrer"),
+		Class("inline-block mb-2 text-blue-600 hover:underline"),
+		Text(label),
+	)
+}
+
+// nutrientRow is one line of the nutrition panel
+type nutrientRow struct {
+	label             string
+	unit              string
+	perServing, total *float64
+}
+
+// nutritionPanel shows the nutrition of one serving, and of all the servings
+// the ingredients are scaled to, for keeping track of macros
+func nutritionPanel(data *parsing.RecipeCollection) Node {
+	if data == nil || len(data.Recipes) == 0 || data.Recipes[0].Nutrition.IsEmpty() {
+		return nil
+	}
+	perServing := *data.Recipes[0].Nutrition
+	servings := data.Recipes[0].Servings
+	total := perServing.Times(float64(servings))
+
+	rows := []nutrientRow{
+		{"Calories", "", perServing.Calories, total.Calories},
+		{"Protein", "g", perServing.Protein, total.Protein},
+		{"Fat", "g", perServing.Fat, total.Fat},
+		{"Carbohydrates", "g", perServing.Carbohydrates, total.Carbohydrates},
+		{"Sodium", "mg", perServing.Sodium, total.Sodium},
+	}
+	perServingLabel := "Per serving"
+	if perServing.ServingSize != "" {
+		perServingLabel += " (" + perServing.ServingSize + ")"
+	}
+	return Div(Class("mb-4"),
+		H3(Class("text-lg font-semibold mb-1 mt-4"), Text("Nutrition")),
+		Table(Class("text-sm"),
+			THead(Tr(
+				Th(),
+				Th(Class("px-2 text-right font-normal text-gray-600"), Text(perServingLabel)),
+				If(servings > 0, Th(Class("px-2 text-right font-normal text-gray-600"), Text(fmt.Sprintf("%d servings", servings)))),
+			)),
+			TBody(Map(rows, func(row nutrientRow) Node {
+				if row.perServing == nil {
+					return nil
+				}
+				return Tr(
+					Td(Class("pr-2"), Text(row.label)),
+					Td(Class("px-2 text-right"), Text(formatNutrient(*row.perServing, row.unit))),
+					If(servings > 0, Td(Class("px-2 text-right"), Text(formatNutrient(*row.total, row.unit)))),
+				)
+			})),
+		),
+	)
+}
+
+// formatNutrient rounds to what a nutrition label shows, like "240" or "3.5 g"
+func formatNutrient(amount float64, unit string) string {
+	if amount < 10 && unit == "g" {
+		amount = math.Round(amount*10) / 10
+	} else {
+		amount = math.Round(amount)
+	}
+	return strings.TrimSpace(strconv.FormatFloat(amount, 'f', -1, 64) + " " + unit)
+}
+
 // RecipeDetailPartial shows the details for a selected recipe
-func RecipeDetailPartial(recipe *model.Recipe, groupID int) Node {
+func RecipeDetailPartial(recipe *model.Recipe, groupID int, opts DetailOptions) Node {
 	if recipe == nil {
 		return nil
 	}
+	data := recipe.Data
+	if opts.Servings > 0 {
+		data = data.Scale(opts.Servings)
+	}
 	return Div(
+		// Lets app.js refresh the view when the recipe changes
+		Data("recipe-id", fmt.Sprint(recipe.ID)),
 		H2(Class("text-xl font-bold mb-4"), Text(recipe.Name)), // title
+		recipeTimes(recipe),
 
 		// Button container - flex row to make buttons appear horizontally
 		Div(Class("flex flex-row gap-4 mb-6"),
-			// View Original Recipe Link
-			A(
+			// View Original Recipe Link, pasted recipes have none
+			If(recipe.Url != "", A(
 				Attr("href", recipe.Url),
 				Attr("target", "_blank"),
 				Attr("rel", "noopener noreferrer"),
 				Class("inline-flex items-center px-4 py-2 bg-blue-500 hover:bg-blue-600 text-white font-medium rounded-md transition-colors"),
 				Text("Go to recipe"),
-			),
+			)),
+			// The copy of the page kept when the recipe was added by link
+			If(recipe.Url != "", A(
+				Href(fmt.Sprintf("/g/%d/recipe/%d/snapshot", groupID, recipe.ID)),
+				Class("inline-flex items-center px-4 py-2 bg-blue-500 hover:bg-blue-600 text-white font-medium rounded-md transition-colors"),
+				Text("Saved copy"),
+			)),
 
 			// Edit Button
 			Button(
@@ -146,6 +485,10 @@ func RecipeDetailPartial(recipe *model.Recipe, groupID int) Node {
 					solid.Trash(Class("text-white h-5 w-5")),
 				),
 			),
+
+			exportMenu(func(format export.Format) string {
+				return fmt.Sprintf("/g/%d/recipe/%d/export/%s", groupID, recipe.ID, format)
+			}),
 		),
 
 		H3(Class("text-lg font-semibold mb-1"), Text("Notes")),
@@ -153,19 +496,52 @@ func RecipeDetailPartial(recipe *model.Recipe, groupID int) Node {
 			Class("whitespace-pre-wrap break-words"), // Preserves newlines and breaks long words
 			Text(recipe.Description),
 		),
-		H3(Class("text-lg font-semibold mb-1"), Text("Ingredients")),
-		Div(
-			Class("whitespace-pre-wrap break-words"), // Preserves newlines and breaks long words
-			Text(parsing.RecipeIngredients(recipe.Data)),
+		Div(Class("flex flex-wrap items-center justify-between gap-2 mb-1"),
+			H3(Class("text-lg font-semibold"), Text("Ingredients")),
+			Div(Class("flex items-center gap-2"),
+				servingsControl(recipe, groupID, opts.Servings),
+				unitSelector(recipe, groupID, opts),
+			),
 		),
-		Img(
-			Src(recipe.ImageURL),
+		extractionStatus(recipe, groupID),
+		extractionPolling(recipe, groupID),
+		recipeIngredients(data, opts.Units),
+		recipeInstructions(recipe, opts.Units),
+		nutritionPanel(data),
+		If(recipe.ImageKey != "", Img(
+			Src(RecipeImageURL(recipe.ImageKey)),
+			Alt(recipe.Name),
 			Class("w-full object-cover rounded-lg"),
 			Loading("lazy"),
+		)),
+	)
+}
+
+// exportMenu offers a download in each export format, url gives its link
+func exportMenu(url func(format export.Format) string) Node {
+	return Details(Class("relative"),
+		Summary(
+			Class("list-none inline-flex items-center px-4 py-2 border border-gray-300 rounded-md text-gray-700 bg-white hover:bg-gray-50 cursor-pointer"),
+			Text("Export"),
+		),
+		Div(Class("absolute z-10 mt-1 w-40 rounded-md shadow-lg bg-white ring-1 ring-black ring-opacity-5 py-1"),
+			Map(export.Formats, func(format export.Format) Node {
+				return A(
+					Href(url(format)),
+					Attr("download"),
+					Class("block px-4 py-2 text-sm text-gray-700 hover:bg-gray-100"),
+					Text(format.Label()),
+				)
+			}),
 		),
 	)
 }
 
+// RecipeImageURL is where a cached recipe photo or thumbnail is served from
+func RecipeImageURL(name string) string {
+	return "/images/r/" + name
+}
+
 // RecipeEditPartial shows the details for a selected recipe in an editable form
 func RecipeEditPartial(recipe *model.Recipe, groupID int) Node {
 	return Div(
@@ -329,6 +705,27 @@ func groupDropDownMenu(group *model.Group) Node {
 					),
 				),
 			),
+			Div(Class("border-t border-gray-100 mt-1 pt-1"),
+				Div(Class("px-4 py-2 text-xs text-gray-500"), Text("EXPORT ALL RECIPES")),
+				Map(export.Formats, func(format export.Format) Node {
+					return A(
+						Href(fmt.Sprintf("/g/%d/recipes/export/%s", group.ID, format)),
+						Attr("download"),
+						Class("block px-4 py-2 text-sm text-gray-700 hover:bg-gray-100"),
+						Text(format.Label()),
+					)
+				}),
+			),
+			If(group.IsAdmin, Div(Class("border-t border-gray-100 mt-1 pt-1"),
+				A(
+					Href(fmt.Sprintf("/g/%d/usage", group.ID)),
+					Class("block px-4 py-2 text-sm text-gray-700 hover:bg-gray-100"),
+					Div(Class("flex items-center gap-2"),
+						solid.ChartBar(Class("h-4 w-4 text-gray-400")),
+						Text("LLM usage"),
+					),
+				),
+			)),
 			// Create New Group option
 			Div(Class("border-t border-gray-100 mt-1 pt-1"),
 				Button(
@@ -387,7 +784,93 @@ func groupMembersDisplay(group *model.Group) Node {
 	)
 }
 
-func RecipeModal(group_id int) Node {
+// The ways a recipe can be added in the new recipe modal
+const (
+	RecipeModalLink   = ""
+	RecipeModalPaste  = "paste"
+	RecipeModalPDF    = "pdf"
+	RecipeModalImport = "import"
+)
+
+// RecipeModal adds a recipe from a link to its page, from pasted text for
+// recipes that only live in emails and notes, from a PDF, or from another
+// recipe manager's export. groups are the user's groups, which an export can
+// be imported into.
+func RecipeModal(group_id int, mode string, groups []model.Group) Node {
+	var form Node
+	switch mode {
+	case RecipeModalPaste:
+		form = Form(
+			hx.Post(fmt.Sprintf("/g/%d/recipes/paste", group_id)),
+			hx.Target("#recipe-detail"),
+			hx.Swap("innerHTML"),
+			Attr("hx-on::after-request", "document.querySelector('#modal-container').innerHTML = ''"),
+
+			Div(
+				Class("mb-4"),
+				Label(Class("block text-sm font-medium text-gray-700"), For("recipe-text"), Text("Recipe text")),
+				Textarea(
+					ID("recipe-text"), Name("text"), Required(), Rows("12"),
+					MaxLength(fmt.Sprint(MaxPastedRecipeLength)),
+					Placeholder("Grandma's apple crisp\n\nIngredients\n6 apples, sliced\n1 cup oats\n...\n\nDirections\n1. Preheat the oven to 350°F\n..."),
+					Class("mt-1 block w-full rounded-md border-gray-300 shadow-sm"),
+				),
+			),
+			recipeModalButtons("Read Recipe"),
+		)
+	case RecipeModalPDF:
+		form = Form(
+			hx.Post(fmt.Sprintf("/g/%d/recipes/pdf", group_id)),
+			hx.Target("#recipe-detail"),
+			hx.Swap("innerHTML"),
+			hx.Encoding("multipart/form-data"),
+			Attr("hx-on::after-request", "document.querySelector('#modal-container').innerHTML = ''"),
+
+			Div(
+				Class("mb-4"),
+				Label(Class("block text-sm font-medium text-gray-700"), For("recipe-pdf"), Text("PDF file")),
+				Input(Type("file"), ID("recipe-pdf"), Name("file"), Required(), Accept(".pdf,application/pdf"), Class("mt-1 block w-full text-sm")),
+				P(Class("mt-1 text-xs text-gray-500"), Text(fmt.Sprintf("Up to %d MB. You'll choose which recipes to add.", MaxPDFBytes>>20))),
+			),
+			recipeModalButtons("Read PDF"),
+		)
+	case RecipeModalImport:
+		form = importForm(group_id, groups)
+	default:
+		form = Form(
+			hx.Post(fmt.Sprintf("/g/%d/recipes", group_id)),
+			hx.Target("#recipe-list"),
+			hx.Swap("innerHTML"),
+			//hx.On("after-request", "document.querySelector('#modal-container').innerHTML = ''"),
+			Attr("hx-on::after-request", "document.querySelector('#modal-container').innerHTML = ''"),
+
+			Div(
+				Class("mb-4"),
+				Label(Class("block text-sm font-medium text-gray-700"), For("recipe-url"), Text("Recipe URL")),
+				Input(Type("url"), ID("recipe-url"), Name("url"), Required(), Class("mt-1 block w-full rounded-md border-gray-300 shadow-sm")),
+			),
+			recipeModalButtons("Save Recipe"),
+		)
+	}
+
+	tab := func(label string, active bool, url string) Node {
+		class := "px-3 py-1 cursor-pointer"
+		if active {
+			class += " bg-blue-500 text-white"
+		} else {
+			class += " bg-white hover:bg-gray-100"
+		}
+		return Button(
+			Type("button"),
+			Class(class),
+			hx.Get(url),
+			hx.Target("#modal-container"),
+			hx.Swap("innerHTML"),
+			Attr("aria-pressed", fmt.Sprint(active)),
+			Text(label),
+		)
+	}
+
 	return Div(
 		Class("fixed inset-0 bg-gray-500 bg-opacity-75 flex items-center justify-center z-50"),
 		Div(
@@ -403,36 +886,34 @@ func RecipeModal(group_id int) Node {
 					Text("×"),
 				),
 			),
-			Form(
-				hx.Post(fmt.Sprintf("/g/%d/recipes", group_id)),
-				hx.Target("#recipe-list"),
-				hx.Swap("innerHTML"),
-				//hx.On("after-request", "document.querySelector('#modal-container').innerHTML = ''"),
-				Attr("hx-on::after-request", "document.querySelector('#modal-container').innerHTML = ''"),
-
-				Div(
-					Class("mb-4"),
-					Label(Class("block text-sm font-medium text-gray-700"), For("recipe-url"), Text("Recipe URL")),
-					Input(Type("url"), ID("recipe-url"), Name("url"), Required(), Class("mt-1 block w-full rounded-md border-gray-300 shadow-sm")),
-				),
-
-				Div(
-					Class("mt-6 flex justify-end"),
-					Button(
-						Type("button"),
-						Class("mr-3 bg-gray-200 hover:bg-gray-300 text-gray-800 font-bold py-2 px-4 rounded cursor-pointer"),
-						hx.Get("/empty"),
-						hx.Target("#modal-container"),
-						hx.Swap("innerHTML"),
-						Text("Cancel"),
-					),
-					Button(
-						Type("submit"),
-						Class("bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded cursor-pointer"),
-						Text("Save Recipe"),
-					),
-				),
+			Div(Class("inline-flex rounded-md border border-gray-300 overflow-hidden text-sm mb-4"),
+				Attr("role", "group"),
+				tab("From a link", mode == RecipeModalLink, fmt.Sprintf("/g/%d/recipes/new", group_id)),
+				tab("Paste text", mode == RecipeModalPaste, fmt.Sprintf("/g/%d/recipes/new?mode=paste", group_id)),
+				tab("PDF", mode == RecipeModalPDF, fmt.Sprintf("/g/%d/recipes/new?mode=pdf", group_id)),
+				tab("Import", mode == RecipeModalImport, fmt.Sprintf("/g/%d/recipes/new?mode=import", group_id)),
 			),
+			form,
+		),
+	)
+}
+
+// recipeModalButtons cancels or submits the add recipe form
+func recipeModalButtons(submit string) Node {
+	return Div(
+		Class("mt-6 flex justify-end"),
+		Button(
+			Type("button"),
+			Class("mr-3 bg-gray-200 hover:bg-gray-300 text-gray-800 font-bold py-2 px-4 rounded cursor-pointer"),
+			hx.Get("/empty"),
+			hx.Target("#modal-container"),
+			hx.Swap("innerHTML"),
+			Text("Cancel"),
+		),
+		Button(
+			Type("submit"),
+			Class("bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded cursor-pointer"),
+			Text(submit),
 		),
 	)
 }
@@ -0,0 +1,193 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	. "maragu.dev/gomponents"
+	hx "maragu.dev/gomponents-htmx"
+	. "maragu.dev/gomponents/html"
+
+	"recipeze/model"
+	"recipeze/parsing"
+)
+
+const (
+	// MaxPastedRecipeLength keeps pasted recipes to what fits in a long email
+	MaxPastedRecipeLength = 20000
+	// MaxPDFBytes is the largest PDF that can be uploaded
+	MaxPDFBytes = 20 << 20
+	// MaxPDFRecipes is how many recipes from one PDF are offered to add
+	MaxPDFRecipes = 50
+)
+
+const inputClass = "w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-blue-500 focus:border-blue-500"
+
+// RecipeReviewPartial shows what was read from a pasted recipe in an editor,
+// so it can be corrected before it is saved. While the recipe is still being
+// read it checks back every few seconds.
+func RecipeReviewPartial(recipe *model.Recipe, groupID int) Node {
+	url := fmt.Sprintf("/g/%d/recipes/review/%d", groupID, recipe.ID)
+	if recipe.Status == model.ExtractionPending || recipe.Status == model.ExtractionRunning {
+		return Div(
+			hx.Get(url),
+			hx.Trigger("load delay:2s"),
+			hx.Target("this"),
+			hx.Swap("outerHTML"),
+			H2(Class("text-xl font-bold mb-4"), Text(recipe.Name)),
+			extractionStatus(recipe, groupID),
+		)
+	}
+
+	var servings int
+	var parts []parsing.Recipe
+	if recipe.Data != nil {
+		parts = recipe.Data.Recipes
+		if len(parts) > 0 {
+			servings = parts[0].Servings
+		}
+	}
+	ingredients := reviewLines(parts, func(part parsing.Recipe) []string {
+		lines := make([]string, 0, len(part.Ingredients))
+		for _, ingredient := range part.Ingredients {
+			lines = append(lines, parsing.FormatIngredient(ingredient))
+		}
+		return lines
+	})
+	instructions := reviewLines(parts, func(part parsing.Recipe) []string { return part.Instructions })
+	notes := reviewLines(parts, func(part parsing.Recipe) []string { return part.Notes })
+
+	return Form(
+		hx.Post(url),
+		hx.Target("#recipe-detail"),
+
+		H2(Class("text-xl font-bold mb-2"), Text("Review recipe")),
+		If(recipe.Status == model.ExtractionFailed,
+			ErrorPartial("We couldn't tell the ingredients from the steps. Your text is in the notes, move each line where it belongs."),
+		),
+		P(Class("text-sm text-gray-600 mb-4"), Text("Check what we read from your text. Put one ingredient or step on each line, and a line like == Frosting == before the lines of each part of the recipe.")),
+
+		reviewField("Recipe Name", "review-name", Input(
+			Type("text"), ID("review-name"), Name("name"), Value(recipe.Name), Required(), Class(inputClass),
+		)),
+		reviewField("Servings", "review-servings", Input(
+			Type("number"), ID("review-servings"), Name("servings"), Min("0"), Max(fmt.Sprint(MaxServings)),
+			If(servings > 0, Value(fmt.Sprint(servings))), Class(inputClass+" max-w-24"),
+		)),
+		reviewField("Ingredients", "review-ingredients", Textarea(
+			ID("review-ingredients"), Name("ingredients"), Rows("8"), Class(inputClass),
+			Text(strings.Join(ingredients, "\n")),
+		)),
+		reviewField("Instructions", "review-instructions", Textarea(
+			ID("review-instructions"), Name("instructions"), Rows("10"), Class(inputClass),
+			Text(strings.Join(instructions, "\n")),
+		)),
+		reviewField("Notes", "review-notes", Textarea(
+			ID("review-notes"), Name("notes"), Rows("4"), Class(inputClass),
+			Text(strings.Join(notes, "\n")),
+		)),
+
+		Div(Class("flex justify-end space-x-3"),
+			Button(
+				Type("button"),
+				Class("px-4 py-2 border border-gray-300 rounded-md shadow-sm text-sm font-medium text-gray-700 bg-white hover:bg-gray-50"),
+				hx.Get(fmt.Sprintf("/g/%d/recipe/%d", groupID, recipe.ID)),
+				hx.Target("#recipe-detail"),
+				Text("Skip review"),
+			),
+			Button(
+				Type("submit"),
+				Class("inline-flex justify-center px-4 py-2 border border-transparent rounded-md shadow-sm text-sm font-medium text-white bg-blue-600 hover:bg-blue-700"),
+				Text("Save Recipe"),
+			),
+		),
+	)
+}
+
+// reviewLines writes the lines of every part of a recipe, each part after the
+// first under its section heading
+func reviewLines(parts []parsing.Recipe, lines func(parsing.Recipe) []string) []string {
+	var all []string
+	for i, part := range parts {
+		partLines := lines(part)
+		if i > 0 && len(partLines) > 0 {
+			all = append(all, parsing.SectionHeading(part.Name))
+		}
+		all = append(all, partLines...)
+	}
+	return all
+}
+
+// reviewField is a labeled control in the review editor
+func reviewField(label, id string, control Node) Node {
+	return Div(Class("mb-4"),
+		Label(Class("block text-sm font-medium text-gray-700 mb-1"), For(id), Text(label)),
+		control,
+	)
+}
+
+// PDFRecipe is a recipe found in an uploaded PDF, with what could be read from
+// it at a glance
+type PDFRecipe struct {
+	Text   string
+	Parsed *parsing.ExtractedRecipe
+}
+
+// PDFReviewPartial lists the recipes found in a PDF so the ones to add can be
+// picked. Recipes where ingredients were found are picked to start with.
+func PDFReviewPartial(groupID int, filename string, recipes []PDFRecipe) Node {
+	return Form(
+		hx.Post(fmt.Sprintf("/g/%d/recipes/pdf/add", groupID)),
+		hx.Target("#recipe-detail"),
+
+		H2(Class("text-xl font-bold mb-2"), Text("Add recipes from "+filename)),
+		P(Class("text-sm text-gray-600 mb-4"), Text(fmt.Sprintf("We found %d recipes. Choose the ones to add, you can check each one after.", len(recipes)))),
+
+		Ul(Class("divide-y divide-gray-200 mb-4"),
+			Map(recipes, func(recipe PDFRecipe) Node {
+				return pdfRecipeItem(recipe)
+			}),
+		),
+
+		Div(Class("flex justify-end space-x-3"),
+			Button(
+				Type("button"),
+				Class("px-4 py-2 border border-gray-300 rounded-md shadow-sm text-sm font-medium text-gray-700 bg-white hover:bg-gray-50"),
+				hx.Get("/empty"),
+				hx.Target("#recipe-detail"),
+				Text("Cancel"),
+			),
+			Button(
+				Type("submit"),
+				Class("inline-flex justify-center px-4 py-2 border border-transparent rounded-md shadow-sm text-sm font-medium text-white bg-blue-600 hover:bg-blue-700"),
+				Text("Add Recipes"),
+			),
+		),
+	)
+}
+
+// pdfRecipeItem is a recipe found in a PDF with a box to pick it and its text
+// to look over
+func pdfRecipeItem(recipe PDFRecipe) Node {
+	title, ingredients, steps := "Untitled recipe", 0, 0
+	if recipe.Parsed != nil {
+		if recipe.Parsed.Title != "" {
+			title = recipe.Parsed.Title
+		}
+		ingredients, steps = len(recipe.Parsed.Ingredients), len(recipe.Parsed.Instructions)
+	}
+	return Li(Class("py-3"),
+		Label(Class("flex items-start space-x-3 cursor-pointer"),
+			// Each box sends the text of its own recipe
+			Input(Type("checkbox"), Name("text"), Value(recipe.Text), If(ingredients > 0, Checked()), Class("mt-1")),
+			Div(
+				Span(Class("font-medium"), Text(title)),
+				P(Class("text-sm text-gray-500"), Text(fmt.Sprintf("%d ingredients, %d steps", ingredients, steps))),
+			),
+		),
+		Details(Class("ml-7 mt-1"),
+			Summary(Class("text-sm text-blue-600 cursor-pointer"), Text("Show text")),
+			Pre(Class("mt-2 text-xs whitespace-pre-wrap bg-gray-50 p-2 rounded"), Text(recipe.Text)),
+		),
+	)
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"fmt"
+	"net/url"
+
+	. "maragu.dev/gomponents"
+	. "maragu.dev/gomponents/html"
+
+	"recipeze/model"
+)
+
+// SnapshotPage shows the copy of a recipe's page saved when it was added, in a
+// sandboxed frame, or just its text. snapshot is nil when no copy was saved.
+func SnapshotPage(props PageProps, recipe *model.Recipe, snapshot *model.Snapshot, textView bool) Node {
+	props.Title = recipe.Name + " (saved copy)"
+	snapshotPath := fmt.Sprintf("/g/%d/recipe/%d/snapshot", recipe.GroupID, recipe.ID)
+
+	return page(props,
+		ModalContainer(),
+		Div(Class("bg-white rounded-lg shadow p-4 mb-4 flex flex-wrap items-center justify-between gap-4"),
+			Div(
+				H1(Class("text-xl font-bold"), Text(recipe.Name)),
+				Iff(snapshot != nil, func() Node {
+					return P(Class("text-sm text-gray-600"),
+						Text("Saved from "),
+						A(
+							Href(snapshot.URL),
+							Target("_blank"),
+							Rel("noopener noreferrer"),
+							Class("text-blue-600 hover:underline"),
+							Text(siteName(snapshot.URL)),
+						),
+						Text(" on "+snapshot.SavedAt.Format("January 2, 2006")),
+					)
+				}),
+			),
+			Div(Class("flex flex-row gap-2"),
+				If(snapshot != nil && textView, snapshotLink(snapshotPath, "Show page")),
+				If(snapshot != nil && !textView, snapshotLink(snapshotPath+"?view=text", "Show text only")),
+				snapshotLink(fmt.Sprintf("/g/%d/recipes", recipe.GroupID), "Back to recipes"),
+			),
+		),
+		snapshotContent(snapshotPath, recipe, snapshot, textView),
+	)
+}
+
+func snapshotContent(snapshotPath string, recipe *model.Recipe, snapshot *model.Snapshot, textView bool) Node {
+	switch {
+	case snapshot == nil:
+		return P(Class("bg-white rounded-lg shadow p-6 text-gray-600"),
+			Text("No copy of this recipe's page was saved. Copies are kept of recipes added by link."),
+		)
+	case textView:
+		return Div(Class("bg-white rounded-lg shadow p-6 whitespace-pre-wrap break-words"),
+			Text(snapshot.Text),
+		)
+	default:
+		return IFrame(
+			Src(snapshotPath+"/page"),
+			// No scripts and no same origin, links may only open a new tab
+			Attr("sandbox", "allow-popups allow-popups-to-escape-sandbox"),
+			Attr("referrerpolicy", "no-referrer"),
+			Title("Saved copy of "+recipe.Name),
+			Class("w-full h-[75vh] bg-white rounded-lg shadow"),
+		)
+	}
+}
+
+func snapshotLink(href, text string) Node {
+	return A(
+		Href(href),
+		Class("inline-flex items-center px-4 py-2 bg-blue-500 hover:bg-blue-600 text-white font-medium rounded-md transition-colors"),
+		Text(text),
+	)
+}
+
+// siteName shortens a page's URL to its host for display
+func siteName(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return pageURL
+	}
+	return u.Host
+}
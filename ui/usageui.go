@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"fmt"
+
+	. "maragu.dev/gomponents"
+	. "maragu.dev/gomponents/html"
+
+	"recipeze/model"
+)
+
+// UsagePage shows a group's admins what reading recipes with the LLM has cost
+// this month, against the budgets, and the latest calls
+func UsagePage(props PageProps, groupID int, report *model.LLMUsage) Node {
+	props.Title = "LLM usage"
+	budget := report.Budget
+
+	return page(props,
+		Div(Class("bg-white rounded-lg shadow p-4 mb-4 flex flex-wrap items-center justify-between gap-4"),
+			Div(
+				H1(Class("text-xl font-bold"), Text("LLM usage")),
+				P(Class("text-sm text-gray-600"),
+					Text("Estimated from the tokens each call used, since "+report.Since.Format("January 2")+". Budgets reset at midnight UTC."),
+				),
+			),
+			snapshotLink(fmt.Sprintf("/g/%d/recipes", groupID), "Back to recipes"),
+		),
+		Div(Class("grid grid-cols-1 sm:grid-cols-2 gap-4 mb-4"),
+			spendCard("Today", report.Today, budget.GroupDaily),
+			spendCard("This month", report.Month, budget.GroupMonthly),
+		),
+		If(budget.UserDaily > 0 || budget.UserMonthly > 0,
+			P(Class("bg-white rounded-lg shadow p-4 mb-4 text-sm text-gray-600"),
+				Text("Each member may also spend "+userBudget(budget)+" on the recipes they add."),
+			),
+		),
+		usageSection("By member",
+			If(len(report.Members) == 0, P(Class("text-sm text-gray-500 italic"), Text("No recipes have been read with the LLM this month."))),
+			If(len(report.Members) > 0, Table(Class("w-full text-sm"),
+				THead(Tr(Class("text-left text-gray-600"),
+					Th(Class("py-1 pr-2 font-normal"), Text("Member")),
+					Th(Class("py-1 px-2 font-normal text-right"), Text("Calls")),
+					Th(Class("py-1 px-2 font-normal text-right"), Text("Tokens in")),
+					Th(Class("py-1 px-2 font-normal text-right"), Text("Tokens out")),
+					Th(Class("py-1 pl-2 font-normal text-right"), Text("Cost")),
+				)),
+				TBody(Map(report.Members, func(member model.MemberLLMUsage) Node {
+					return Tr(Class("border-t border-gray-100"),
+						Td(Class("py-1 pr-2"), Text(nameOrNobody(member.Name))),
+						Td(Class("py-1 px-2 text-right"), Text(fmt.Sprint(member.Calls))),
+						Td(Class("py-1 px-2 text-right"), Text(fmt.Sprint(member.InputTokens))),
+						Td(Class("py-1 px-2 text-right"), Text(fmt.Sprint(member.OutputTokens))),
+						Td(Class("py-1 pl-2 text-right"), Text(formatMicros(member.Cost))),
+					)
+				})),
+			)),
+		),
+		usageSection("Latest calls",
+			If(len(report.Recent) == 0, P(Class("text-sm text-gray-500 italic"), Text("No calls yet."))),
+			If(len(report.Recent) > 0, Div(Class("overflow-x-auto"), Table(Class("w-full text-sm"),
+				THead(Tr(Class("text-left text-gray-600"),
+					Th(Class("py-1 pr-2 font-normal"), Text("When")),
+					Th(Class("py-1 px-2 font-normal"), Text("Recipe")),
+					Th(Class("py-1 px-2 font-normal"), Text("Member")),
+					Th(Class("py-1 px-2 font-normal"), Text("Model")),
+					Th(Class("py-1 px-2 font-normal text-right"), Text("Tokens in / out")),
+					Th(Class("py-1 px-2 font-normal text-right"), Text("Time")),
+					Th(Class("py-1 pl-2 font-normal text-right"), Text("Cost")),
+				)),
+				TBody(Map(report.Recent, func(call model.LLMCall) Node {
+					return Tr(Class("border-t border-gray-100"),
+						Td(Class("py-1 pr-2 whitespace-nowrap"), Text(call.At.UTC().Format("Jan 2 15:04"))),
+						Td(Class("py-1 px-2"),
+							If(call.RecipeID != 0, Text(nameOrNobody(call.RecipeName))),
+							If(call.RecipeID == 0, Span(Class("text-gray-500 italic"), Text("Deleted"))),
+							If(!call.Succeeded, Span(Class("ml-2 text-xs text-red-600"), Text("failed"))),
+						),
+						Td(Class("py-1 px-2"), Text(nameOrNobody(call.UserName))),
+						Td(Class("py-1 px-2 text-gray-600"), Text(call.Model)),
+						Td(Class("py-1 px-2 text-right whitespace-nowrap"), Text(fmt.Sprintf("%d / %d", call.InputTokens, call.OutputTokens))),
+						Td(Class("py-1 px-2 text-right"), Text(fmt.Sprintf("%.1fs", call.Latency.Seconds()))),
+						Td(Class("py-1 pl-2 text-right"), Text(formatMicros(call.Cost))),
+					)
+				})),
+			))),
+		),
+	)
+}
+
+func usageSection(title string, children ...Node) Node {
+	return Div(Class("bg-white rounded-lg shadow p-4 mb-4"),
+		H2(Class("text-lg font-semibold mb-2"), Text(title)),
+		Group(children),
+	)
+}
+
+// spendCard shows what the group spent in a period, and how much of its
+// budget that is
+func spendCard(label string, spent, budget int64) Node {
+	limit := "No budget set"
+	if budget > 0 {
+		limit = fmt.Sprintf("of %s budget", formatMicros(budget))
+	}
+	return Div(Class("bg-white rounded-lg shadow p-4"),
+		P(Class("text-sm text-gray-600"), Text(label)),
+		P(Class("text-2xl font-bold"), Text(formatMicros(spent))),
+		P(Class("text-sm text-gray-500"), Text(limit)),
+		If(budget > 0, Div(Class("mt-2 h-2 bg-gray-100 rounded"),
+			Div(
+				Class("h-2 rounded "+budgetColor(spent, budget)),
+				Style(fmt.Sprintf("width: %d%%", min(100, spent*100/budget))),
+			),
+		)),
+	)
+}
+
+func budgetColor(spent, budget int64) string {
+	if spent >= budget {
+		return "bg-red-500"
+	}
+	return "bg-green-500"
+}
+
+// userBudget describes what each member may spend, like "$0.50 a day"
+func userBudget(budget model.LLMBudget) string {
+	switch {
+	case budget.UserDaily > 0 && budget.UserMonthly > 0:
+		return formatMicros(budget.UserDaily) + " a day and " + formatMicros(budget.UserMonthly) + " a month"
+	case budget.UserDaily > 0:
+		return formatMicros(budget.UserDaily) + " a day"
+	default:
+		return formatMicros(budget.UserMonthly) + " a month"
+	}
+}
+
+// formatMicros shows millionths of a dollar as dollars, with more places for
+// the small amounts single calls cost
+func formatMicros(micros int64) string {
+	dollars := float64(micros) / 1e6
+	if micros != 0 && micros < 10_000 {
+		return fmt.Sprintf("$%.4f", dollars)
+	}
+	return fmt.Sprintf("$%.2f", dollars)
+}
+
+func nameOrNobody(name string) string {
+	if name == "" {
+		return "-"
+	}
+	return name
+}
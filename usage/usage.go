@@ -0,0 +1,267 @@
+// Package usage records what every call to the LLM costs and keeps groups and
+// users within their budgets.
+//
+// A Meter wraps the configured parsing.RecipeExtractor. Before each call it
+// checks the spend of the group and user the call is for, taken from the
+// context with WithScope, and refuses with an *ExceededError once a budget is
+// used up. Costs are estimates from the tokens each call used.
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"recipeze/parsing"
+	"recipeze/repo"
+)
+
+// Scope is who an LLM call is made for
+type Scope struct {
+	GroupID  int
+	UserID   int // Who added the recipe
+	RecipeID int
+}
+
+type scopeKey struct{}
+
+// WithScope attributes the LLM calls made with ctx to scope
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFrom gives the scope set with WithScope, or the zero Scope for calls
+// that aren't made for anyone in particular
+func ScopeFrom(ctx context.Context) Scope {
+	scope, _ := ctx.Value(scopeKey{}).(Scope)
+	return scope
+}
+
+// Price is what a model charges, in dollars per million tokens
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// prices are the list prices of the default models and their relatives, by
+// model name prefix
+var prices = map[string]Price{
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4},
+	"claude-3-5-sonnet": {Input: 3, Output: 15},
+	"claude-3-7-sonnet": {Input: 3, Output: 15},
+	"claude-sonnet-4":   {Input: 3, Output: 15},
+	"claude-3-opus":     {Input: 15, Output: 75},
+	"claude-opus-4":     {Input: 15, Output: 75},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+	"gpt-4o":            {Input: 2.50, Output: 10},
+	"gpt-4.1-mini":      {Input: 0.40, Output: 1.60},
+	"gpt-4.1":           {Input: 2, Output: 8},
+}
+
+// PriceOf gives the list price of a model, named as parsing.RecipeExtractor's
+// Model names it. Unknown models, such as local ones, are free.
+func PriceOf(model string) Price {
+	_, name, _ := strings.Cut(model, "/")
+	// The longest prefix wins, so gpt-4o-mini isn't priced as gpt-4o
+	prefixes := make([]string, 0, len(prices))
+	for prefix := range prices {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return prices[prefix]
+		}
+	}
+	return Price{}
+}
+
+// Micros converts dollars to the millionths of a dollar costs are kept in
+func Micros(dollars float64) int64 {
+	return int64(dollars*1e6 + 0.5)
+}
+
+// Cost estimates a call in millionths of a dollar
+func (p Price) Cost(inputTokens, outputTokens int) int64 {
+	return int64(float64(inputTokens)*p.Input + float64(outputTokens)*p.Output + 0.5)
+}
+
+// Budget caps what can be spent on the LLM, in millionths of a dollar. Zero
+// leaves that period unlimited. Days and months run in UTC.
+type Budget struct {
+	GroupDaily   int64
+	GroupMonthly int64
+	UserDaily    int64
+	UserMonthly  int64
+}
+
+// Spend is what a group and a user have spent on the LLM so far, in millionths
+// of a dollar
+type Spend struct {
+	GroupDay   int64
+	GroupMonth int64
+	UserDay    int64
+	UserMonth  int64
+}
+
+// ExceededError tells which budget was used up and when it resets. It matches
+// parsing.ErrOverBudget, so extraction falls back to structured data.
+type ExceededError struct {
+	Who    string // "group" or "user"
+	Period string // "daily" or "monthly"
+	Until  time.Time
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("the %s's %s LLM budget is used up until %s", e.Who, e.Period, e.Until.Format("2 Jan 15:04 MST"))
+}
+
+func (e *ExceededError) Unwrap() error { return parsing.ErrOverBudget }
+
+// Check gives an *ExceededError when spend has reached one of the budgets. The
+// monthly budgets are checked first, they reset last.
+func (b Budget) Check(spend Spend, now time.Time) error {
+	day, month := periodStarts(now)
+	nextDay, nextMonth := day.AddDate(0, 0, 1), month.AddDate(0, 1, 0)
+	for _, limit := range []struct {
+		who, period  string
+		budget, used int64
+		until        time.Time
+	}{
+		{"group", "monthly", b.GroupMonthly, spend.GroupMonth, nextMonth},
+		{"user", "monthly", b.UserMonthly, spend.UserMonth, nextMonth},
+		{"group", "daily", b.GroupDaily, spend.GroupDay, nextDay},
+		{"user", "daily", b.UserDaily, spend.UserDay, nextDay},
+	} {
+		if limit.budget > 0 && limit.used >= limit.budget {
+			return &ExceededError{Who: limit.who, Period: limit.period, Until: limit.until}
+		}
+	}
+	return nil
+}
+
+// IsZero tells if the budget leaves every period unlimited
+func (b Budget) IsZero() bool {
+	return b == Budget{}
+}
+
+// periodStarts gives the start of now's day and month in UTC
+func periodStarts(now time.Time) (day, month time.Time) {
+	now = now.UTC()
+	day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day, month
+}
+
+// DayStart is when the current budget day began
+func DayStart(now time.Time) time.Time {
+	day, _ := periodStarts(now)
+	return day
+}
+
+// MonthStart is when the current budget month began
+func MonthStart(now time.Time) time.Time {
+	_, month := periodStarts(now)
+	return month
+}
+
+// Options configure a Meter
+type Options struct {
+	Budget Budget
+	// Price overrides the list price of the model, for models that aren't
+	// known or are billed differently
+	Price *Price
+}
+
+// Meter is a parsing.RecipeExtractor that records every call it passes on
+// and refuses calls once a budget is used up
+type Meter struct {
+	extractor parsing.RecipeExtractor
+	queries   *repo.Queries
+	budget    Budget
+	price     Price
+}
+
+// NewMeter meters the calls to extractor, recording them through queries
+func NewMeter(extractor parsing.RecipeExtractor, queries *repo.Queries, opts Options) *Meter {
+	price := PriceOf(extractor.Model())
+	if opts.Price != nil {
+		price = *opts.Price
+	}
+	return &Meter{
+		extractor: extractor,
+		queries:   queries,
+		budget:    opts.Budget,
+		price:     price,
+	}
+}
+
+func (m *Meter) Complete(ctx context.Context, prompt string) (*parsing.Completion, error) {
+	scope := ScopeFrom(ctx)
+	if err := m.checkBudget(ctx, scope); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	completion, err := m.extractor.Complete(ctx, prompt)
+	params := repo.RecordLLMUsageParams{
+		GroupID:   int4(scope.GroupID),
+		UserID:    int4(scope.UserID),
+		RecipeID:  int4(scope.RecipeID),
+		Model:     m.extractor.Model(),
+		LatencyMs: int32(time.Since(start).Milliseconds()),
+		Succeeded: err == nil,
+	}
+	if completion != nil {
+		params.InputTokens = int32(completion.InputTokens)
+		params.OutputTokens = int32(completion.OutputTokens)
+		params.CostMicros = m.price.Cost(completion.InputTokens, completion.OutputTokens)
+	}
+	// The call was made, and paid for, even if whoever made it has given up
+	if recordErr := m.queries.RecordLLMUsage(context.WithoutCancel(ctx), params); recordErr != nil {
+		slog.Error("Could not record LLM usage", "model", params.Model, "recipeID", scope.RecipeID, "error", recordErr)
+	}
+	return completion, err
+}
+
+func (m *Meter) Model() string {
+	return m.extractor.Model()
+}
+
+// checkBudget refuses calls for a group or user that has used up a budget.
+// Calls made at the same time may all pass, so a budget can be overshot by a
+// few calls.
+func (m *Meter) checkBudget(ctx context.Context, scope Scope) error {
+	if m.budget.IsZero() || (scope.GroupID == 0 && scope.UserID == 0) {
+		return nil
+	}
+	now := time.Now()
+	day, month := periodStarts(now)
+	row, err := m.queries.GetLLMSpend(ctx, repo.GetLLMSpendParams{
+		GroupID:    int4(scope.GroupID),
+		DayStart:   pgtype.Timestamptz{Time: day, Valid: true},
+		UserID:     int4(scope.UserID),
+		MonthStart: pgtype.Timestamptz{Time: month, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("could not check LLM budget: %w", err)
+	}
+	err = m.budget.Check(Spend(row), now)
+	var exceeded *ExceededError
+	if errors.As(err, &exceeded) {
+		slog.Info("LLM budget used up", "groupID", scope.GroupID, "userID", scope.UserID, "who", exceeded.Who, "period", exceeded.Period)
+	}
+	return err
+}
+
+// int4 stores an ID, zero being none
+func int4(id int) pgtype.Int4 {
+	return pgtype.Int4{Int32: int32(id), Valid: id != 0}
+}
@@ -0,0 +1,82 @@
+package usage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"maragu.dev/is"
+
+	"recipeze/parsing"
+	"recipeze/usage"
+)
+
+func TestPriceOf(t *testing.T) {
+	tests := []struct {
+		model string
+		want  usage.Price
+	}{
+		{"anthropic/claude-3-5-haiku-latest", usage.Price{Input: 0.80, Output: 4}},
+		{"anthropic/claude-3-5-haiku-20241022", usage.Price{Input: 0.80, Output: 4}},
+		{"openai/gpt-4o-mini", usage.Price{Input: 0.15, Output: 0.60}},
+		{"openai/gpt-4o-2024-08-06", usage.Price{Input: 2.50, Output: 10}},
+		{"openai/llama3.1", usage.Price{}},
+		{"fake/fake", usage.Price{}},
+	}
+	for _, test := range tests {
+		t.Run(test.model, func(t *testing.T) {
+			is.Equal(t, test.want, usage.PriceOf(test.model))
+		})
+	}
+}
+
+func TestPrice_Cost(t *testing.T) {
+	price := usage.Price{Input: 0.80, Output: 4}
+	// 2000 * 0.80 + 500 * 4 millionths of a dollar
+	is.Equal(t, int64(3600), price.Cost(2000, 500))
+	is.Equal(t, int64(0), usage.Price{}.Cost(2000, 500))
+}
+
+func TestBudget_Check(t *testing.T) {
+	now := time.Date(2025, time.March, 14, 15, 30, 0, 0, time.UTC)
+	tomorrow := time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)
+	nextMonth := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		budget usage.Budget
+		spend  usage.Spend
+		want   *usage.ExceededError
+	}{
+		{"unlimited", usage.Budget{}, usage.Spend{GroupDay: 1e9, UserMonth: 1e9}, nil},
+		{"under budget", usage.Budget{GroupDaily: 1000, UserMonthly: 5000}, usage.Spend{GroupDay: 999, UserMonth: 4999}, nil},
+		{"group day used up", usage.Budget{GroupDaily: 1000}, usage.Spend{GroupDay: 1000}, &usage.ExceededError{Who: "group", Period: "daily", Until: tomorrow}},
+		{"user month used up", usage.Budget{UserMonthly: 5000}, usage.Spend{UserMonth: 6000}, &usage.ExceededError{Who: "user", Period: "monthly", Until: nextMonth}},
+		{"month wins over day", usage.Budget{GroupDaily: 10, GroupMonthly: 10}, usage.Spend{GroupDay: 10, GroupMonth: 10}, &usage.ExceededError{Who: "group", Period: "monthly", Until: nextMonth}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.budget.Check(test.spend, now)
+			if test.want == nil {
+				is.NotError(t, err)
+				return
+			}
+			var exceeded *usage.ExceededError
+			is.True(t, errors.As(err, &exceeded))
+			is.Equal(t, *test.want, *exceeded)
+			is.True(t, errors.Is(err, parsing.ErrOverBudget))
+		})
+	}
+}
+
+func TestExceededError(t *testing.T) {
+	err := &usage.ExceededError{Who: "group", Period: "daily", Until: time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)}
+	is.Equal(t, "the group's daily LLM budget is used up until 15 Mar 00:00 UTC", err.Error())
+}
+
+func TestScopeFrom(t *testing.T) {
+	is.Equal(t, usage.Scope{}, usage.ScopeFrom(context.Background()))
+	scope := usage.Scope{GroupID: 1, UserID: 2, RecipeID: 3}
+	is.Equal(t, scope, usage.ScopeFrom(usage.WithScope(context.Background(), scope)))
+}